@@ -3,9 +3,11 @@ package main
 import (
 	"flag"
 	"log"
-	"megga-backend/internal/devutils"
-	"megga-backend/internal/database"
 	"megga-backend/internal/config"
+	"megga-backend/internal/database"
+	"megga-backend/internal/devutils"
+	"megga-backend/internal/services"
+	"time"
 )
 
 func main() {
@@ -16,10 +18,13 @@ func main() {
 
 	migrate := flag.Bool("migrate", false, "Run database migrations")
 	seed := flag.Bool("seed", false, "Seed the database with test data")
+	backfill := flag.Bool("backfill", false, "Backfill BLS observation history")
+	startYear := flag.Int("start-year", time.Now().Year()-10, "First year to backfill")
+	endYear := flag.Int("end-year", time.Now().Year(), "Last year to backfill")
 	flag.Parse()
 
-	if !*migrate && !*seed {
-		log.Println("No action specified. Use --migrate or --seed.")
+	if !*migrate && !*seed && !*backfill {
+		log.Println("No action specified. Use --migrate, --seed or --backfill.")
 		return
 	}
 
@@ -35,4 +40,11 @@ func main() {
 		log.Println("Seeding the database...")
 		devutils.SeedDB(database.DB)
 	}
+
+	if *backfill {
+		log.Println("Backfilling observation history...")
+		if err := services.BackfillBLSData(database.DB, *startYear, *endYear); err != nil {
+			log.Fatalf("❌ Backfill failed: %v", err)
+		}
+	}
 }
@@ -1,53 +1,80 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
-	"time"
 
 	"megga-backend/internal/config"
 	"megga-backend/internal/database"
 	"megga-backend/internal/middleware"
 	"megga-backend/internal/routes"
+	"megga-backend/internal/scheduler"
 	"megga-backend/internal/services"
 
 	"github.com/gorilla/mux"
 )
 
+const backgroundJobsLease = "background-jobs"
+
 func main() {
 	config.LoadAndValidateEnv()
 	database.InitDB()
 	defer database.CloseDB()
 
-	initBLS := os.Getenv("INIT_BLS") == "true"
+	mailer, err := services.NewMailer(config.Mail())
+	if err != nil {
+		log.Fatalf("❌ Invalid mail configuration: %v", err)
+	}
+	services.SetMailer(mailer)
 
-	go func() {
-		if initBLS {
-			log.Println("⏳ INIT_BLS set to true. Initializing BLS data...")
-			err := services.FetchLatestBLSData(database.DB)
-			if err != nil {
-				log.Printf("❌ Error initializing BLS data: %v", err)
-			} else {
-				log.Println("✅ BLS data initialized successfully!")
-			}
-		} else {
-			log.Println("⏳ INIT_BLS set to false. Skipping initial BLS data fetch.")
-		}
+	location, err := config.SchedulerLocation()
+	if err != nil {
+		log.Fatalf("❌ Invalid SCHEDULER_TIMEZONE: %v", err)
+	}
 
-		ticker := time.NewTicker(24 * time.Hour)
-		defer ticker.Stop()
+	jobs := scheduler.New(database.DB, location)
+	err = jobs.Register(services.IngestJobName, config.IngestSchedule(), func(ctx context.Context) error {
+		return services.RunIngestJob(ctx, database.DB)
+	})
+	if err != nil {
+		log.Fatalf("❌ Invalid INGEST_SCHEDULE: %v", err)
+	}
+	err = jobs.Register(services.ExpireThresholdsJobName, config.ExpireSchedule(), func(ctx context.Context) error {
+		return services.RunExpireThresholdsJob(ctx, database.DB)
+	})
+	if err != nil {
+		log.Fatalf("❌ Invalid EXPIRE_SCHEDULE: %v", err)
+	}
 
-		for range ticker.C {
-			err := services.FetchLatestBLSData(database.DB)
-			if err != nil {
-				log.Printf("❌ Error fetching BLS data: %v", err)
-			} else {
-				log.Println("✅ Successfully updated BLS data.")
-			}
+	// Only the instance holding the lease runs scheduled jobs. A newly elected
+	// leader catches up on anything the previous one missed.
+	elector := scheduler.NewElector(database.DB, backgroundJobsLease, scheduler.DefaultLeaseTTL)
+	elector.OnElected = jobs.CatchUp
+	jobs.Leader = elector
+	jobs.Start(context.Background())
+
+	if _, err := elector.TryAcquire(context.Background()); err != nil {
+		log.Printf("❌ %v", err)
+	}
+	go elector.Run(context.Background())
+
+	// Only the leader sends queued letters, so one instance dispatches at a
+	// time.
+	go services.RunOutboxDispatcher(context.Background(), database.DB, elector, config.OutboxPollInterval())
+
+	if os.Getenv("INIT_BLS") == "true" && elector.IsLeader() {
+		log.Println("⏳ INIT_BLS set to true. Initializing BLS data...")
+		if _, err := jobs.RunNow(context.Background(), services.IngestJobName); err != nil {
+			log.Printf("❌ Error initializing BLS data: %v", err)
 		}
-	}()
+	} else if os.Getenv("INIT_BLS") == "true" {
+		log.Println("⏳ INIT_BLS set to true, but another instance is the leader. Skipping initial BLS data fetch.")
+	} else {
+		log.Println("⏳ INIT_BLS set to false. Skipping initial BLS data fetch.")
+	}
 
 	cognitoConfig := middleware.CognitoConfig{
 		UserPoolID: os.Getenv("COGNITO_USER_POOL_ID"),
@@ -76,7 +103,7 @@ func main() {
 
 	router.Use(middleware.ValidateCognitoToken(cognitoConfig))
 
-	routes.RegisterRoutes(router, database.DB)
+	routes.RegisterRoutes(router, database.DB, jobs)
 
 	if config.IsDevelopmentMode() {
 		log.Println("📌 Registered Routes:")
@@ -101,7 +128,7 @@ func main() {
 	addr := fmt.Sprintf(":%s", port)
 
 	log.Printf("🚀 Starting server on port %s...", port)
-	err := http.ListenAndServe(addr, router)
+	err = http.ListenAndServe(addr, router)
 	if err != nil {
 		log.Fatalf("❌ Failed to start server: %v", err)
 	}
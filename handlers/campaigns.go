@@ -0,0 +1,279 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"megga-backend/internal/config"
+	"megga-backend/internal/database"
+	"megga-backend/internal/models"
+	"megga-backend/internal/services"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v4"
+)
+
+type campaignRequest struct {
+	models.Threshold
+	Name           string `json:"name"`
+	Description    string `json:"description"`
+	LetterSubject  string `json:"letterSubject"`
+	LetterTemplate string `json:"letterTemplate"` // Letter to recipients, with [Placeholder] fields
+}
+
+// CreateCampaign publishes a campaign. It takes the fields of POST /thresholds,
+// whose recipients become the campaign's suggested recipients, together with
+// the campaign's name, description and letter. The signed-in user is the
+// owner.
+func CreateCampaign(w http.ResponseWriter, r *http.Request, db database.DBQuerier) {
+	var request campaignRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if status, msg := prepareThresholdDefinition(db, &request.Threshold); status != 0 {
+		http.Error(w, msg, status)
+		return
+	}
+	if len(request.Recipients) == 0 {
+		http.Error(w, "Missing required fields", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(request.Name) == "" || strings.TrimSpace(request.LetterSubject) == "" || strings.TrimSpace(request.LetterTemplate) == "" {
+		http.Error(w, "A campaign needs a name, a letter subject and a letter template", http.StatusBadRequest)
+		return
+	}
+	if msg := validateThresholdExpiry(request.ExpiresAt); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	// Members are told about each firing themselves, so the owner's letter
+	// would only repeat theirs.
+	request.NotifyUser = false
+
+	ownerID, ok := authenticatedUserID(w, r, db)
+	if !ok {
+		return
+	}
+	request.UserID = ownerID
+
+	tx, err := db.BeginTx(context.Background(), pgx.TxOptions{})
+	if err != nil {
+		log.Printf("❌ Error Starting Transaction: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(context.Background())
+
+	thresholdID, msg := insertThreshold(tx, request.Threshold)
+	if msg != "" {
+		http.Error(w, msg, http.StatusInternalServerError)
+		return
+	}
+
+	var campaignID int
+	err = tx.QueryRow(context.Background(), `
+		INSERT INTO campaigns (owner_id, threshold_id, name, description, letter_subject, letter_template)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING campaign_id`,
+		request.UserID, thresholdID, request.Name, request.Description, request.LetterSubject, request.LetterTemplate).Scan(&campaignID)
+	if err != nil {
+		log.Printf("❌ Error Inserting Campaign: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(context.Background()); err != nil {
+		log.Printf("❌ Error Committing Transaction: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("✅ Campaign Created Successfully: ID=%d, ThresholdID=%d", campaignID, thresholdID)
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":      "Campaign created successfully",
+		"campaign_id":  campaignID,
+		"threshold_id": thresholdID,
+	})
+}
+
+// GetCampaigns lists the published campaigns with their member counts.
+func GetCampaigns(w http.ResponseWriter, r *http.Request, db database.DBQuerier) {
+	campaigns, err := services.GetCampaigns(context.Background(), db)
+	if err != nil {
+		if config.IsDevelopmentMode() {
+			log.Printf("❌ [ERROR] Database query failed in GetCampaigns(): %v", err)
+		}
+		http.Error(w, "Database query error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(campaigns)
+}
+
+// JoinCampaign adds the signed-in user to a campaign, so the campaign's
+// letters go out with their name whenever it fires. Joining twice changes
+// nothing.
+func JoinCampaign(w http.ResponseWriter, r *http.Request, db database.DBQuerier) {
+	campaignID, userID, ok := campaignMemberFromRequest(w, r, db)
+	if !ok {
+		return
+	}
+
+	var exists int
+	err := db.QueryRow(context.Background(), "SELECT 1 FROM campaigns WHERE campaign_id = $1", campaignID).Scan(&exists)
+	if err == pgx.ErrNoRows {
+		http.Error(w, "Campaign not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	_, err = db.Exec(context.Background(), `
+		INSERT INTO campaign_members (campaign_id, user_id)
+		VALUES ($1, $2)
+		ON CONFLICT (campaign_id, user_id) DO NOTHING`, campaignID, userID)
+	if err != nil {
+		log.Printf("❌ Error adding user %d to campaign %d: %v", userID, campaignID, err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("✅ User %d joined campaign %d", userID, campaignID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Joined campaign successfully"})
+}
+
+// LeaveCampaign removes the signed-in user from a campaign.
+func LeaveCampaign(w http.ResponseWriter, r *http.Request, db database.DBQuerier) {
+	campaignID, userID, ok := campaignMemberFromRequest(w, r, db)
+	if !ok {
+		return
+	}
+
+	res, err := db.Exec(context.Background(),
+		"DELETE FROM campaign_members WHERE campaign_id = $1 AND user_id = $2", campaignID, userID)
+	if err != nil {
+		log.Printf("❌ Error removing user %d from campaign %d: %v", userID, campaignID, err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if res.RowsAffected() == 0 {
+		http.Error(w, "Not a member of this campaign", http.StatusNotFound)
+		return
+	}
+
+	log.Printf("✅ User %d left campaign %d", userID, campaignID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Left campaign successfully"})
+}
+
+// GetCampaignStats shows a campaign's owner how many users have joined and
+// how many letters have gone out for them.
+func GetCampaignStats(w http.ResponseWriter, r *http.Request, db database.DBQuerier) {
+	campaignID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || campaignID <= 0 {
+		http.Error(w, "Invalid or missing campaign ID", http.StatusBadRequest)
+		return
+	}
+
+	var ownerEmail string
+	err = db.QueryRow(context.Background(), `
+		SELECT u.email
+		FROM campaigns c
+		JOIN users u ON u.user_id = c.owner_id
+		WHERE c.campaign_id = $1`, campaignID).Scan(&ownerEmail)
+	if err == pgx.ErrNoRows {
+		http.Error(w, "Campaign not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if !strings.EqualFold(ownerEmail, r.Header.Get("X-User-Email")) {
+		http.Error(w, "Forbidden: only the campaign's owner can see its stats", http.StatusForbidden)
+		return
+	}
+
+	stats, err := services.GetCampaignStats(context.Background(), db, campaignID)
+	if err != nil {
+		if config.IsDevelopmentMode() {
+			log.Printf("❌ [ERROR] Database query failed in GetCampaignStats(): %v", err)
+		}
+		http.Error(w, "Database query error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
+
+// campaignMemberFromRequest reads the campaign ID of a join or leave request
+// and the signed-in user it is for, writing the error response itself when
+// either is invalid.
+func campaignMemberFromRequest(w http.ResponseWriter, r *http.Request, db database.DBQuerier) (int, int, bool) {
+	campaignID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || campaignID <= 0 {
+		http.Error(w, "Invalid or missing campaign ID", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	userID, ok := authenticatedUserID(w, r, db)
+	if !ok {
+		return 0, 0, false
+	}
+	return campaignID, userID, true
+}
+
+// authenticatedUserID looks up the user the request's token was issued to,
+// writing the error response itself when there is none.
+func authenticatedUserID(w http.ResponseWriter, r *http.Request, db database.DBQuerier) (int, bool) {
+	email := r.Header.Get("X-User-Email")
+	if email == "" {
+		http.Error(w, "Unauthorized: Missing user details", http.StatusUnauthorized)
+		return 0, false
+	}
+
+	var userID int
+	err := db.QueryRow(context.Background(), "SELECT user_id FROM users WHERE LOWER(email) = LOWER($1)", email).Scan(&userID)
+	if err == pgx.ErrNoRows {
+		http.Error(w, "Unauthorized: Unknown user", http.StatusUnauthorized)
+		return 0, false
+	} else if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return 0, false
+	}
+	return userID, true
+}
+
+func RegisterCampaignRoutes(router *mux.Router, db database.DBQuerier) {
+	router.HandleFunc("/campaigns", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			CreateCampaign(w, r, db)
+		} else {
+			GetCampaigns(w, r, db)
+		}
+	}).Methods("GET", "POST")
+
+	router.HandleFunc("/campaigns/{id}/join", func(w http.ResponseWriter, r *http.Request) {
+		JoinCampaign(w, r, db)
+	}).Methods("POST")
+
+	router.HandleFunc("/campaigns/{id}/leave", func(w http.ResponseWriter, r *http.Request) {
+		LeaveCampaign(w, r, db)
+	}).Methods("POST")
+
+	router.HandleFunc("/campaigns/{id}/stats", func(w http.ResponseWriter, r *http.Request) {
+		GetCampaignStats(w, r, db)
+	}).Methods("GET")
+}
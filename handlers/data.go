@@ -3,11 +3,11 @@ package handlers
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 	"log"
 	"megga-backend/internal/config"
 	"megga-backend/internal/database"
 	"megga-backend/internal/models"
+	"megga-backend/internal/services"
 	"net/http"
 	"strconv"
 	"time"
@@ -35,17 +35,36 @@ func CreateData(w http.ResponseWriter, r *http.Request, db database.DBQuerier) {
 		return
 	}
 
-	if info, exists := config.BLS_SERIES_INFO[data.SeriesID]; exists {
-		data.Name = info.Name
-		data.Unit = info.Unit
-	} else {
+	current := models.MonthlyPeriod(time.Now())
+	if data.Period == "" {
+		data.Period = current.Code()
+	}
+
+	if data.Year == "" {
+		data.Year = current.YearString()
+	}
+
+	period, err := models.ParsePeriod(data.Year, data.Period)
+	if err != nil {
+		http.Error(w, "Invalid year or period: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	data.PeriodLabel = period.Label()
+
+	info, err := services.GetActiveSeriesByID(db, data.SeriesID)
+	if err == pgx.ErrNoRows {
 		http.Error(w, "Invalid series_id", http.StatusBadRequest)
 		return
+	} else if err != nil {
+		http.Error(w, "Database query error", http.StatusInternalServerError)
+		return
 	}
+	data.Name = info.Name
+	data.Unit = info.Unit
 
 	var existingID int
 	checkQuery := `SELECT data_id FROM data WHERE series_id = $1`
-	err := db.QueryRow(context.Background(), checkQuery, data.SeriesID).Scan(&existingID)
+	err = db.QueryRow(context.Background(), checkQuery, data.SeriesID).Scan(&existingID)
 
 	if err == nil {
 		http.Error(w, "Duplicate series_id: This series already exists.", http.StatusConflict)
@@ -57,14 +76,6 @@ func CreateData(w http.ResponseWriter, r *http.Request, db database.DBQuerier) {
 
 	data.PreviousValue = data.LatestValue
 
-	if data.Period == "" {
-		data.Period = fmt.Sprintf("M%02d", time.Now().Month())
-	}
-
-	if data.Year == "" {
-		data.Year = fmt.Sprintf("%d", time.Now().Year())
-	}
-
 	query := `
 		INSERT INTO data (name, series_id, unit, previous_value, latest_value, last_updated, period, year)
 		VALUES ($1, $2, $3, $4, $5, NOW(), $6, $7)
@@ -119,6 +130,7 @@ func GetData(w http.ResponseWriter, r *http.Request, db database.DBQuerier) {
 			http.Error(w, "Error scanning data: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
+		data.PeriodLabel = models.PeriodLabel(data.Year, data.Period)
 		if config.IsDevelopmentMode() {
 			log.Printf("✅ [DEBUG] Fetched Data Row: %+v", data)
 		}
@@ -164,6 +176,7 @@ func GetDataByID(w http.ResponseWriter, r *http.Request, db database.DBQuerier)
 		http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	data.PeriodLabel = models.PeriodLabel(data.Year, data.Period)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(data)
@@ -183,6 +196,13 @@ func UpdateData(w http.ResponseWriter, r *http.Request, db database.DBQuerier) {
 		return
 	}
 
+	if data.Year != "" || data.Period != "" {
+		if _, err := models.ParsePeriod(data.Year, data.Period); err != nil {
+			http.Error(w, "Invalid year or period: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
 	query := `
     UPDATE data
     SET previous_value = latest_value,
@@ -240,6 +260,68 @@ func DeleteData(w http.ResponseWriter, r *http.Request, db database.DBQuerier) {
 	json.NewEncoder(w).Encode(map[string]string{"message": "Data deleted successfully"})
 }
 
+func GetDataObservations(w http.ResponseWriter, r *http.Request, db database.DBQuerier) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil || id <= 0 {
+		http.Error(w, "Invalid data ID", http.StatusBadRequest)
+		return
+	}
+
+	startYear := r.URL.Query().Get("start_year")
+	endYear := r.URL.Query().Get("end_year")
+	for _, year := range []string{startYear, endYear} {
+		if year == "" {
+			continue
+		}
+		if _, err := strconv.Atoi(year); err != nil {
+			http.Error(w, "Invalid year filter", http.StatusBadRequest)
+			return
+		}
+	}
+
+	query := `
+		SELECT o.observation_id, o.series_id, o.year, o.period, o.value, o.preliminary, o.footnotes, o.recorded_at
+		FROM data_observations o
+		JOIN data d ON d.series_id = o.series_id
+		WHERE d.data_id = $1
+		  AND ($2 = '' OR o.year >= $2)
+		  AND ($3 = '' OR o.year <= $3)
+		ORDER BY o.year, o.period
+	`
+	rows, err := db.Query(context.Background(), query, id, startYear, endYear)
+	if err != nil {
+		if config.IsDevelopmentMode() {
+			log.Printf("❌ [ERROR] Database query failed in GetDataObservations(): %v", err)
+		}
+		http.Error(w, "Database query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	observations := []models.DataObservation{}
+	for rows.Next() {
+		var observation models.DataObservation
+		if err := rows.Scan(
+			&observation.ObservationID, &observation.SeriesID, &observation.Year,
+			&observation.Period, &observation.Value, &observation.Preliminary, &observation.Footnotes,
+			&observation.RecordedAt,
+		); err != nil {
+			if config.IsDevelopmentMode() {
+				log.Printf("❌ [ERROR] Error scanning observation row in GetDataObservations(): %v", err)
+			}
+			http.Error(w, "Error scanning observations", http.StatusInternalServerError)
+			return
+		}
+		observation.PeriodLabel = models.PeriodLabel(observation.Year, observation.Period)
+		observations = append(observations, observation)
+	}
+	services.SortObservations(observations)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(observations)
+}
+
 func RegisterDataRoutes(router *mux.Router, db database.DBQuerier) {
 	router.HandleFunc("/data", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == "POST" {
@@ -262,4 +344,12 @@ func RegisterDataRoutes(router *mux.Router, db database.DBQuerier) {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	}).Methods("GET", "PUT", "DELETE")
+
+	router.HandleFunc("/data/{id}/observations", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			GetDataObservations(w, r, db)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}).Methods("GET")
 }
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"megga-backend/internal/config"
+	"megga-backend/internal/database"
+	"megga-backend/internal/scheduler"
+	"megga-backend/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v4"
+)
+
+const (
+	defaultJobRunsLimit = 50
+	maxJobRunsLimit     = 500
+)
+
+// JobRunner starts a registered background job outside its schedule and
+// returns the ID of the recorded job run.
+type JobRunner interface {
+	RunNow(ctx context.Context, name string) (int, error)
+}
+
+func TriggerIngest(w http.ResponseWriter, r *http.Request, runner JobRunner) {
+	jobRunID, err := runner.RunNow(context.Background(), services.IngestJobName)
+	if errors.Is(err, scheduler.ErrJobRunning) {
+		http.Error(w, "Ingestion is already running", http.StatusConflict)
+		return
+	} else if errors.Is(err, scheduler.ErrNotLeader) {
+		http.Error(w, "Another instance is the leader and runs ingestion", http.StatusConflict)
+		return
+	} else if err != nil {
+		log.Printf("❌ Error starting ingestion: %v", err)
+		http.Error(w, "Error starting ingestion", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":    "Ingestion started",
+		"job_run_id": jobRunID,
+	})
+}
+
+func GetJobRuns(w http.ResponseWriter, r *http.Request, db database.DBQuerier) {
+	limit := defaultJobRunsLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 || parsed > maxJobRunsLimit {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	runs, err := services.GetJobRuns(context.Background(), db, limit)
+	if err != nil {
+		if config.IsDevelopmentMode() {
+			log.Printf("❌ [ERROR] Database query failed in GetJobRuns(): %v", err)
+		}
+		http.Error(w, "Database query error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(runs)
+}
+
+func GetJobRun(w http.ResponseWriter, r *http.Request, db database.DBQuerier) {
+	jobRunID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid job run ID", http.StatusBadRequest)
+		return
+	}
+
+	run, err := services.GetJobRun(context.Background(), db, jobRunID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "Job run not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		if config.IsDevelopmentMode() {
+			log.Printf("❌ [ERROR] Database query failed in GetJobRun(): %v", err)
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(run)
+}
+
+// RegisterJobRoutes expects the admin subrouter, so paths are relative to /admin.
+func RegisterJobRoutes(router *mux.Router, db database.DBQuerier, runner JobRunner) {
+	router.HandleFunc("/jobs/ingest", func(w http.ResponseWriter, r *http.Request) {
+		TriggerIngest(w, r, runner)
+	}).Methods("POST")
+
+	router.HandleFunc("/jobs", func(w http.ResponseWriter, r *http.Request) {
+		GetJobRuns(w, r, db)
+	}).Methods("GET")
+
+	router.HandleFunc("/jobs/{id}", func(w http.ResponseWriter, r *http.Request) {
+		GetJobRun(w, r, db)
+	}).Methods("GET")
+}
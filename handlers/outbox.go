@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"megga-backend/internal/config"
+	"megga-backend/internal/database"
+	"megga-backend/internal/models"
+	"megga-backend/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v4"
+)
+
+const (
+	defaultOutboxLimit = 50
+	maxOutboxLimit     = 500
+)
+
+func GetOutbox(w http.ResponseWriter, r *http.Request, db database.DBQuerier) {
+	status := r.URL.Query().Get("status")
+	if status != "" && !models.IsKnownOutboxStatus(status) {
+		http.Error(w, "Invalid status", http.StatusBadRequest)
+		return
+	}
+
+	limit := defaultOutboxLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 || parsed > maxOutboxLimit {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	letters, err := services.GetOutbox(context.Background(), db, status, limit)
+	if err != nil {
+		if config.IsDevelopmentMode() {
+			log.Printf("❌ [ERROR] Database query failed in GetOutbox(): %v", err)
+		}
+		http.Error(w, "Database query error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(letters)
+}
+
+func RequeueDeadLetter(w http.ResponseWriter, r *http.Request, db database.DBQuerier) {
+	outboxID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid outbox ID", http.StatusBadRequest)
+		return
+	}
+
+	err = services.RequeueDeadLetter(context.Background(), db, outboxID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "Dead letter not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("❌ %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Dead letter requeued"})
+}
+
+func DiscardDeadLetter(w http.ResponseWriter, r *http.Request, db database.DBQuerier) {
+	outboxID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid outbox ID", http.StatusBadRequest)
+		return
+	}
+
+	err = services.DiscardDeadLetter(context.Background(), db, outboxID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "Dead letter not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("❌ %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Dead letter discarded"})
+}
+
+// RegisterOutboxRoutes expects the admin subrouter, so paths are relative to /admin.
+func RegisterOutboxRoutes(router *mux.Router, db database.DBQuerier) {
+	router.HandleFunc("/outbox", func(w http.ResponseWriter, r *http.Request) {
+		GetOutbox(w, r, db)
+	}).Methods("GET")
+
+	router.HandleFunc("/outbox/{id}/requeue", func(w http.ResponseWriter, r *http.Request) {
+		RequeueDeadLetter(w, r, db)
+	}).Methods("POST")
+
+	router.HandleFunc("/outbox/{id}", func(w http.ResponseWriter, r *http.Request) {
+		DiscardDeadLetter(w, r, db)
+	}).Methods("DELETE")
+}
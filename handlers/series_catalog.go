@@ -0,0 +1,297 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"megga-backend/internal/config"
+	"megga-backend/internal/database"
+	"megga-backend/internal/expression"
+	"megga-backend/internal/models"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v4"
+)
+
+type seriesRequest struct {
+	SeriesID string `json:"series_id"`
+	Name     string `json:"name"`
+	Unit     string `json:"unit"`
+	Category string `json:"category"`
+	Active   *bool  `json:"active"`
+	Provider string `json:"provider"`
+	Priority string `json:"priority"`
+	Alias    string `json:"alias"`
+}
+
+func CreateSeries(w http.ResponseWriter, r *http.Request, db database.DBQuerier) {
+	var request seriesRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	request.SeriesID = strings.TrimSpace(request.SeriesID)
+	if request.SeriesID == "" || strings.TrimSpace(request.Name) == "" {
+		http.Error(w, "Missing required fields", http.StatusBadRequest)
+		return
+	}
+
+	if request.Provider == "" {
+		request.Provider = models.ProviderBLS
+	}
+	if !models.IsKnownProvider(request.Provider) {
+		http.Error(w, "Invalid provider", http.StatusBadRequest)
+		return
+	}
+
+	if request.Priority == "" {
+		request.Priority = models.PriorityNormal
+	}
+	if !models.IsKnownPriority(request.Priority) {
+		http.Error(w, "Invalid priority", http.StatusBadRequest)
+		return
+	}
+
+	request.Alias = strings.TrimSpace(request.Alias)
+	if request.Alias != "" && !expression.IsValidAlias(request.Alias) {
+		http.Error(w, "Invalid alias: use letters, digits and underscores, not starting with a digit", http.StatusBadRequest)
+		return
+	}
+
+	series := models.Series{
+		SeriesID: request.SeriesID,
+		Name:     request.Name,
+		Unit:     request.Unit,
+		Category: request.Category,
+		Active:   true,
+		Provider: request.Provider,
+		Priority: request.Priority,
+		Alias:    request.Alias,
+	}
+	if request.Active != nil {
+		series.Active = *request.Active
+	}
+
+	var existingID string
+	err := db.QueryRow(context.Background(), `SELECT series_id FROM series_catalog WHERE series_id = $1`, series.SeriesID).Scan(&existingID)
+	if err == nil {
+		http.Error(w, "Duplicate series_id: This series is already in the catalog.", http.StatusConflict)
+		return
+	} else if err != pgx.ErrNoRows {
+		http.Error(w, "Database query error", http.StatusInternalServerError)
+		return
+	}
+	if status, msg := checkAliasAvailable(db, series.Alias, series.SeriesID); status != 0 {
+		http.Error(w, msg, status)
+		return
+	}
+
+	query := `
+		INSERT INTO series_catalog (series_id, name, unit, category, active, provider, priority, alias, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), NOW(), NOW())
+		RETURNING created_at, updated_at
+	`
+	err = db.QueryRow(context.Background(), query, series.SeriesID, series.Name, series.Unit, series.Category, series.Active, series.Provider, series.Priority, series.Alias).
+		Scan(&series.CreatedAt, &series.UpdatedAt)
+	if err != nil {
+		log.Printf("❌ Database error creating series: %v", err)
+		http.Error(w, "Database insert error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Series created successfully",
+		"series":  series,
+	})
+}
+
+func GetSeriesCatalog(w http.ResponseWriter, r *http.Request, db database.DBQuerier) {
+	query := `
+		SELECT series_id, name, unit, category, active, provider, priority, COALESCE(alias, ''), created_at, updated_at
+		FROM series_catalog
+		ORDER BY series_id
+	`
+	rows, err := db.Query(context.Background(), query)
+	if err != nil {
+		if config.IsDevelopmentMode() {
+			log.Printf("❌ [ERROR] Database query failed in GetSeriesCatalog(): %v", err)
+		}
+		http.Error(w, "Database query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	catalog := []models.Series{}
+	for rows.Next() {
+		var series models.Series
+		if err := rows.Scan(&series.SeriesID, &series.Name, &series.Unit, &series.Category, &series.Active, &series.Provider, &series.Priority, &series.Alias, &series.CreatedAt, &series.UpdatedAt); err != nil {
+			http.Error(w, "Error scanning series", http.StatusInternalServerError)
+			return
+		}
+		catalog = append(catalog, series)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(catalog)
+}
+
+func GetSeriesByID(w http.ResponseWriter, r *http.Request, db database.DBQuerier) {
+	seriesID := mux.Vars(r)["id"]
+	if seriesID == "" {
+		http.Error(w, "Invalid series ID", http.StatusBadRequest)
+		return
+	}
+
+	var series models.Series
+	query := `
+		SELECT series_id, name, unit, category, active, provider, priority, COALESCE(alias, ''), created_at, updated_at
+		FROM series_catalog WHERE series_id = $1
+	`
+	err := db.QueryRow(context.Background(), query, seriesID).
+		Scan(&series.SeriesID, &series.Name, &series.Unit, &series.Category, &series.Active, &series.Provider, &series.Priority, &series.Alias, &series.CreatedAt, &series.UpdatedAt)
+	if err == pgx.ErrNoRows {
+		http.Error(w, "Series not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(series)
+}
+
+func UpdateSeries(w http.ResponseWriter, r *http.Request, db database.DBQuerier) {
+	seriesID := mux.Vars(r)["id"]
+	if seriesID == "" {
+		http.Error(w, "Invalid series ID", http.StatusBadRequest)
+		return
+	}
+
+	var request seriesRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if strings.TrimSpace(request.Name) == "" || request.Active == nil {
+		http.Error(w, "Missing required fields", http.StatusBadRequest)
+		return
+	}
+
+	if request.Provider == "" {
+		request.Provider = models.ProviderBLS
+	}
+	if !models.IsKnownProvider(request.Provider) {
+		http.Error(w, "Invalid provider", http.StatusBadRequest)
+		return
+	}
+
+	if request.Priority == "" {
+		request.Priority = models.PriorityNormal
+	}
+	if !models.IsKnownPriority(request.Priority) {
+		http.Error(w, "Invalid priority", http.StatusBadRequest)
+		return
+	}
+
+	request.Alias = strings.TrimSpace(request.Alias)
+	if request.Alias != "" && !expression.IsValidAlias(request.Alias) {
+		http.Error(w, "Invalid alias: use letters, digits and underscores, not starting with a digit", http.StatusBadRequest)
+		return
+	}
+
+	if status, msg := checkAliasAvailable(db, request.Alias, seriesID); status != 0 {
+		http.Error(w, msg, status)
+		return
+	}
+
+	query := `
+		UPDATE series_catalog
+		SET name = $1, unit = $2, category = $3, active = $4, provider = $5, priority = $6, alias = NULLIF($7, ''), updated_at = NOW()
+		WHERE series_id = $8
+	`
+	res, err := db.Exec(context.Background(), query, request.Name, request.Unit, request.Category, *request.Active, request.Provider, request.Priority, request.Alias, seriesID)
+	if err != nil {
+		if config.IsDevelopmentMode() {
+			log.Printf("❌ [ERROR] Database error in UpdateSeries(): %v", err)
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	if res.RowsAffected() == 0 {
+		http.Error(w, "Series not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Series updated successfully"})
+}
+
+func DeleteSeries(w http.ResponseWriter, r *http.Request, db database.DBQuerier) {
+	seriesID := mux.Vars(r)["id"]
+	if seriesID == "" {
+		http.Error(w, "Invalid series ID", http.StatusBadRequest)
+		return
+	}
+
+	res, err := db.Exec(context.Background(), "DELETE FROM series_catalog WHERE series_id = $1", seriesID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	if res.RowsAffected() == 0 {
+		http.Error(w, "Series not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Series deleted successfully"})
+}
+
+// checkAliasAvailable returns an HTTP status and message when another series
+// already uses the alias, or 0 when it is free or empty.
+func checkAliasAvailable(db database.DBQuerier, alias, seriesID string) (int, string) {
+	if alias == "" {
+		return 0, ""
+	}
+	var existingID string
+	err := db.QueryRow(context.Background(), `SELECT series_id FROM series_catalog WHERE alias = $1 AND series_id <> $2`, alias, seriesID).Scan(&existingID)
+	if err == nil {
+		return http.StatusConflict, "Duplicate alias: " + existingID + " already uses it."
+	} else if err != pgx.ErrNoRows {
+		return http.StatusInternalServerError, "Database query error"
+	}
+	return 0, ""
+}
+
+// RegisterSeriesCatalogRoutes expects the admin subrouter, so paths are relative to /admin.
+func RegisterSeriesCatalogRoutes(router *mux.Router, db database.DBQuerier) {
+	router.HandleFunc("/series", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			CreateSeries(w, r, db)
+		} else if r.Method == "GET" {
+			GetSeriesCatalog(w, r, db)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}).Methods("POST", "GET")
+
+	router.HandleFunc("/series/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			GetSeriesByID(w, r, db)
+		} else if r.Method == "PUT" {
+			UpdateSeries(w, r, db)
+		} else if r.Method == "DELETE" {
+			DeleteSeries(w, r, db)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}).Methods("GET", "PUT", "DELETE")
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"megga-backend/internal/database"
+	"megga-backend/internal/models"
+	"megga-backend/internal/services"
+	"net/http"
+	"time"
+)
+
+type backtestRequest struct {
+	models.Threshold
+	From string `json:"from"` // YYYY-MM-DD
+	To   string `json:"to"`   // YYYY-MM-DD, today if empty
+}
+
+// BacktestThreshold replays a threshold definition, which need not be saved,
+// over the stored history and reports each period it would have fired on.
+func BacktestThreshold(w http.ResponseWriter, r *http.Request, db database.DBQuerier) {
+	var request backtestRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	from, err := time.Parse("2006-01-02", request.From)
+	if err != nil {
+		http.Error(w, "Invalid or missing from date: use YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	to := time.Now().UTC().Truncate(24 * time.Hour)
+	if request.To != "" {
+		if to, err = time.Parse("2006-01-02", request.To); err != nil {
+			http.Error(w, "Invalid to date: use YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+	}
+	if to.Before(from) {
+		http.Error(w, "The to date cannot be before the from date", http.StatusBadRequest)
+		return
+	}
+
+	if status, msg := prepareThresholdDefinition(db, &request.Threshold); status != 0 {
+		http.Error(w, msg, status)
+		return
+	}
+
+	backtest, err := services.BacktestThreshold(db, request.Threshold, from, to)
+	if errors.Is(err, services.ErrUnknownData) {
+		http.Error(w, "Invalid data ID: "+err.Error(), http.StatusBadRequest)
+		return
+	} else if err != nil {
+		log.Printf("❌ Error backtesting threshold: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("✅ Backtest from %s to %s: %d periods evaluated, %d firings", backtest.From, backtest.To, backtest.Evaluated, len(backtest.Firings))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(backtest)
+}
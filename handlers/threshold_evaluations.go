@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"megga-backend/internal/config"
+	"megga-backend/internal/database"
+	"megga-backend/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v4"
+)
+
+const (
+	defaultThresholdEvaluationsLimit = 50
+	maxThresholdEvaluationsLimit     = 500
+)
+
+// GetThresholdEvaluations lists a threshold's most recent evaluations, newest
+// first, with the values each one read and why the threshold did or did not
+// fire.
+func GetThresholdEvaluations(w http.ResponseWriter, r *http.Request, db database.DBQuerier) {
+	thresholdID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || thresholdID <= 0 {
+		http.Error(w, "Invalid or missing threshold ID", http.StatusBadRequest)
+		return
+	}
+
+	limit := defaultThresholdEvaluationsLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 || parsed > maxThresholdEvaluationsLimit {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	var exists int
+	err = db.QueryRow(context.Background(), "SELECT 1 FROM thresholds WHERE threshold_id = $1", thresholdID).Scan(&exists)
+	if err == pgx.ErrNoRows {
+		http.Error(w, "Threshold not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	evaluations, err := services.GetThresholdEvaluations(context.Background(), db, thresholdID, limit)
+	if err != nil {
+		if config.IsDevelopmentMode() {
+			log.Printf("❌ [ERROR] Database query failed in GetThresholdEvaluations(): %v", err)
+		}
+		http.Error(w, "Database query error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(evaluations)
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"megga-backend/internal/database"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v4"
+)
+
+// maxSnoozeHours caps a snooze at a year; longer pauses should use an expiry
+// or delete the threshold.
+const maxSnoozeHours = 24 * 365
+
+type snoozeRequest struct {
+	Hours int `json:"hours"` // How long to pause the threshold for
+}
+
+// SnoozeThreshold pauses a threshold for a number of hours. It keeps its
+// recipients and state, and the monitor skips it until the pause runs out.
+func SnoozeThreshold(w http.ResponseWriter, r *http.Request, db database.DBQuerier) {
+	thresholdID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || thresholdID <= 0 {
+		http.Error(w, "Invalid or missing threshold ID", http.StatusBadRequest)
+		return
+	}
+
+	var request snoozeRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if request.Hours <= 0 || request.Hours > maxSnoozeHours {
+		http.Error(w, "Hours must be between 1 and 8760", http.StatusBadRequest)
+		return
+	}
+
+	var pausedUntil time.Time
+	err = db.QueryRow(context.Background(), `
+		UPDATE thresholds
+		SET paused_until = NOW() + make_interval(hours => $1)
+		WHERE threshold_id = $2
+		RETURNING paused_until`, request.Hours, thresholdID).Scan(&pausedUntil)
+	if err == pgx.ErrNoRows {
+		http.Error(w, "Threshold not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("❌ Error snoozing Threshold ID %d: %v", thresholdID, err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("😴 Snoozed Threshold ID %d until %s", thresholdID, pausedUntil.Format(time.RFC3339))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":      "Threshold snoozed successfully",
+		"paused_until": pausedUntil,
+	})
+}
+
+// ResumeThreshold lifts a threshold's pause so the next check includes it.
+func ResumeThreshold(w http.ResponseWriter, r *http.Request, db database.DBQuerier) {
+	thresholdID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || thresholdID <= 0 {
+		http.Error(w, "Invalid or missing threshold ID", http.StatusBadRequest)
+		return
+	}
+
+	res, err := db.Exec(context.Background(), "UPDATE thresholds SET paused_until = NULL WHERE threshold_id = $1", thresholdID)
+	if err != nil {
+		log.Printf("❌ Error resuming Threshold ID %d: %v", thresholdID, err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if res.RowsAffected() == 0 {
+		http.Error(w, "Threshold not found", http.StatusNotFound)
+		return
+	}
+
+	log.Printf("▶️ Resumed Threshold ID %d", thresholdID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Threshold resumed successfully"})
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"megga-backend/internal/database"
+	"megga-backend/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v4"
+)
+
+// PreviewThreshold runs a threshold's check against the current data and
+// returns the letters it would send, without sending or saving anything.
+func PreviewThreshold(w http.ResponseWriter, r *http.Request, db database.DBQuerier) {
+	thresholdID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || thresholdID <= 0 {
+		http.Error(w, "Invalid or missing threshold ID", http.StatusBadRequest)
+		return
+	}
+
+	preview, err := services.PreviewThreshold(context.Background(), db, thresholdID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "Threshold not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("❌ Error previewing Threshold ID %d: %v", thresholdID, err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(preview)
+}
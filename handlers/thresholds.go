@@ -3,12 +3,17 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"log"
 	"megga-backend/internal/database"
+	"megga-backend/internal/expression"
 	"megga-backend/internal/models"
+	"megga-backend/internal/services"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/jackc/pgx/v4"
@@ -36,15 +41,22 @@ func CreateThreshold(w http.ResponseWriter, r *http.Request, db database.DBQueri
 
 	log.Printf("✅ Decoded Request: %+v", request)
 
-	if request.UserID == 0 || request.DataID == 0 || request.ThresholdValue == 0 || len(request.Recipients) == 0 {
-		log.Printf("❌ Missing Required Fields: UserID=%d, DataID=%d, ThresholdValue=%f, Recipients=%v",
-			request.UserID, request.DataID, request.ThresholdValue, request.Recipients)
+	if status, msg := prepareThresholdDefinition(db, &request); status != 0 {
+		http.Error(w, msg, status)
+		return
+	}
+	if request.UserID == 0 || len(request.Recipients) == 0 {
+		log.Printf("❌ Missing Required Fields: UserID=%d, Recipients=%v", request.UserID, request.Recipients)
 		http.Error(w, "Missing required fields", http.StatusBadRequest)
 		return
 	}
+	if msg := validateThresholdExpiry(request.ExpiresAt); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
 
-	log.Printf("✅ Preparing to Insert: UserID=%d, DataID=%d, ThresholdValue=%.2f, NotifyUser=%t, Recipients=%v",
-		request.UserID, request.DataID, request.ThresholdValue, request.NotifyUser, request.Recipients)
+	log.Printf("✅ Preparing to Insert: UserID=%d, DataID=%d, ThresholdValue=%.2f, Mode=%s, Direction=%s, NotifyUser=%t, Recipients=%v",
+		request.UserID, request.DataID, request.ThresholdValue, request.Mode, request.Direction, request.NotifyUser, request.Recipients)
 
 	tx, err := db.BeginTx(context.Background(), pgx.TxOptions{})
 	if err != nil {
@@ -54,36 +66,12 @@ func CreateThreshold(w http.ResponseWriter, r *http.Request, db database.DBQueri
 	}
 	defer tx.Rollback(context.Background())
 
-	var thresholdID int
-	query := `INSERT INTO thresholds (user_id, data_id, threshold_value, notify_user, created_at)
-	          VALUES ($1, $2, $3, $4, NOW()) RETURNING threshold_id`
-	err = tx.QueryRow(context.Background(), query, request.UserID, request.DataID, request.ThresholdValue, request.NotifyUser).
-		Scan(&thresholdID)
-
-	if err != nil {
-		log.Printf("❌ Error Inserting Threshold: %v", err)
-		http.Error(w, "Database error", http.StatusInternalServerError)
+	thresholdID, msg := insertThreshold(tx, request)
+	if msg != "" {
+		http.Error(w, msg, http.StatusInternalServerError)
 		return
 	}
 
-	log.Printf("✅ Inserted Threshold: ID=%d", thresholdID)
-
-	for _, recipientID := range request.Recipients {
-		log.Printf("🔍 Attempting to insert recipient: ThresholdID=%d, RecipientID=%d", thresholdID, recipientID)
-
-		_, err := tx.Exec(context.Background(),
-			"INSERT INTO threshold_recipients (threshold_id, recipient_id) VALUES ($1, $2)",
-			thresholdID, recipientID)
-
-		if err != nil {
-			log.Printf("❌ Error inserting recipient for threshold: %v", err)
-			http.Error(w, "Error associating recipients", http.StatusInternalServerError)
-			return
-		}
-
-		log.Printf("✅ Successfully inserted: ThresholdID=%d, RecipientID=%d", thresholdID, recipientID)
-	}
-
 	if err := tx.Commit(context.Background()); err != nil {
 		log.Printf("❌ Error Committing Transaction: %v", err)
 		http.Error(w, "Database error", http.StatusInternalServerError)
@@ -111,19 +99,39 @@ func GetThresholdById(w http.ResponseWriter, r *http.Request, db database.DBQuer
 	log.Printf("🔍 Fetching details for threshold ID: %d", thresholdID)
 
 	type ThresholdWithRecipients struct {
-		ThresholdID    int     `json:"threshold_id"`
-		DataID         int     `json:"data_id"`
-		Name           string  `json:"name"`
-		ThresholdValue float64 `json:"threshold_value"`
-		NotifyUser     bool    `json:"notify_user"`
-		Recipients     []int64 `json:"recipients"`
+		ThresholdID    int      `json:"threshold_id"`
+		DataID         int      `json:"data_id"`
+		Name           string   `json:"name"`
+		ThresholdValue float64  `json:"threshold_value"`
+		Mode           string   `json:"mode"`
+		Direction      string   `json:"direction"`
+		BaselineValue  *float64 `json:"baseline_value,omitempty"`
+		NotifyUser     bool     `json:"notify_user"`
+		Recipients     []int64  `json:"recipients"`
+
+		State           string     `json:"state"`
+		Hysteresis      float64    `json:"hysteresis"`
+		CooldownHours   int        `json:"cooldown_hours"`
+		LastTriggeredAt *time.Time `json:"last_triggered_at,omitempty"`
+
+		Operator   string                      `json:"operator"`
+		Conditions []models.ThresholdCondition `json:"conditions"`
+		Expression string                      `json:"expression,omitempty"`
+
+		PausedUntil *time.Time `json:"paused_until,omitempty"`
+		ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+
+		Escalations   []models.EscalationTier `json:"escalations"`
+		BreachPeriods int                     `json:"breach_periods"`
 	}
 
 	var threshold ThresholdWithRecipients
 
 	query := `
-		SELECT t.threshold_id, t.data_id, d.name, t.threshold_value, t.notify_user, 
-		       COALESCE(ARRAY_AGG(tr.recipient_id) FILTER (WHERE tr.recipient_id IS NOT NULL), ARRAY[]::BIGINT[]) AS recipients
+		SELECT t.threshold_id, t.data_id, d.name, t.threshold_value, t.mode, t.direction, t.baseline_value, t.notify_user, 
+		       COALESCE(ARRAY_AGG(tr.recipient_id) FILTER (WHERE tr.recipient_id IS NOT NULL), ARRAY[]::BIGINT[]) AS recipients,
+		       t.state, t.hysteresis, t.cooldown_hours, t.last_triggered_at, t.operator, t.expression,
+		       t.paused_until, t.expires_at, t.breach_periods
 		FROM thresholds t
 		JOIN data d ON t.data_id = d.data_id
 		LEFT JOIN threshold_recipients tr ON t.threshold_id = tr.threshold_id
@@ -136,7 +144,9 @@ func GetThresholdById(w http.ResponseWriter, r *http.Request, db database.DBQuer
 
 	err = db.QueryRow(context.Background(), query, thresholdID).Scan(
 		&threshold.ThresholdID, &threshold.DataID, &threshold.Name,
-		&threshold.ThresholdValue, &threshold.NotifyUser, pq.Array(&threshold.Recipients),
+		&threshold.ThresholdValue, &threshold.Mode, &threshold.Direction, &threshold.BaselineValue, &threshold.NotifyUser, pq.Array(&threshold.Recipients),
+		&threshold.State, &threshold.Hysteresis, &threshold.CooldownHours, &threshold.LastTriggeredAt, &threshold.Operator, &threshold.Expression,
+		&threshold.PausedUntil, &threshold.ExpiresAt, &threshold.BreachPeriods,
 	)
 
 	if err == pgx.ErrNoRows {
@@ -150,6 +160,28 @@ func GetThresholdById(w http.ResponseWriter, r *http.Request, db database.DBQuer
 		return
 	}
 
+	conditions, err := services.GetThresholdConditions(db, []int{thresholdID})
+	if err != nil {
+		log.Printf("❌ Database Query Error: %v", err)
+		http.Error(w, "Database query error", http.StatusInternalServerError)
+		return
+	}
+	threshold.Conditions = conditions[thresholdID]
+	if threshold.Conditions == nil {
+		threshold.Conditions = []models.ThresholdCondition{}
+	}
+
+	escalations, err := services.GetEscalationTiers(context.Background(), db, []int{thresholdID})
+	if err != nil {
+		log.Printf("❌ Database Query Error: %v", err)
+		http.Error(w, "Database query error", http.StatusInternalServerError)
+		return
+	}
+	threshold.Escalations = escalations[thresholdID]
+	if threshold.Escalations == nil {
+		threshold.Escalations = []models.EscalationTier{}
+	}
+
 	log.Printf("✅ Retrieved Threshold: %+v", threshold)
 
 	w.Header().Set("Content-Type", "application/json")
@@ -171,11 +203,88 @@ func UpdateThreshold(w http.ResponseWriter, r *http.Request, db database.DBQueri
 	}
 	defer r.Body.Close()
 
-	var threshold models.Threshold
-	if err := json.Unmarshal(body, &threshold); err != nil {
+	// clearExpression turns a rule back into an ordinary threshold, since an
+	// empty expression keeps the stored one.
+	var request struct {
+		models.Threshold
+		ClearExpression bool `json:"clearExpression"`
+	}
+	if err := json.Unmarshal(body, &request); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
+	threshold := request.Threshold
+	if request.ClearExpression && threshold.Expression != "" {
+		http.Error(w, "An update cannot both set and clear the expression", http.StatusBadRequest)
+		return
+	}
+
+	// An empty mode or expression keeps the stored one, so the value,
+	// direction and re-arm settings are checked against what the threshold
+	// will be. A rule has no value or mode of its own, so as on create those
+	// checks are skipped for it. The value and hysteresis are always replaced,
+	// so those come from the request.
+	var storedMode, storedExpression string
+	err = db.QueryRow(context.Background(), "SELECT mode, expression FROM thresholds WHERE threshold_id = $1", id).Scan(&storedMode, &storedExpression)
+	if err == pgx.ErrNoRows {
+		http.Error(w, "Threshold not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("❌ Database Query Error: %v", err)
+		http.Error(w, "Database query error", http.StatusInternalServerError)
+		return
+	}
+	mode := threshold.Mode
+	if mode == "" {
+		mode = storedMode
+	}
+	if threshold.Expression != "" || (storedExpression != "" && !request.ClearExpression) {
+		if len(threshold.Conditions) > 0 {
+			http.Error(w, "A threshold with an expression cannot also have conditions", http.StatusBadRequest)
+			return
+		}
+		if threshold.Operator != "" {
+			http.Error(w, "A threshold with an expression cannot also have an operator", http.StatusBadRequest)
+			return
+		}
+		if threshold.Expression != "" {
+			if _, status, msg := checkThresholdExpression(db, threshold.Expression); status != 0 {
+				http.Error(w, msg, status)
+				return
+			}
+		}
+	} else {
+		if msg := validateThresholdMode(mode, threshold.ThresholdValue); msg != "" {
+			http.Error(w, msg, http.StatusBadRequest)
+			return
+		}
+		if threshold.Direction != "" {
+			if msg := validateThresholdDirection(mode, threshold.Direction); msg != "" {
+				http.Error(w, msg, http.StatusBadRequest)
+				return
+			}
+		}
+		if msg := validateThresholdRearm(mode, threshold.ThresholdValue, threshold.Hysteresis, threshold.CooldownHours); msg != "" {
+			http.Error(w, msg, http.StatusBadRequest)
+			return
+		}
+	}
+	if threshold.Operator != "" || threshold.Conditions != nil {
+		if msg := validateThresholdConditions(threshold.Operator, threshold.Conditions, threshold.Hysteresis); msg != "" {
+			http.Error(w, msg, http.StatusBadRequest)
+			return
+		}
+	}
+	if threshold.Escalations != nil {
+		if msg := validateEscalations(threshold.Escalations); msg != "" {
+			http.Error(w, msg, http.StatusBadRequest)
+			return
+		}
+	}
+	if msg := validateThresholdExpiry(threshold.ExpiresAt); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
 
 	log.Printf("✏️ Updating Threshold ID %d: %+v", id, threshold)
 
@@ -186,19 +295,65 @@ func UpdateThreshold(w http.ResponseWriter, r *http.Request, db database.DBQueri
 	}
 	defer tx.Rollback(context.Background())
 
+	// An empty mode or direction keeps the current one, except that a switch to
+	// absolute turns "either" into "rise". Thresholds created before modes
+	// existed get their baseline the first time they are updated. Any update
+	// re-arms the threshold and starts its breach count over, so the new
+	// settings take effect on the next check.
+	// An empty operator or expression also keeps the current one, unless the
+	// expression is cleared, while the expiry is replaced like the re-arm
+	// settings, so leaving it out clears it.
 	query := `
 		UPDATE thresholds
-		SET threshold_value = $1, notify_user = $2
-		WHERE threshold_id = $3
+		SET threshold_value = $1, notify_user = $2, mode = COALESCE(NULLIF($3, ''), mode),
+			direction = COALESCE(NULLIF($4, ''), CASE
+				WHEN COALESCE(NULLIF($3, ''), mode) = 'absolute' AND direction = 'either' THEN 'rise'
+				ELSE direction
+			END),
+			baseline_value = COALESCE(baseline_value, (SELECT latest_value FROM data WHERE data_id = thresholds.data_id)),
+			hysteresis = $5, cooldown_hours = $6, state = 'armed', operator = COALESCE(NULLIF($7, ''), operator),
+			expression = CASE WHEN $11 THEN '' ELSE COALESCE(NULLIF($8, ''), expression) END, expires_at = $9,
+			breach_periods = 0, breach_year = '', breach_period = ''
+		WHERE threshold_id = $10
 		RETURNING threshold_id
 	`
-	err = tx.QueryRow(context.Background(), query, threshold.ThresholdValue, threshold.NotifyUser, id).Scan(&threshold.ThresholdID)
+	err = tx.QueryRow(context.Background(), query, threshold.ThresholdValue, threshold.NotifyUser, threshold.Mode, threshold.Direction,
+		threshold.Hysteresis, threshold.CooldownHours, threshold.Operator, threshold.Expression, threshold.ExpiresAt, id, request.ClearExpression).Scan(&threshold.ThresholdID)
 	if err != nil {
 		log.Printf("❌ Error updating threshold: %v", err)
 		http.Error(w, "Database update error", http.StatusInternalServerError)
 		return
 	}
 
+	// Leaving conditions out keeps them; sending a list, even an empty one,
+	// replaces them. A new expression replaces any conditions the threshold had.
+	if threshold.Conditions != nil || threshold.Expression != "" {
+		_, err := tx.Exec(context.Background(), "DELETE FROM threshold_conditions WHERE threshold_id = $1", id)
+		if err != nil {
+			http.Error(w, "Error removing old conditions", http.StatusInternalServerError)
+			return
+		}
+		if err := insertThresholdConditions(tx, id, threshold.Conditions); err != nil {
+			log.Printf("❌ Error inserting conditions for threshold: %v", err)
+			http.Error(w, "Error saving conditions", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// Escalation tiers are replaced the same way.
+	if threshold.Escalations != nil {
+		_, err := tx.Exec(context.Background(), "DELETE FROM threshold_escalations WHERE threshold_id = $1", id)
+		if err != nil {
+			http.Error(w, "Error removing old escalation tiers", http.StatusInternalServerError)
+			return
+		}
+		if err := insertEscalationTiers(tx, id, threshold.Escalations); err != nil {
+			log.Printf("❌ Error inserting escalation tiers for threshold: %v", err)
+			http.Error(w, "Error saving escalation tiers", http.StatusInternalServerError)
+			return
+		}
+	}
+
 	var existingRecipientIDs []int
 	getRecipientsQuery := `SELECT recipient_id FROM threshold_recipients WHERE threshold_id = $1`
 	rows, err := tx.Query(context.Background(), getRecipientsQuery, id)
@@ -294,7 +449,265 @@ func DeleteThreshold(w http.ResponseWriter, r *http.Request, db database.DBQueri
 	json.NewEncoder(w).Encode(map[string]string{"message": "Threshold deleted successfully"})
 }
 
+// prepareThresholdDefinition checks what a threshold watches and when it
+// fires, filling in the defaults for mode, direction and operator. It returns
+// an HTTP status and message when the definition cannot be used.
+func prepareThresholdDefinition(db database.DBQuerier, request *models.Threshold) (int, string) {
+	// A rule replaces the threshold's own condition, and its first series
+	// becomes the one the letters are about unless dataId says otherwise.
+	if request.Expression != "" {
+		dataID, status, msg := checkThresholdExpression(db, request.Expression)
+		if status != 0 {
+			return status, msg
+		}
+		if len(request.Conditions) > 0 {
+			return http.StatusBadRequest, "A threshold with an expression cannot also have conditions"
+		}
+		if request.Operator != "" {
+			return http.StatusBadRequest, "A threshold with an expression cannot also have an operator"
+		}
+		if request.DataID == 0 {
+			request.DataID = dataID
+		}
+	}
+
+	if request.DataID == 0 || (request.ThresholdValue == 0 && request.Expression == "") {
+		log.Printf("❌ Missing Required Fields: DataID=%d, ThresholdValue=%f", request.DataID, request.ThresholdValue)
+		return http.StatusBadRequest, "Missing required fields"
+	}
+
+	if request.Mode == "" {
+		request.Mode = models.ThresholdModePercentPrevious
+	}
+	if request.Direction == "" {
+		request.Direction = models.ThresholdDirectionEither
+		if request.Mode == models.ThresholdModeAbsolute {
+			request.Direction = models.ThresholdDirectionRise
+		}
+	}
+	if request.Expression == "" {
+		if msg := validateThresholdMode(request.Mode, request.ThresholdValue); msg != "" {
+			return http.StatusBadRequest, msg
+		}
+		if msg := validateThresholdDirection(request.Mode, request.Direction); msg != "" {
+			return http.StatusBadRequest, msg
+		}
+		if msg := validateThresholdRearm(request.Mode, request.ThresholdValue, request.Hysteresis, request.CooldownHours); msg != "" {
+			return http.StatusBadRequest, msg
+		}
+	}
+	if request.Operator == "" {
+		request.Operator = models.ThresholdOperatorAnd
+	}
+	if msg := validateThresholdConditions(request.Operator, request.Conditions, request.Hysteresis); msg != "" {
+		return http.StatusBadRequest, msg
+	}
+	if msg := validateEscalations(request.Escalations); msg != "" {
+		return http.StatusBadRequest, msg
+	}
+	return 0, ""
+}
+
+// validateThresholdMode returns a client-facing message when the mode is
+// unknown or the value does not make sense for it.
+func validateThresholdMode(mode string, value float64) string {
+	if !models.IsKnownThresholdMode(mode) {
+		return "Invalid mode: must be absolute, percent_previous, percent_since_created or percent_yoy"
+	}
+	if value <= 0 {
+		if mode == models.ThresholdModeAbsolute {
+			return "Threshold value must be a positive price level"
+		}
+		return "Threshold value must be a positive percentage"
+	}
+	return ""
+}
+
+// validateThresholdDirection checks the direction against the mode.
+func validateThresholdDirection(mode, direction string) string {
+	if !models.IsKnownThresholdDirection(direction) {
+		return "Invalid direction: must be rise, fall or either"
+	}
+	if mode == models.ThresholdModeAbsolute && direction == models.ThresholdDirectionEither {
+		return "Absolute thresholds must have a direction of rise or fall"
+	}
+	return ""
+}
+
+// validateThresholdRearm checks the hysteresis band and cooldown. A percent
+// threshold's band must be narrower than its limit or it could never re-arm.
+func validateThresholdRearm(mode string, value, hysteresis float64, cooldownHours int) string {
+	if hysteresis < 0 {
+		return "Hysteresis cannot be negative"
+	}
+	if cooldownHours < 0 {
+		return "Cooldown hours cannot be negative"
+	}
+	if mode != "" && mode != models.ThresholdModeAbsolute && hysteresis >= value {
+		return "Hysteresis must be smaller than the threshold value"
+	}
+	return ""
+}
+
+// validateThresholdExpiry checks that an expiry, if one is set, has not
+// already passed.
+func validateThresholdExpiry(expiresAt *time.Time) string {
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return "Expiry must be in the future"
+	}
+	return ""
+}
+
+// validateThresholdConditions checks the operator, which may be empty on
+// updates that keep it, and the extra conditions of a compound threshold,
+// filling in each condition's default mode and direction.
+func validateThresholdConditions(operator string, conditions []models.ThresholdCondition, hysteresis float64) string {
+	if operator != "" && !models.IsKnownThresholdOperator(operator) {
+		return "Invalid operator: must be and or or"
+	}
+	for i := range conditions {
+		condition := &conditions[i]
+		if condition.DataID == 0 {
+			return "Each condition needs a data ID"
+		}
+		if condition.Mode == "" {
+			condition.Mode = models.ThresholdModePercentPrevious
+		}
+		if condition.Direction == "" {
+			condition.Direction = models.ThresholdDirectionEither
+			if condition.Mode == models.ThresholdModeAbsolute {
+				condition.Direction = models.ThresholdDirectionRise
+			}
+		}
+		if msg := validateThresholdMode(condition.Mode, condition.Value); msg != "" {
+			return msg
+		}
+		if msg := validateThresholdDirection(condition.Mode, condition.Direction); msg != "" {
+			return msg
+		}
+		if msg := validateThresholdRearm(condition.Mode, condition.Value, hysteresis, 0); msg != "" {
+			return msg
+		}
+	}
+	return ""
+}
+
+// validateEscalations checks a threshold's escalation tiers. Each waits for
+// at least two periods, since the first is the threshold firing, no two wait
+// for the same number, and each adds recipients or a letter of its own.
+func validateEscalations(escalations []models.EscalationTier) string {
+	seen := make(map[int]bool)
+	for _, tier := range escalations {
+		if tier.AfterPeriods < 2 {
+			return "Each escalation tier must wait for at least 2 periods"
+		}
+		if seen[tier.AfterPeriods] {
+			return "Escalation tiers must wait for different numbers of periods"
+		}
+		seen[tier.AfterPeriods] = true
+		if len(tier.Recipients) == 0 && strings.TrimSpace(tier.LetterTemplate) == "" {
+			return "Each escalation tier needs recipients or a letter template"
+		}
+	}
+	return ""
+}
+
+// checkThresholdExpression parses a rule and resolves the series it names,
+// returning the data ID of the first one, or an HTTP status and message when
+// the rule cannot be used.
+func checkThresholdExpression(db database.DBQuerier, text string) (int, int, string) {
+	rule, err := expression.Parse(text)
+	if err != nil {
+		return 0, http.StatusBadRequest, "Invalid expression: " + err.Error()
+	}
+	refs, err := services.ResolveSeriesRefs(db, rule.Series())
+	if errors.Is(err, services.ErrUnknownSeries) {
+		return 0, http.StatusBadRequest, "Invalid expression: " + err.Error()
+	} else if err != nil {
+		log.Printf("❌ Error resolving expression series: %v", err)
+		return 0, http.StatusInternalServerError, "Database error"
+	}
+	return refs[rule.Series()[0]].DataID, 0, ""
+}
+
+// insertThreshold stores a checked threshold with its conditions and
+// recipients inside tx. It returns the new threshold's ID, or a client-facing
+// message when a step failed.
+func insertThreshold(tx pgx.Tx, request models.Threshold) (int, string) {
+	var thresholdID int
+	query := `INSERT INTO thresholds (user_id, data_id, threshold_value, mode, direction, baseline_value, notify_user, hysteresis, cooldown_hours, operator, expression, expires_at, created_at)
+	          VALUES ($1, $2, $3, $4, $5, (SELECT latest_value FROM data WHERE data_id = $2), $6, $7, $8, $9, $10, $11, NOW()) RETURNING threshold_id`
+	err := tx.QueryRow(context.Background(), query, request.UserID, request.DataID, request.ThresholdValue, request.Mode, request.Direction, request.NotifyUser,
+		request.Hysteresis, request.CooldownHours, request.Operator, request.Expression, request.ExpiresAt).
+		Scan(&thresholdID)
+
+	if err != nil {
+		log.Printf("❌ Error Inserting Threshold: %v", err)
+		return 0, "Database error"
+	}
+
+	log.Printf("✅ Inserted Threshold: ID=%d", thresholdID)
+
+	if err := insertThresholdConditions(tx, thresholdID, request.Conditions); err != nil {
+		log.Printf("❌ Error inserting conditions for threshold: %v", err)
+		return 0, "Error saving conditions"
+	}
+	if err := insertEscalationTiers(tx, thresholdID, request.Escalations); err != nil {
+		log.Printf("❌ Error inserting escalation tiers for threshold: %v", err)
+		return 0, "Error saving escalation tiers"
+	}
+
+	for _, recipientID := range request.Recipients {
+		log.Printf("🔍 Attempting to insert recipient: ThresholdID=%d, RecipientID=%d", thresholdID, recipientID)
+
+		_, err := tx.Exec(context.Background(),
+			"INSERT INTO threshold_recipients (threshold_id, recipient_id) VALUES ($1, $2)",
+			thresholdID, recipientID)
+
+		if err != nil {
+			log.Printf("❌ Error inserting recipient for threshold: %v", err)
+			return 0, "Error associating recipients"
+		}
+
+		log.Printf("✅ Successfully inserted: ThresholdID=%d, RecipientID=%d", thresholdID, recipientID)
+	}
+	return thresholdID, ""
+}
+
+// insertThresholdConditions stores a compound threshold's extra conditions in
+// order, each with its own baseline for percent_since_created.
+func insertThresholdConditions(tx pgx.Tx, thresholdID int, conditions []models.ThresholdCondition) error {
+	for i, condition := range conditions {
+		_, err := tx.Exec(context.Background(), `
+			INSERT INTO threshold_conditions (threshold_id, data_id, mode, direction, value, baseline_value, position)
+			VALUES ($1, $2, $3, $4, $5, (SELECT latest_value FROM data WHERE data_id = $2), $6)`,
+			thresholdID, condition.DataID, condition.Mode, condition.Direction, condition.Value, i+1)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// insertEscalationTiers stores a threshold's escalation tiers.
+func insertEscalationTiers(tx pgx.Tx, thresholdID int, escalations []models.EscalationTier) error {
+	for _, tier := range escalations {
+		_, err := tx.Exec(context.Background(), `
+			INSERT INTO threshold_escalations (threshold_id, after_periods, recipient_ids, letter_subject, letter_template)
+			VALUES ($1, $2, $3, $4, $5)`,
+			thresholdID, tier.AfterPeriods, pq.Array(tier.Recipients), tier.LetterSubject, tier.LetterTemplate)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func RegisterThresholdRoutes(router *mux.Router, db database.DBQuerier) {
+	router.HandleFunc("/thresholds/backtest", func(w http.ResponseWriter, r *http.Request) {
+		BacktestThreshold(w, r, db)
+	}).Methods("POST")
+
 	router.HandleFunc("/thresholds/{id}", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == "GET" {
 			GetThresholdById(w, r, db)
@@ -307,6 +720,22 @@ func RegisterThresholdRoutes(router *mux.Router, db database.DBQuerier) {
 		}
 	}).Methods("GET", "PUT", "DELETE")
 
+	router.HandleFunc("/thresholds/{id}/evaluations", func(w http.ResponseWriter, r *http.Request) {
+		GetThresholdEvaluations(w, r, db)
+	}).Methods("GET")
+
+	router.HandleFunc("/thresholds/{id}/preview", func(w http.ResponseWriter, r *http.Request) {
+		PreviewThreshold(w, r, db)
+	}).Methods("POST")
+
+	router.HandleFunc("/thresholds/{id}/snooze", func(w http.ResponseWriter, r *http.Request) {
+		SnoozeThreshold(w, r, db)
+	}).Methods("POST")
+
+	router.HandleFunc("/thresholds/{id}/resume", func(w http.ResponseWriter, r *http.Request) {
+		ResumeThreshold(w, r, db)
+	}).Methods("POST")
+
 	router.HandleFunc("/thresholds", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == "POST" {
 			CreateThreshold(w, r, db)
@@ -9,8 +9,10 @@ import (
 	"megga-backend/internal/config"
 	"megga-backend/internal/database"
 	"megga-backend/internal/models"
+	"megga-backend/internal/services"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/jackc/pgx/v4"
@@ -180,15 +182,26 @@ func GetThresholdsForUser(w http.ResponseWriter, r *http.Request, db database.DB
 		DataID         int     `json:"data_id"`
 		Name           string  `json:"name"`
 		ThresholdValue float64 `json:"threshold_value"`
+		Mode           string  `json:"mode"`
+		Direction      string  `json:"direction"`
+		State          string  `json:"state"`
 		NotifyUser     bool    `json:"notify_user"`
 		Recipients     []int64 `json:"recipients"`
+
+		Operator   string                      `json:"operator"`
+		Conditions []models.ThresholdCondition `json:"conditions"`
+		Expression string                      `json:"expression,omitempty"`
+
+		PausedUntil *time.Time `json:"paused_until,omitempty"`
+		ExpiresAt   *time.Time `json:"expires_at,omitempty"`
 	}
 
 	var thresholds []ThresholdWithRecipients
 
 	query := `
-		SELECT t.threshold_id, t.data_id, d.name, t.threshold_value, t.notify_user, 
-		       COALESCE(ARRAY_AGG(tr.recipient_id) FILTER (WHERE tr.recipient_id IS NOT NULL), ARRAY[]::BIGINT[]) AS recipients
+		SELECT t.threshold_id, t.data_id, d.name, t.threshold_value, t.mode, t.direction, t.state, t.notify_user, 
+		       COALESCE(ARRAY_AGG(tr.recipient_id) FILTER (WHERE tr.recipient_id IS NOT NULL), ARRAY[]::BIGINT[]) AS recipients,
+		       t.operator, t.expression, t.paused_until, t.expires_at
 		FROM thresholds t
 		JOIN data d ON t.data_id = d.data_id
 		LEFT JOIN threshold_recipients tr ON t.threshold_id = tr.threshold_id
@@ -226,7 +239,7 @@ func GetThresholdsForUser(w http.ResponseWriter, r *http.Request, db database.DB
 			log.Println("🔍 Scanning row data...")
 		}
 
-		if err := rows.Scan(&threshold.ThresholdID, &threshold.DataID, &threshold.Name, &threshold.ThresholdValue, &threshold.NotifyUser, pq.Array(&threshold.Recipients)); err != nil {
+		if err := rows.Scan(&threshold.ThresholdID, &threshold.DataID, &threshold.Name, &threshold.ThresholdValue, &threshold.Mode, &threshold.Direction, &threshold.State, &threshold.NotifyUser, pq.Array(&threshold.Recipients), &threshold.Operator, &threshold.Expression, &threshold.PausedUntil, &threshold.ExpiresAt); err != nil {
 			if config.IsDevelopmentMode() {
 				log.Printf("❌ Error Scanning Data: %v", err)
 			}
@@ -243,6 +256,26 @@ func GetThresholdsForUser(w http.ResponseWriter, r *http.Request, db database.DB
 			break
 		}
 	}
+	rows.Close()
+
+	thresholdIDs := make([]int, len(thresholds))
+	for i, threshold := range thresholds {
+		thresholdIDs[i] = threshold.ThresholdID
+	}
+	conditions, err := services.GetThresholdConditions(db, thresholdIDs)
+	if err != nil {
+		if config.IsDevelopmentMode() {
+			log.Printf("❌ Database Query Error: %v", err)
+		}
+		http.Error(w, "Database query error", http.StatusInternalServerError)
+		return
+	}
+	for i := range thresholds {
+		thresholds[i].Conditions = conditions[thresholds[i].ThresholdID]
+		if thresholds[i].Conditions == nil {
+			thresholds[i].Conditions = []models.ThresholdCondition{}
+		}
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(thresholds)
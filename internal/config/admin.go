@@ -0,0 +1,17 @@
+package config
+
+import (
+	"os"
+	"strings"
+)
+
+func AdminEmails() []string {
+	var emails []string
+	for _, email := range strings.Split(os.Getenv("ADMIN_EMAILS"), ",") {
+		email = strings.TrimSpace(email)
+		if email != "" {
+			emails = append(emails, strings.ToLower(email))
+		}
+	}
+	return emails
+}
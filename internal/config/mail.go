@@ -0,0 +1,49 @@
+package config
+
+import "os"
+
+// Mail backends. The log backend only writes letters to the log, which is all
+// the proof of concept has done so far.
+const (
+	MailBackendLog  = "log"
+	MailBackendSMTP = "smtp"
+	MailBackendFile = "file"
+)
+
+const (
+	defaultSMTPPort    = "587"
+	defaultMailDropDir = "mail"
+)
+
+// MailConfig says how letters are delivered.
+type MailConfig struct {
+	Backend      string // See MailBackend* constants
+	From         string // Sender address
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string // Plain auth is used only when this is set
+	SMTPPassword string
+	DropDir      string // Where the file backend writes .eml files
+}
+
+func Mail() MailConfig {
+	mail := MailConfig{
+		Backend:      os.Getenv("MAIL_BACKEND"),
+		From:         os.Getenv("SENDER_EMAIL"),
+		SMTPHost:     os.Getenv("SMTP_HOST"),
+		SMTPPort:     os.Getenv("SMTP_PORT"),
+		SMTPUsername: os.Getenv("SMTP_USERNAME"),
+		SMTPPassword: os.Getenv("SMTP_PASSWORD"),
+		DropDir:      os.Getenv("MAIL_DROP_DIR"),
+	}
+	if mail.Backend == "" {
+		mail.Backend = MailBackendLog
+	}
+	if mail.SMTPPort == "" {
+		mail.SMTPPort = defaultSMTPPort
+	}
+	if mail.DropDir == "" {
+		mail.DropDir = defaultMailDropDir
+	}
+	return mail
+}
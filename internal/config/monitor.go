@@ -0,0 +1,19 @@
+package config
+
+import (
+	"os"
+	"strconv"
+)
+
+const defaultThresholdWorkers = 8
+
+// ThresholdWorkers is how many thresholds are checked at once. Each check
+// holds a database connection while it saves its state and sends its letters,
+// so this should stay well below the pool size.
+func ThresholdWorkers() int {
+	workers, err := strconv.Atoi(os.Getenv("THRESHOLD_WORKERS"))
+	if err != nil || workers < 1 {
+		return defaultThresholdWorkers
+	}
+	return workers
+}
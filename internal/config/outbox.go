@@ -0,0 +1,42 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultOutboxMaxAttempts  = 8
+	defaultOutboxRetryDelay   = time.Minute
+	defaultOutboxPollInterval = 15 * time.Second
+)
+
+// OutboxMaxAttempts is how many times a queued letter is tried before it is
+// set aside as a dead letter.
+func OutboxMaxAttempts() int {
+	attempts, err := strconv.Atoi(os.Getenv("OUTBOX_MAX_ATTEMPTS"))
+	if err != nil || attempts < 1 {
+		return defaultOutboxMaxAttempts
+	}
+	return attempts
+}
+
+// OutboxRetryDelay is how long the dispatcher waits before retrying a letter
+// that failed once. The wait doubles with each further failure.
+func OutboxRetryDelay() time.Duration {
+	return durationFromEnv("OUTBOX_RETRY_DELAY", defaultOutboxRetryDelay)
+}
+
+// OutboxPollInterval is how often the dispatcher looks for letters to send.
+func OutboxPollInterval() time.Duration {
+	return durationFromEnv("OUTBOX_POLL_INTERVAL", defaultOutboxPollInterval)
+}
+
+func durationFromEnv(key string, fallback time.Duration) time.Duration {
+	duration, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || duration <= 0 {
+		return fallback
+	}
+	return duration
+}
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"os"
+	"time"
+
+	_ "time/tzdata"
+)
+
+const (
+	defaultSchedulerTimezone = "America/New_York"
+	defaultIngestSchedule    = "30 8 * * 1-5"
+	defaultExpireSchedule    = "15 * * * *"
+)
+
+// SchedulerLocation is the time zone cron schedules are evaluated in. BLS
+// publishes at 8:30 ET, so Eastern time is the default.
+func SchedulerLocation() (*time.Location, error) {
+	name := os.Getenv("SCHEDULER_TIMEZONE")
+	if name == "" {
+		name = defaultSchedulerTimezone
+	}
+	return time.LoadLocation(name)
+}
+
+func IngestSchedule() string {
+	if schedule := os.Getenv("INGEST_SCHEDULE"); schedule != "" {
+		return schedule
+	}
+	return defaultIngestSchedule
+}
+
+// ExpireSchedule is when expired thresholds are removed. The monitor already
+// skips them, so hourly is plenty.
+func ExpireSchedule() string {
+	if schedule := os.Getenv("EXPIRE_SCHEDULE"); schedule != "" {
+		return schedule
+	}
+	return defaultExpireSchedule
+}
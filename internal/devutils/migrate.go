@@ -57,6 +57,221 @@ func MigrateDB(db database.DBQuerier) {
 			user_msg TEXT,
 			recipient_msg TEXT
 		)`},
+		{"Creating Data_Observation table", `CREATE TABLE IF NOT EXISTS data_observations (
+			observation_id SERIAL PRIMARY KEY,
+			series_id VARCHAR(255) NOT NULL,
+			year VARCHAR(10) NOT NULL,
+			period VARCHAR(10) NOT NULL,
+			value FLOAT NOT NULL,
+			recorded_at TIMESTAMP DEFAULT NOW(),
+			UNIQUE (series_id, year, period)
+		)`},
+		{"Creating Series_Catalog table", `CREATE TABLE IF NOT EXISTS series_catalog (
+			series_id VARCHAR(255) PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			unit VARCHAR(50) NOT NULL DEFAULT '',
+			category VARCHAR(100) NOT NULL DEFAULT '',
+			active BOOLEAN NOT NULL DEFAULT TRUE,
+			created_at TIMESTAMP DEFAULT NOW(),
+			updated_at TIMESTAMP DEFAULT NOW()
+		)`},
+		{"Populating default Series_Catalog entries", `INSERT INTO series_catalog (series_id, name, unit, category) VALUES
+			('APU0000708111', 'Eggs, Grade A, Large', 'per dozen', 'food'),
+			('APU0000702111', 'Bread, White, Pan', 'per lb.', 'food'),
+			('APU0000709213', 'Milk, Fresh, Low Fat', 'per gallon', 'food'),
+			('APU0000FF1101', 'Chicken Breast, Boneless', 'per lb.', 'food'),
+			('APU0000704111', 'Bacon, Sliced', 'per lb.', 'food'),
+			('APU0000711111', 'Apples, Red Delicious', 'per lb.', 'food'),
+			('APU0000711311', 'Oranges, Navel', 'per lb.', 'food'),
+			('APU00007471A', 'Gasoline, All Types', 'per gal.', 'energy'),
+			('LEU0252881600', 'Median Usual Weekly Earnings', 'constant 1982-1984 dollars', 'wages')
+			ON CONFLICT (series_id) DO NOTHING`},
+		{"Adding provider to Series_Catalog table", `ALTER TABLE series_catalog
+			ADD COLUMN IF NOT EXISTS provider VARCHAR(50) NOT NULL DEFAULT 'bls'`},
+		{"Adding priority to Series_Catalog table", `ALTER TABLE series_catalog
+			ADD COLUMN IF NOT EXISTS priority VARCHAR(10) NOT NULL DEFAULT 'normal'`},
+		{"Creating API_Usage table", `CREATE TABLE IF NOT EXISTS api_usage (
+			provider VARCHAR(50) NOT NULL,
+			key_id VARCHAR(64) NOT NULL,
+			usage_date DATE NOT NULL,
+			request_count INT NOT NULL DEFAULT 0,
+			updated_at TIMESTAMP DEFAULT NOW(),
+			PRIMARY KEY (provider, key_id, usage_date)
+		)`},
+		{"Creating Ingestion_Runs table", `CREATE TABLE IF NOT EXISTS ingestion_runs (
+			ingestion_run_id SERIAL PRIMARY KEY,
+			status VARCHAR(20) NOT NULL,
+			error TEXT NOT NULL DEFAULT '',
+			started_at TIMESTAMP DEFAULT NOW(),
+			finished_at TIMESTAMP
+		)`},
+		{"Creating Ingestion_Series_Results table", `CREATE TABLE IF NOT EXISTS ingestion_series_results (
+			ingestion_run_id INT NOT NULL REFERENCES ingestion_runs(ingestion_run_id) ON DELETE CASCADE,
+			series_id VARCHAR(50) NOT NULL,
+			provider VARCHAR(50) NOT NULL,
+			outcome VARCHAR(20) NOT NULL,
+			message TEXT NOT NULL DEFAULT '',
+			observation_count INT NOT NULL DEFAULT 0,
+			PRIMARY KEY (ingestion_run_id, series_id)
+		)`},
+		{"Adding preliminary to Data_Observation table", `ALTER TABLE data_observations
+			ADD COLUMN IF NOT EXISTS preliminary BOOLEAN NOT NULL DEFAULT FALSE`},
+		{"Adding footnotes to Data_Observation table", `ALTER TABLE data_observations
+			ADD COLUMN IF NOT EXISTS footnotes TEXT NOT NULL DEFAULT ''`},
+		{"Creating Data_Revisions table", `CREATE TABLE IF NOT EXISTS data_revisions (
+			revision_id SERIAL PRIMARY KEY,
+			series_id VARCHAR(255) NOT NULL,
+			year VARCHAR(10) NOT NULL,
+			period VARCHAR(10) NOT NULL,
+			previous_value FLOAT NOT NULL,
+			revised_value FLOAT NOT NULL,
+			preliminary BOOLEAN NOT NULL DEFAULT FALSE,
+			detected_at TIMESTAMP DEFAULT NOW(),
+			processed_at TIMESTAMP
+		)`},
+		{"Creating Job_Runs table", `CREATE TABLE IF NOT EXISTS job_runs (
+			job_run_id SERIAL PRIMARY KEY,
+			job_name VARCHAR(100) NOT NULL,
+			trigger VARCHAR(20) NOT NULL,
+			scheduled_for TIMESTAMP NOT NULL,
+			status VARCHAR(20) NOT NULL,
+			error TEXT NOT NULL DEFAULT '',
+			started_at TIMESTAMP DEFAULT NOW(),
+			finished_at TIMESTAMP
+		)`},
+		{"Indexing Job_Runs by job", `CREATE INDEX IF NOT EXISTS job_runs_job_name_scheduled_for_idx
+			ON job_runs (job_name, scheduled_for DESC)`},
+		{"Creating Leases table", `CREATE TABLE IF NOT EXISTS leases (
+			name VARCHAR(100) PRIMARY KEY,
+			holder VARCHAR(255) NOT NULL,
+			acquired_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			expires_at TIMESTAMP NOT NULL
+		)`},
+		{"Adding ingestion_run_id to Job_Runs table", `ALTER TABLE job_runs
+			ADD COLUMN IF NOT EXISTS ingestion_run_id INT REFERENCES ingestion_runs(ingestion_run_id) ON DELETE SET NULL`},
+		{"Adding thresholds_checked to Ingestion_Runs table", `ALTER TABLE ingestion_runs
+			ADD COLUMN IF NOT EXISTS thresholds_checked INT`},
+		{"Adding thresholds_triggered to Ingestion_Runs table", `ALTER TABLE ingestion_runs
+			ADD COLUMN IF NOT EXISTS thresholds_triggered INT`},
+		{"Adding mode to Threshold table", `ALTER TABLE thresholds
+			ADD COLUMN IF NOT EXISTS mode VARCHAR(30) NOT NULL DEFAULT 'percent_previous'`},
+		{"Adding baseline_value to Threshold table", `ALTER TABLE thresholds
+			ADD COLUMN IF NOT EXISTS baseline_value FLOAT`},
+		{"Adding direction to Threshold table", `ALTER TABLE thresholds
+			ADD COLUMN IF NOT EXISTS direction VARCHAR(10) NOT NULL DEFAULT 'either'`},
+		{"Adding state to Threshold table", `ALTER TABLE thresholds
+			ADD COLUMN IF NOT EXISTS state VARCHAR(20) NOT NULL DEFAULT 'armed'`},
+		{"Adding hysteresis to Threshold table", `ALTER TABLE thresholds
+			ADD COLUMN IF NOT EXISTS hysteresis FLOAT NOT NULL DEFAULT 0`},
+		{"Adding cooldown_hours to Threshold table", `ALTER TABLE thresholds
+			ADD COLUMN IF NOT EXISTS cooldown_hours INT NOT NULL DEFAULT 0`},
+		{"Adding last_triggered_at to Threshold table", `ALTER TABLE thresholds
+			ADD COLUMN IF NOT EXISTS last_triggered_at TIMESTAMP`},
+		{"Adding operator to Threshold table", `ALTER TABLE thresholds
+			ADD COLUMN IF NOT EXISTS operator VARCHAR(3) NOT NULL DEFAULT 'and'`},
+		{"Creating Threshold_Condition table", `CREATE TABLE IF NOT EXISTS threshold_conditions (
+			condition_id SERIAL PRIMARY KEY,
+			threshold_id INT NOT NULL REFERENCES thresholds(threshold_id) ON DELETE CASCADE,
+			data_id INT NOT NULL REFERENCES data(data_id) ON DELETE CASCADE,
+			mode VARCHAR(30) NOT NULL,
+			direction VARCHAR(10) NOT NULL,
+			value FLOAT NOT NULL,
+			baseline_value FLOAT,
+			position INT NOT NULL
+		)`},
+		{"Adding expression to Threshold table", `ALTER TABLE thresholds
+			ADD COLUMN IF NOT EXISTS expression TEXT NOT NULL DEFAULT ''`},
+		{"Adding alias to Series_Catalog table", `ALTER TABLE series_catalog
+			ADD COLUMN IF NOT EXISTS alias VARCHAR(50) UNIQUE`},
+		{"Populating default Series_Catalog aliases", `UPDATE series_catalog SET alias = defaults.alias
+			FROM (VALUES
+				('APU0000708111', 'eggs'),
+				('APU0000702111', 'bread'),
+				('APU0000709213', 'milk'),
+				('APU0000FF1101', 'chicken'),
+				('APU0000704111', 'bacon'),
+				('APU0000711111', 'apples'),
+				('APU0000711311', 'oranges'),
+				('APU00007471A', 'gasoline'),
+				('LEU0252881600', 'earnings')
+			) AS defaults (series_id, alias)
+			WHERE series_catalog.series_id = defaults.series_id AND series_catalog.alias IS NULL`},
+		{"Creating Threshold_Evaluation table", `CREATE TABLE IF NOT EXISTS threshold_evaluations (
+			evaluation_id SERIAL PRIMARY KEY,
+			threshold_id INT NOT NULL REFERENCES thresholds(threshold_id) ON DELETE CASCADE,
+			evaluated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			inputs JSONB NOT NULL DEFAULT '[]',
+			metric FLOAT,
+			comparison TEXT NOT NULL DEFAULT '',
+			triggered BOOLEAN NOT NULL DEFAULT FALSE,
+			previous_state VARCHAR(20) NOT NULL,
+			state VARCHAR(20) NOT NULL,
+			notified BOOLEAN NOT NULL DEFAULT FALSE,
+			explanation TEXT NOT NULL DEFAULT '',
+			error TEXT NOT NULL DEFAULT ''
+		)`},
+		{"Indexing Threshold_Evaluation table", `CREATE INDEX IF NOT EXISTS threshold_evaluations_threshold_idx
+			ON threshold_evaluations (threshold_id, evaluated_at DESC)`},
+		{"Adding pause and expiry to Threshold table", `ALTER TABLE thresholds
+			ADD COLUMN IF NOT EXISTS paused_until TIMESTAMP,
+			ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP`},
+		{"Creating Campaign table", `CREATE TABLE IF NOT EXISTS campaigns (
+			campaign_id SERIAL PRIMARY KEY,
+			owner_id INT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+			threshold_id INT NOT NULL UNIQUE REFERENCES thresholds(threshold_id) ON DELETE CASCADE,
+			name TEXT NOT NULL,
+			description TEXT NOT NULL DEFAULT '',
+			letter_subject TEXT NOT NULL,
+			letter_template TEXT NOT NULL,
+			created_at TIMESTAMP NOT NULL DEFAULT NOW()
+		)`},
+		{"Creating Campaign_Member table", `CREATE TABLE IF NOT EXISTS campaign_members (
+			campaign_id INT NOT NULL REFERENCES campaigns(campaign_id) ON DELETE CASCADE,
+			user_id INT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+			joined_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			PRIMARY KEY (campaign_id, user_id)
+		)`},
+		{"Creating Campaign_Delivery table", `CREATE TABLE IF NOT EXISTS campaign_deliveries (
+			delivery_id SERIAL PRIMARY KEY,
+			campaign_id INT NOT NULL REFERENCES campaigns(campaign_id) ON DELETE CASCADE,
+			delivered_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			members INT NOT NULL,
+			letters INT NOT NULL
+		)`},
+		{"Adding breach tracking to Threshold table", `ALTER TABLE thresholds
+			ADD COLUMN IF NOT EXISTS breach_periods INT NOT NULL DEFAULT 0,
+			ADD COLUMN IF NOT EXISTS breach_year VARCHAR(10) NOT NULL DEFAULT '',
+			ADD COLUMN IF NOT EXISTS breach_period VARCHAR(10) NOT NULL DEFAULT ''`},
+		{"Creating Threshold_Escalation table", `CREATE TABLE IF NOT EXISTS threshold_escalations (
+			escalation_id SERIAL PRIMARY KEY,
+			threshold_id INT NOT NULL REFERENCES thresholds(threshold_id) ON DELETE CASCADE,
+			after_periods INT NOT NULL CHECK (after_periods >= 2),
+			recipient_ids INT[] NOT NULL DEFAULT '{}',
+			letter_subject TEXT NOT NULL DEFAULT '',
+			letter_template TEXT NOT NULL DEFAULT '',
+			UNIQUE (threshold_id, after_periods)
+		)`},
+		{"Adding escalation to Threshold_Evaluation table", `ALTER TABLE threshold_evaluations
+			ADD COLUMN IF NOT EXISTS breach_periods INT NOT NULL DEFAULT 0,
+			ADD COLUMN IF NOT EXISTS tier INT NOT NULL DEFAULT 0,
+			ADD COLUMN IF NOT EXISTS escalated BOOLEAN NOT NULL DEFAULT FALSE`},
+		{"Creating Notification_Outbox table", `CREATE TABLE IF NOT EXISTS notification_outbox (
+			outbox_id SERIAL PRIMARY KEY,
+			threshold_id INT REFERENCES thresholds(threshold_id) ON DELETE SET NULL,
+			campaign_id INT REFERENCES campaigns(campaign_id) ON DELETE SET NULL,
+			kind VARCHAR(20) NOT NULL,
+			to_address TEXT NOT NULL,
+			subject TEXT NOT NULL,
+			body TEXT NOT NULL,
+			status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sent', 'dead')),
+			attempts INT NOT NULL DEFAULT 0,
+			next_attempt_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			last_error TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			sent_at TIMESTAMP
+		)`},
+		{"Indexing Notification_Outbox table", `CREATE INDEX IF NOT EXISTS notification_outbox_pending_idx
+			ON notification_outbox (next_attempt_at, outbox_id) WHERE status = 'pending'`},
 	}
 
 	for _, m := range migrations {
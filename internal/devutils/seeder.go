@@ -27,6 +27,14 @@ func SeedDB(db database.DBQuerier) {
 			('Inflation', 'LEU0252881600', 'constant 1982-1984 dollars', 2.5, 2.8, NOW(), 'Q4', '2024')
 			ON CONFLICT DO NOTHING;`},
 
+		{"Inserting Data Observations", `INSERT INTO data_observations (series_id, year, period, value) VALUES 
+			('APU0000708111', '2024', 'M10', 3.37),
+			('APU0000708111', '2024', 'M11', 3.65),
+			('APU0000708111', '2024', 'M12', 4.15),
+			('LEU0252881600', '2024', 'Q03', 365.0),
+			('LEU0252881600', '2024', 'Q04', 366.0)
+			ON CONFLICT DO NOTHING;`},
+
 		{"Inserting Thresholds", `INSERT INTO thresholds (user_id, data_id, threshold_value, created_at, notify_user) VALUES 
 			((SELECT user_id FROM users WHERE email = 'user1@example.com'), (SELECT data_id FROM data WHERE series_id = 'APU0000708111'), 5.0, NOW(), true),
 			((SELECT user_id FROM users WHERE email = 'user2@example.com'), (SELECT data_id FROM data WHERE series_id = 'LEU0252881600'), 10.0, NOW(), false)
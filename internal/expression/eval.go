@@ -0,0 +1,281 @@
+package expression
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNotEnoughData is returned when a series has too few stored values for a
+// function, for example avg_n(eggs, 12) on a series with six observations.
+var ErrNotEnoughData = errors.New("not enough data")
+
+// Source supplies the values of the series a rule refers to.
+type Source interface {
+	// Recent returns up to n of the series' most recent values, newest first.
+	Recent(series string, n int) ([]float64, error)
+	// YearAgo returns the value for the same period a year before the latest
+	// one, or ErrNotEnoughData when it was never stored.
+	YearAgo(series string) (float64, error)
+}
+
+// Call is a function call made while evaluating a rule, with its result.
+type Call struct {
+	Function string
+	Series   string
+	Periods  int
+	Value    float64
+}
+
+// Expr returns the call as written in a rule, such as "avg_n(eggs, 12)".
+func (c Call) Expr() string {
+	if c.Periods > 0 {
+		return fmt.Sprintf("%s(%s, %d)", c.Function, c.Series, c.Periods)
+	}
+	return fmt.Sprintf("%s(%s)", c.Function, c.Series)
+}
+
+func (c Call) String() string {
+	return fmt.Sprintf("%s = %.2f", c.Expr(), c.Value)
+}
+
+// Evaluate reports whether the rule holds for the values in src. "and" and
+// "or" stop at the first side that decides the result.
+func (e *Expression) Evaluate(src Source) (bool, error) {
+	holds, _, err := e.Trace(src)
+	return holds, err
+}
+
+// Trace evaluates the rule like Evaluate and also returns the function calls
+// it made, in order, so callers can show the values behind the result.
+func (e *Expression) Trace(src Source) (bool, []Call, error) {
+	ev := &evaluation{src: src}
+	result, err := e.root.eval(ev)
+	if err != nil {
+		return false, ev.calls, err
+	}
+	return result.boolean, ev.calls, nil
+}
+
+type evaluation struct {
+	src   Source
+	calls []Call
+}
+
+type valueKind int
+
+const (
+	kindNumber valueKind = iota
+	kindBool
+)
+
+type value struct {
+	number  float64
+	boolean bool
+}
+
+type node interface {
+	kind() valueKind
+	eval(ev *evaluation) (value, error)
+}
+
+type numberNode struct {
+	value float64
+}
+
+func (n *numberNode) kind() valueKind { return kindNumber }
+
+func (n *numberNode) eval(*evaluation) (value, error) {
+	return value{number: n.value}, nil
+}
+
+type function struct {
+	window bool
+	eval   func(src Source, series string, n int) (float64, error)
+}
+
+var functions = map[string]function{
+	"latest": {eval: func(src Source, series string, _ int) (float64, error) {
+		values, err := recent(src, series, 1)
+		if err != nil {
+			return 0, err
+		}
+		return values[0], nil
+	}},
+	"previous": {eval: func(src Source, series string, _ int) (float64, error) {
+		values, err := recent(src, series, 2)
+		if err != nil {
+			return 0, err
+		}
+		return values[1], nil
+	}},
+	"change": {eval: func(src Source, series string, _ int) (float64, error) {
+		values, err := recent(src, series, 2)
+		if err != nil {
+			return 0, err
+		}
+		return percentChange(series, values[1], values[0])
+	}},
+	"yoy": {eval: func(src Source, series string, _ int) (float64, error) {
+		values, err := recent(src, series, 1)
+		if err != nil {
+			return 0, err
+		}
+		yearAgo, err := src.YearAgo(series)
+		if err != nil {
+			return 0, err
+		}
+		return percentChange(series, yearAgo, values[0])
+	}},
+	"avg_n": {window: true, eval: func(src Source, series string, n int) (float64, error) {
+		values, err := recent(src, series, n)
+		if err != nil {
+			return 0, err
+		}
+		var sum float64
+		for _, v := range values {
+			sum += v
+		}
+		return sum / float64(n), nil
+	}},
+	"max_n": {window: true, eval: func(src Source, series string, n int) (float64, error) {
+		values, err := recent(src, series, n)
+		if err != nil {
+			return 0, err
+		}
+		max := values[0]
+		for _, v := range values[1:] {
+			if v > max {
+				max = v
+			}
+		}
+		return max, nil
+	}},
+	"min_n": {window: true, eval: func(src Source, series string, n int) (float64, error) {
+		values, err := recent(src, series, n)
+		if err != nil {
+			return 0, err
+		}
+		min := values[0]
+		for _, v := range values[1:] {
+			if v < min {
+				min = v
+			}
+		}
+		return min, nil
+	}},
+}
+
+func recent(src Source, series string, n int) ([]float64, error) {
+	values, err := src.Recent(series, n)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) < n {
+		return nil, fmt.Errorf("%w: %s has %d of the %d values needed", ErrNotEnoughData, series, len(values), n)
+	}
+	return values, nil
+}
+
+func percentChange(series string, base, latest float64) (float64, error) {
+	if base == 0 {
+		return 0, fmt.Errorf("%w: %s has a base value of zero", ErrNotEnoughData, series)
+	}
+	return (latest - base) / base * 100, nil
+}
+
+type callNode struct {
+	name   string
+	fn     function
+	series string
+	n      int
+}
+
+func (n *callNode) kind() valueKind { return kindNumber }
+
+func (n *callNode) eval(ev *evaluation) (value, error) {
+	result, err := n.fn.eval(ev.src, n.series, n.n)
+	if err != nil {
+		return value{}, fmt.Errorf("%s(%s): %w", n.name, n.series, err)
+	}
+	ev.calls = append(ev.calls, Call{Function: n.name, Series: n.series, Periods: n.n, Value: result})
+	return value{number: result}, nil
+}
+
+type unaryNode struct {
+	op      string
+	operand node
+}
+
+func (n *unaryNode) kind() valueKind {
+	if n.op == "not" {
+		return kindBool
+	}
+	return kindNumber
+}
+
+func (n *unaryNode) eval(ev *evaluation) (value, error) {
+	operand, err := n.operand.eval(ev)
+	if err != nil {
+		return value{}, err
+	}
+	if n.op == "not" {
+		return value{boolean: !operand.boolean}, nil
+	}
+	return value{number: -operand.number}, nil
+}
+
+type binaryNode struct {
+	op          string
+	left, right node
+}
+
+func (n *binaryNode) kind() valueKind {
+	switch n.op {
+	case "+", "-", "*", "/":
+		return kindNumber
+	}
+	return kindBool
+}
+
+func (n *binaryNode) eval(ev *evaluation) (value, error) {
+	left, err := n.left.eval(ev)
+	if err != nil {
+		return value{}, err
+	}
+	if n.op == "and" && !left.boolean || n.op == "or" && left.boolean {
+		return left, nil
+	}
+	right, err := n.right.eval(ev)
+	if err != nil {
+		return value{}, err
+	}
+
+	a, b := left.number, right.number
+	switch n.op {
+	case "and", "or":
+		return right, nil
+	case "+":
+		return value{number: a + b}, nil
+	case "-":
+		return value{number: a - b}, nil
+	case "*":
+		return value{number: a * b}, nil
+	case "/":
+		if b == 0 {
+			return value{}, errors.New("division by zero")
+		}
+		return value{number: a / b}, nil
+	case ">":
+		return value{boolean: a > b}, nil
+	case ">=":
+		return value{boolean: a >= b}, nil
+	case "<":
+		return value{boolean: a < b}, nil
+	case "<=":
+		return value{boolean: a <= b}, nil
+	case "==":
+		return value{boolean: a == b}, nil
+	default:
+		return value{boolean: a != b}, nil
+	}
+}
@@ -0,0 +1,123 @@
+package expression
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNumber
+	tokenIdent
+	tokenOperator
+	tokenLParen
+	tokenRParen
+	tokenComma
+)
+
+type token struct {
+	kind   tokenKind
+	text   string
+	number float64
+	pos    int
+}
+
+// SyntaxError reports where in the rule parsing stopped. Pos counts bytes from
+// the start of the rule, starting at 1.
+type SyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("position %d: %s", e.Pos, e.Msg)
+}
+
+func syntaxError(pos int, format string, args ...interface{}) *SyntaxError {
+	return &SyntaxError{Pos: pos + 1, Msg: fmt.Sprintf(format, args...)}
+}
+
+var twoCharOperators = []string{">=", "<=", "==", "!="}
+
+func lex(src string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(src); {
+		c := rune(src[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "(", pos: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")", pos: i})
+			i++
+		case c == ',':
+			tokens = append(tokens, token{kind: tokenComma, text: ",", pos: i})
+			i++
+		case unicode.IsDigit(c) || c == '.':
+			start := i
+			for i < len(src) && (unicode.IsDigit(rune(src[i])) || src[i] == '.') {
+				i++
+			}
+			number, err := strconv.ParseFloat(src[start:i], 64)
+			if err != nil {
+				return nil, syntaxError(start, "invalid number %q", src[start:i])
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: src[start:i], number: number, pos: start})
+		case isIdentStart(c):
+			start := i
+			for i < len(src) && isIdentPart(rune(src[i])) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: src[start:i], pos: start})
+		default:
+			operator := ""
+			for _, candidate := range twoCharOperators {
+				if strings.HasPrefix(src[i:], candidate) {
+					operator = candidate
+				}
+			}
+			if operator == "" && strings.ContainsRune("<>+-*/", c) {
+				operator = string(c)
+			}
+			if operator == "" {
+				return nil, syntaxError(i, "unexpected character %q", c)
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: operator, pos: i})
+			i += len(operator)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(src)}), nil
+}
+
+func isIdentStart(c rune) bool {
+	return c == '_' || c < unicode.MaxASCII && unicode.IsLetter(c)
+}
+
+func isIdentPart(c rune) bool {
+	return isIdentStart(c) || c < unicode.MaxASCII && unicode.IsDigit(c)
+}
+
+// IsValidAlias reports whether name can be used to refer to a series in a rule:
+// letters, digits and underscores not starting with a digit, and not a
+// keyword or function name.
+func IsValidAlias(name string) bool {
+	if name == "" || !isIdentStart(rune(name[0])) {
+		return false
+	}
+	for _, c := range name {
+		if !isIdentPart(c) {
+			return false
+		}
+	}
+	lower := strings.ToLower(name)
+	if _, ok := functions[lower]; ok {
+		return false
+	}
+	return lower != "and" && lower != "or" && lower != "not"
+}
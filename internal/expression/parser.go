@@ -0,0 +1,318 @@
+// Package expression parses and evaluates threshold rules such as
+// "yoy(eggs) > 8 and latest(gasoline) > 3.5".
+//
+// A rule compares numbers built from series functions, number literals and
+// + - * /, and joins comparisons with and, or and not. Series are referred to
+// by their catalog alias or series ID inside a function call:
+//
+//	latest(s)    the latest value
+//	previous(s)  the value for the period before the latest one
+//	change(s)    percent change from the previous period
+//	yoy(s)       percent change from the same period a year earlier
+//	avg_n(s, n)  average of the latest n values
+//	max_n(s, n)  highest of the latest n values
+//	min_n(s, n)  lowest of the latest n values
+package expression
+
+import (
+	"strings"
+)
+
+const maxWindow = 120
+
+// Expression is a parsed rule.
+type Expression struct {
+	text   string
+	root   node
+	series []string
+}
+
+func (e *Expression) String() string {
+	return e.text
+}
+
+// Series returns the series the rule refers to, in order of first use.
+func (e *Expression) Series() []string {
+	return append([]string(nil), e.series...)
+}
+
+// Parse checks a rule's syntax and that it is a condition, not just a number.
+func Parse(text string) (*Expression, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, syntaxError(0, "rule is empty")
+	}
+	tokens, err := lex(text)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens, seen: make(map[string]bool)}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if next := p.peek(); next.kind != tokenEOF {
+		return nil, syntaxError(next.pos, "unexpected %q", next.text)
+	}
+	if root.kind() != kindBool {
+		return nil, syntaxError(0, "rule must be a comparison, such as latest(eggs) > 4")
+	}
+	return &Expression{text: text, root: root, series: p.series}, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+	series []string
+	seen   map[string]bool
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) isKeyword(word string) bool {
+	t := p.peek()
+	return t.kind == tokenIdent && strings.ToLower(t.text) == word
+}
+
+func (p *parser) isOperator(operators ...string) bool {
+	t := p.peek()
+	if t.kind != tokenOperator {
+		return false
+	}
+	for _, operator := range operators {
+		if t.text == operator {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) expect(kind tokenKind, what string) (token, error) {
+	t := p.next()
+	if t.kind != kind {
+		return t, syntaxError(t.pos, "expected %s, found %s", what, describe(t))
+	}
+	return t, nil
+}
+
+func describe(t token) string {
+	if t.kind == tokenEOF {
+		return "end of rule"
+	}
+	return "\"" + t.text + "\""
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("or") {
+		op := p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		if err := requireKind(op, kindBool, left, right); err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: "or", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("and") {
+		op := p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		if err := requireKind(op, kindBool, left, right); err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: "and", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (node, error) {
+	if !p.isKeyword("not") {
+		return p.parseComparison()
+	}
+	op := p.next()
+	operand, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	if err := requireKind(op, kindBool, operand); err != nil {
+		return nil, err
+	}
+	return &unaryNode{op: "not", operand: operand}, nil
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	if !p.isOperator(">", ">=", "<", "<=", "==", "!=") {
+		return left, nil
+	}
+	op := p.next()
+	right, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	if err := requireKind(op, kindNumber, left, right); err != nil {
+		return nil, err
+	}
+	return &binaryNode{op: op.text, left: left, right: right}, nil
+}
+
+func (p *parser) parseSum() (node, error) {
+	left, err := p.parseProduct()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("+", "-") {
+		op := p.next()
+		right, err := p.parseProduct()
+		if err != nil {
+			return nil, err
+		}
+		if err := requireKind(op, kindNumber, left, right); err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op.text, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseProduct() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("*", "/") {
+		op := p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if err := requireKind(op, kindNumber, left, right); err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op.text, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if !p.isOperator("-") {
+		return p.parsePrimary()
+	}
+	op := p.next()
+	operand, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	if err := requireKind(op, kindNumber, operand); err != nil {
+		return nil, err
+	}
+	return &unaryNode{op: "-", operand: operand}, nil
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenNumber:
+		return &numberNode{value: t.number}, nil
+	case tokenLParen:
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokenRParen, "\")\""); err != nil {
+			return nil, err
+		}
+		return inner, nil
+	case tokenIdent:
+		name := strings.ToLower(t.text)
+		if fn, ok := functions[name]; ok {
+			return p.parseCall(t, name, fn)
+		}
+		if name == "and" || name == "or" || name == "not" {
+			return nil, syntaxError(t.pos, "unexpected %q", t.text)
+		}
+		if p.peek().kind == tokenLParen {
+			return nil, syntaxError(t.pos, "unknown function %q", t.text)
+		}
+		return nil, syntaxError(t.pos, "series %q must be used inside a function, such as latest(%s)", t.text, t.text)
+	}
+	return nil, syntaxError(t.pos, "expected a number, function or \"(\", found %s", describe(t))
+}
+
+func (p *parser) parseCall(name token, fnName string, fn function) (node, error) {
+	if _, err := p.expect(tokenLParen, "\"(\" after "+name.text); err != nil {
+		return nil, err
+	}
+	series, err := p.expect(tokenIdent, "a series name")
+	if err != nil {
+		return nil, err
+	}
+	if !IsValidAlias(series.text) {
+		return nil, syntaxError(series.pos, "expected a series name, found %q", series.text)
+	}
+
+	call := &callNode{name: fnName, fn: fn, series: series.text}
+	if fn.window {
+		if _, err := p.expect(tokenComma, "\",\" and a number of periods"); err != nil {
+			return nil, err
+		}
+		n, err := p.expect(tokenNumber, "a number of periods")
+		if err != nil {
+			return nil, err
+		}
+		if n.number != float64(int(n.number)) || n.number < 1 || n.number > maxWindow {
+			return nil, syntaxError(n.pos, "number of periods must be a whole number from 1 to %d", maxWindow)
+		}
+		call.n = int(n.number)
+	}
+	if _, err := p.expect(tokenRParen, "\")\""); err != nil {
+		return nil, err
+	}
+
+	if !p.seen[call.series] {
+		p.seen[call.series] = true
+		p.series = append(p.series, call.series)
+	}
+	return call, nil
+}
+
+func requireKind(op token, want valueKind, operands ...node) error {
+	for _, operand := range operands {
+		if operand.kind() == want {
+			continue
+		}
+		if want == kindBool {
+			return syntaxError(op.pos, "%q needs a comparison on each side", op.text)
+		}
+		return syntaxError(op.pos, "%q needs a number on each side", op.text)
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"log"
+	"megga-backend/internal/config"
+	"net/http"
+	"strings"
+)
+
+func RequireAdmin(adminEmails []string) func(http.Handler) http.Handler {
+	allowed := make(map[string]bool, len(adminEmails))
+	for _, email := range adminEmails {
+		allowed[strings.ToLower(email)] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			email := strings.ToLower(r.Header.Get("X-User-Email"))
+			if email == "" || !allowed[email] {
+				if config.IsDevelopmentMode() {
+					log.Printf("❌ DEBUG: Admin access denied for %q", email)
+				}
+				http.Error(w, "Forbidden: admin access required", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Campaign is a threshold published for many users to join. The threshold
+// holds the series, condition and suggested recipients, and every member
+// signs their own copy of the campaign's letter when it fires.
+type Campaign struct {
+	CampaignID     int       `json:"campaign_id" db:"campaign_id"`         // Primary Key
+	OwnerID        int       `json:"owner_id" db:"owner_id"`               // User who published the campaign
+	ThresholdID    int       `json:"threshold_id" db:"threshold_id"`       // Threshold that sets the campaign off
+	Name           string    `json:"name" db:"name"`                       // Shown to users browsing campaigns
+	Description    string    `json:"description" db:"description"`         // What the campaign is asking for
+	DataName       string    `json:"data_name,omitempty" db:"-"`           // Name of the threshold's series
+	Members        int       `json:"members" db:"-"`                       // Users who have joined
+	LetterSubject  string    `json:"letter_subject" db:"letter_subject"`   // Subject of the letter to recipients
+	LetterTemplate string    `json:"letter_template" db:"letter_template"` // Letter to recipients, with [Placeholder] fields
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`           // When it was published
+}
+
+// CampaignStats is what a campaign's owner sees of its reach.
+type CampaignStats struct {
+	CampaignID      int        `json:"campaign_id"`                 // Campaign counted
+	Members         int        `json:"members"`                     // Users who have joined
+	Deliveries      int        `json:"deliveries"`                  // Times the campaign fired and queued letters
+	LettersSent     int        `json:"letters_sent"`                // Letters to recipients the outbox has sent
+	LastDeliveredAt *time.Time `json:"last_delivered_at,omitempty"` // When a letter last went out
+}
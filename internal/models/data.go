@@ -12,4 +12,5 @@ type Data struct {
 	LastUpdated   time.Time `json:"last_updated" db:"last_updated"`     // When updated
 	Period        string    `json:"period" db:"period"`                 // Last period
 	Year          string    `json:"year" db:"year"`                     // Last year
+	PeriodLabel   string    `json:"period_label,omitempty" db:"-"`      // e.g. "December 2024"
 }
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+type DataObservation struct {
+	ObservationID int       `json:"observation_id" db:"observation_id"` // Primary Key
+	SeriesID      string    `json:"series_id" db:"series_id"`           // Series ID
+	Year          string    `json:"year" db:"year"`                     // Observation year
+	Period        string    `json:"period" db:"period"`                 // Observation period
+	PeriodLabel   string    `json:"period_label,omitempty" db:"-"`      // e.g. "December 2024"
+	Value         float64   `json:"value" db:"value"`                   // Observed value
+	Preliminary   bool      `json:"preliminary" db:"preliminary"`       // Subject to revision
+	Footnotes     string    `json:"footnotes,omitempty" db:"footnotes"` // Provider footnotes
+	RecordedAt    time.Time `json:"recorded_at" db:"recorded_at"`       // When stored
+}
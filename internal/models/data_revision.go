@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+type DataRevision struct {
+	RevisionID    int        `json:"revision_id" db:"revision_id"`             // Primary Key
+	SeriesID      string     `json:"series_id" db:"series_id"`                 // Series ID
+	Year          string     `json:"year" db:"year"`                           // Observation year
+	Period        string     `json:"period" db:"period"`                       // Observation period
+	PreviousValue float64    `json:"previous_value" db:"previous_value"`       // Value before the revision
+	RevisedValue  float64    `json:"revised_value" db:"revised_value"`         // Value after the revision
+	Preliminary   bool       `json:"preliminary" db:"preliminary"`             // Revised value is still preliminary
+	DetectedAt    time.Time  `json:"detected_at" db:"detected_at"`             // When the change was seen
+	ProcessedAt   *time.Time `json:"processed_at,omitempty" db:"processed_at"` // When owners were told
+}
@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+type IngestionResult struct {
+	IngestionRunID int             `json:"ingestion_run_id" db:"ingestion_run_id"` // Primary Key
+	Status         string          `json:"status" db:"status"`                     // running, succeeded, partial or failed
+	StartedAt      time.Time       `json:"started_at" db:"started_at"`             // When the run began
+	FinishedAt     *time.Time      `json:"finished_at,omitempty" db:"finished_at"` // When the run ended
+	Error          string          `json:"error,omitempty" db:"error"`             // Run-level failure
+	Series         []SeriesOutcome `json:"series"`                                 // Per-series outcomes
+	Revisions      []DataRevision  `json:"revisions,omitempty"`                    // Stored values that changed
+	Thresholds     *ThresholdCheck `json:"thresholds,omitempty"`                   // Set once thresholds were evaluated
+}
+
+type ThresholdCheck struct {
+	SeriesIDs []string `json:"series_ids,omitempty"` // Series whose latest value changed
+	Checked   int      `json:"checked"`              // Thresholds evaluated
+	Triggered int      `json:"triggered"`            // Thresholds that sent notifications
+	Error     string   `json:"error,omitempty"`      // Evaluation failure, if any
+}
+
+type SeriesOutcome struct {
+	SeriesID         string `json:"series_id" db:"series_id"`                 // Series ID
+	Provider         string `json:"provider" db:"provider"`                   // Provider asked for the series
+	Outcome          string `json:"outcome" db:"outcome"`                     // See Outcome* constants
+	Message          string `json:"message,omitempty" db:"message"`           // Provider message, if any
+	ObservationCount int    `json:"observation_count" db:"observation_count"` // Observations received
+}
+
+const (
+	OutcomeOK            = "ok"
+	OutcomeNoData        = "no_data"
+	OutcomeInvalidSeries = "invalid_series"
+	OutcomeThrottled     = "throttled"
+	OutcomeDeferred      = "deferred"
+	OutcomeError         = "error"
+)
+
+const (
+	IngestionRunning   = "running"
+	IngestionSucceeded = "succeeded"
+	IngestionPartial   = "partial"
+	IngestionFailed    = "failed"
+)
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+type JobRun struct {
+	JobRunID     int        `json:"job_run_id" db:"job_run_id"`             // Primary Key
+	JobName      string     `json:"job_name" db:"job_name"`                 // Registered job name
+	Trigger      string     `json:"trigger" db:"trigger"`                   // schedule, catch_up or manual
+	ScheduledFor time.Time  `json:"scheduled_for" db:"scheduled_for"`       // Slot the run was for
+	Status       string     `json:"status" db:"status"`                     // running, succeeded or failed
+	Error        string     `json:"error,omitempty" db:"error"`             // Failure, if any
+	StartedAt    time.Time  `json:"started_at" db:"started_at"`             // When the run began
+	FinishedAt   *time.Time `json:"finished_at,omitempty" db:"finished_at"` // When the run ended
+
+	IngestionRunID  *int             `json:"ingestion_run_id,omitempty" db:"ingestion_run_id"` // Ingestion run started by the job
+	DurationSeconds *float64         `json:"duration_seconds,omitempty" db:"-"`                // Set once the run has finished
+	Ingestion       *IngestionResult `json:"ingestion,omitempty" db:"-"`                       // Status and per-series outcomes
+}
+
+const (
+	JobTriggerSchedule = "schedule"
+	JobTriggerCatchUp  = "catch_up"
+	JobTriggerManual   = "manual"
+)
+
+const (
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+)
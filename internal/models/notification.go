@@ -11,3 +11,16 @@ type Notification struct {
 	UserMsg        string    `json:"user_msg" db:"user_msg"`               // Message to user
 	RecipientMsg   string    `json:"recipient_msg" db:"recipient_msg"`     // Message to recipient
 }
+
+// RenderedEmail is a letter filled in from its template.
+type RenderedEmail struct {
+	Kind    string `json:"kind"`    // recipient or user
+	To      string `json:"to"`      // Address the letter goes to
+	Subject string `json:"subject"` // Subject line
+	Body    string `json:"body"`    // Letter text
+}
+
+const (
+	EmailKindRecipient = "recipient"
+	EmailKindUser      = "user"
+)
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// OutboxNotification is a letter waiting in, or gone through, the
+// notification outbox.
+type OutboxNotification struct {
+	OutboxID      int        `json:"outbox_id" db:"outbox_id"`                 // Primary Key
+	ThresholdID   *int       `json:"threshold_id,omitempty" db:"threshold_id"` // Threshold that fired; unset once it is deleted
+	CampaignID    *int       `json:"campaign_id,omitempty" db:"campaign_id"`   // Campaign the letter was sent for, if any
+	Kind          string     `json:"kind" db:"kind"`                           // recipient or user
+	To            string     `json:"to" db:"to_address"`                       // Address the letter goes to
+	Subject       string     `json:"subject" db:"subject"`                     // Subject line
+	Body          string     `json:"body" db:"body"`                           // Letter text
+	Status        string     `json:"status" db:"status"`                       // pending, sent or dead
+	Attempts      int        `json:"attempts" db:"attempts"`                   // Delivery attempts so far
+	NextAttemptAt time.Time  `json:"next_attempt_at" db:"next_attempt_at"`     // When a pending letter is next tried
+	LastError     string     `json:"last_error,omitempty" db:"last_error"`     // Why the last attempt failed
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`               // When the letter was queued
+	SentAt        *time.Time `json:"sent_at,omitempty" db:"sent_at"`           // When the letter was delivered
+}
+
+const (
+	OutboxPending = "pending"
+	OutboxSent    = "sent"
+	OutboxDead    = "dead"
+)
+
+func IsKnownOutboxStatus(status string) bool {
+	return status == OutboxPending || status == OutboxSent || status == OutboxDead
+}
+
+// OutboxDispatch counts what a pass of the dispatcher did with the letters it
+// claimed.
+type OutboxDispatch struct {
+	Sent    int `json:"sent"`
+	Retried int `json:"retried"` // Failed and left for a later attempt
+	Dead    int `json:"dead"`    // Failed for the last time
+}
@@ -0,0 +1,162 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+type PeriodKind string
+
+const (
+	PeriodMonthly    PeriodKind = "monthly"
+	PeriodQuarterly  PeriodKind = "quarterly"
+	PeriodSemiannual PeriodKind = "semiannual"
+	PeriodAnnual     PeriodKind = "annual"
+)
+
+// Period is a parsed BLS year/period pair such as 2024 "M12", "Q04", "S02" or
+// "A01". BLS annual averages (M13, Q05, S03) are treated as annual periods.
+type Period struct {
+	Year   int
+	Kind   PeriodKind
+	Number int
+}
+
+func ParsePeriod(year, period string) (Period, error) {
+	parsedYear, err := strconv.Atoi(year)
+	if err != nil || parsedYear <= 0 {
+		return Period{}, fmt.Errorf("invalid year %q", year)
+	}
+	if len(period) < 2 {
+		return Period{}, fmt.Errorf("invalid period %q", period)
+	}
+
+	number, err := strconv.Atoi(period[1:])
+	if err != nil {
+		return Period{}, fmt.Errorf("invalid period %q", period)
+	}
+
+	p := Period{Year: parsedYear, Number: number}
+	switch {
+	case period[0] == 'M' && number >= 1 && number <= 12:
+		p.Kind = PeriodMonthly
+	case period[0] == 'Q' && number >= 1 && number <= 4:
+		p.Kind = PeriodQuarterly
+	case period[0] == 'S' && number >= 1 && number <= 2:
+		p.Kind = PeriodSemiannual
+	case period[0] == 'A' && number == 1,
+		period[0] == 'M' && number == 13,
+		period[0] == 'Q' && number == 5,
+		period[0] == 'S' && number == 3:
+		p.Kind = PeriodAnnual
+		p.Number = 1
+	default:
+		return Period{}, fmt.Errorf("invalid period %q", period)
+	}
+	return p, nil
+}
+
+// IsAnnualAverage reports whether a BLS period code is the annual average of a
+// monthly, quarterly or semiannual series. It summarizes the year rather than
+// following December, so it has no place in the series' own sequence.
+func IsAnnualAverage(period string) bool {
+	return period == "M13" || period == "Q05" || period == "S03"
+}
+
+// MonthlyPeriod returns the monthly period containing t.
+func MonthlyPeriod(t time.Time) Period {
+	return Period{Year: t.Year(), Kind: PeriodMonthly, Number: int(t.Month())}
+}
+
+func (p Period) monthsPerPeriod() int {
+	switch p.Kind {
+	case PeriodQuarterly:
+		return 3
+	case PeriodSemiannual:
+		return 6
+	case PeriodAnnual:
+		return 12
+	default:
+		return 1
+	}
+}
+
+// Date returns the first day of the period.
+func (p Period) Date() time.Time {
+	month := (p.Number-1)*p.monthsPerPeriod() + 1
+	return time.Date(p.Year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// End returns the first day after the period.
+func (p Period) End() time.Time {
+	return p.Date().AddDate(0, p.monthsPerPeriod(), 0)
+}
+
+// Before orders periods by when they end, so a December value sorts after the
+// fourth quarter it belongs to and an annual value sorts last in its year.
+func (p Period) Before(other Period) bool {
+	if !p.End().Equal(other.End()) {
+		return p.End().Before(other.End())
+	}
+	return p.monthsPerPeriod() < other.monthsPerPeriod()
+}
+
+// Previous returns the period immediately before p of the same kind.
+func (p Period) Previous() Period {
+	return p.AddPeriods(-1)
+}
+
+func (p Period) AddPeriods(n int) Period {
+	perYear := 12 / p.monthsPerPeriod()
+	index := p.Year*perYear + (p.Number - 1) + n
+	return Period{Year: index / perYear, Kind: p.Kind, Number: index%perYear + 1}
+}
+
+// YearAgo returns the same period one year earlier.
+func (p Period) YearAgo() Period {
+	return Period{Year: p.Year - 1, Kind: p.Kind, Number: p.Number}
+}
+
+// Code returns the BLS period code, e.g. "M12".
+func (p Period) Code() string {
+	switch p.Kind {
+	case PeriodQuarterly:
+		return fmt.Sprintf("Q%02d", p.Number)
+	case PeriodSemiannual:
+		return fmt.Sprintf("S%02d", p.Number)
+	case PeriodAnnual:
+		return "A01"
+	default:
+		return fmt.Sprintf("M%02d", p.Number)
+	}
+}
+
+func (p Period) YearString() string {
+	return strconv.Itoa(p.Year)
+}
+
+// Label renders the period for letters and API responses, e.g.
+// "December 2024", "Q4 2024", "H2 2024" or "2024".
+func (p Period) Label() string {
+	switch p.Kind {
+	case PeriodQuarterly:
+		return fmt.Sprintf("Q%d %d", p.Number, p.Year)
+	case PeriodSemiannual:
+		return fmt.Sprintf("H%d %d", p.Number, p.Year)
+	case PeriodAnnual:
+		return strconv.Itoa(p.Year)
+	default:
+		return fmt.Sprintf("%s %d", time.Month(p.Number), p.Year)
+	}
+}
+
+// PeriodLabel renders a stored year/period pair, falling back to the raw codes
+// when they cannot be parsed.
+func PeriodLabel(year, period string) string {
+	parsed, err := ParsePeriod(year, period)
+	if err != nil {
+		return year + " " + period
+	}
+	return parsed.Label()
+}
@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+type Series struct {
+	SeriesID  string    `json:"series_id" db:"series_id"`   // Primary Key
+	Name      string    `json:"name" db:"name"`             // Display name
+	Unit      string    `json:"unit" db:"unit"`             // Unit of measurement
+	Category  string    `json:"category" db:"category"`     // E.g., "food"
+	Active    bool      `json:"active" db:"active"`         // Included in ingestion
+	Provider  string    `json:"provider" db:"provider"`     // Source that owns the series
+	Priority  string    `json:"priority" db:"priority"`     // Ingestion priority under quota pressure
+	Alias     string    `json:"alias,omitempty" db:"alias"` // Name used in threshold rules, e.g. "eggs"
+	CreatedAt time.Time `json:"created_at" db:"created_at"` // When added
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"` // When changed
+}
+
+const (
+	ProviderBLS  = "bls"
+	ProviderFRED = "fred"
+	ProviderEIA  = "eia"
+)
+
+func IsKnownProvider(provider string) bool {
+	switch provider {
+	case ProviderBLS, ProviderFRED, ProviderEIA:
+		return true
+	}
+	return false
+}
+
+const (
+	PriorityHigh   = "high"
+	PriorityNormal = "normal"
+	PriorityLow    = "low"
+)
+
+func PriorityRank(priority string) int {
+	switch priority {
+	case PriorityHigh:
+		return 2
+	case PriorityLow:
+		return 0
+	}
+	return 1
+}
+
+func IsKnownPriority(priority string) bool {
+	switch priority {
+	case PriorityHigh, PriorityNormal, PriorityLow:
+		return true
+	}
+	return false
+}
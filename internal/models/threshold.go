@@ -7,7 +7,124 @@ type Threshold struct {
 	UserID         int       `json:"userId" db:"user_id"`
 	DataID         int       `json:"dataId" db:"data_id"`
 	ThresholdValue float64   `json:"thresholdValue" db:"threshold_value"`
+	Mode           string    `json:"mode" db:"mode"`                              // See ThresholdMode* constants
+	Direction      string    `json:"direction" db:"direction"`                    // rise, fall or either
+	BaselineValue  *float64  `json:"baselineValue,omitempty" db:"baseline_value"` // Latest value when the threshold was created
 	CreatedAt      time.Time `json:"createdAt,omitempty" db:"created_at"`
 	NotifyUser     bool      `json:"notifyUser" db:"notify_user"`
 	Recipients     []int     `json:"recipients,omitempty"`
+
+	State           string     `json:"state,omitempty" db:"state"`                       // See ThresholdState* constants
+	Hysteresis      float64    `json:"hysteresis" db:"hysteresis"`                       // How far back inside the limit the value must move to re-arm
+	CooldownHours   int        `json:"cooldownHours" db:"cooldown_hours"`                // Re-arm this long after firing; 0 disables
+	LastTriggeredAt *time.Time `json:"lastTriggeredAt,omitempty" db:"last_triggered_at"` // When notifications were last sent
+
+	Operator   string               `json:"operator,omitempty" db:"operator"` // and or or; joins the conditions
+	Conditions []ThresholdCondition `json:"conditions,omitempty"`             // Conditions beyond the threshold's own
+
+	Expression string `json:"expression,omitempty" db:"expression"` // Rule evaluated instead of the conditions, if set
+
+	PausedUntil *time.Time `json:"pausedUntil,omitempty" db:"paused_until"` // Not checked before this time
+	ExpiresAt   *time.Time `json:"expiresAt,omitempty" db:"expires_at"`     // Removed once this time has passed
+
+	Escalations   []EscalationTier `json:"escalations,omitempty"`                       // Tiers that add recipients while the breach lasts
+	BreachPeriods int              `json:"breachPeriods,omitempty" db:"breach_periods"` // Periods in a row the threshold has held
+	BreachYear    string           `json:"-" db:"breach_year"`                          // Year of the last period counted
+	BreachPeriod  string           `json:"-" db:"breach_period"`                        // Last period counted
+}
+
+// EscalationTier raises the pressure once a threshold has held for
+// AfterPeriods periods in a row: its recipients are added to the threshold's
+// own and to those of earlier tiers, and its letter, if set, replaces the
+// default one.
+type EscalationTier struct {
+	EscalationID   int     `json:"escalationId,omitempty" db:"escalation_id"`     // Primary Key
+	ThresholdID    int     `json:"thresholdId,omitempty" db:"threshold_id"`       // Owning threshold
+	AfterPeriods   int     `json:"afterPeriods" db:"after_periods"`               // Periods the breach must last, at least 2
+	Recipients     []int64 `json:"recipients" db:"recipient_ids"`                 // Recipients added at this tier
+	LetterSubject  string  `json:"letterSubject,omitempty" db:"letter_subject"`   // Subject of the tier's letter
+	LetterTemplate string  `json:"letterTemplate,omitempty" db:"letter_template"` // Tier's letter, with [Placeholder] fields
+}
+
+// ThresholdCondition is an extra condition on a compound threshold. The
+// threshold's own data, mode, direction and value form its first condition.
+type ThresholdCondition struct {
+	ConditionID   int      `json:"conditionId,omitempty" db:"condition_id"`     // Primary Key
+	ThresholdID   int      `json:"thresholdId,omitempty" db:"threshold_id"`     // Owning threshold
+	DataID        int      `json:"dataId" db:"data_id"`                         // Series being watched
+	Name          string   `json:"name,omitempty" db:"-"`                       // Data name, for responses and letters
+	Mode          string   `json:"mode" db:"mode"`                              // See ThresholdMode* constants
+	Direction     string   `json:"direction" db:"direction"`                    // rise, fall or either
+	Value         float64  `json:"value" db:"value"`                            // Price level or percentage
+	BaselineValue *float64 `json:"baselineValue,omitempty" db:"baseline_value"` // Latest value when the condition was created
 }
+
+// PrimaryCondition returns the threshold's own condition.
+func (t Threshold) PrimaryCondition() ThresholdCondition {
+	return ThresholdCondition{
+		ThresholdID:   t.ThresholdID,
+		DataID:        t.DataID,
+		Mode:          t.Mode,
+		Direction:     t.Direction,
+		Value:         t.ThresholdValue,
+		BaselineValue: t.BaselineValue,
+	}
+}
+
+// AllConditions returns the primary condition followed by any extra ones.
+func (t Threshold) AllConditions() []ThresholdCondition {
+	return append([]ThresholdCondition{t.PrimaryCondition()}, t.Conditions...)
+}
+
+// Threshold modes. ThresholdValue is a price level for absolute thresholds and
+// a percentage for the others.
+const (
+	ThresholdModeAbsolute            = "absolute"
+	ThresholdModePercentPrevious     = "percent_previous"
+	ThresholdModePercentSinceCreated = "percent_since_created"
+	ThresholdModePercentYoY          = "percent_yoy"
+)
+
+func IsKnownThresholdMode(mode string) bool {
+	switch mode {
+	case ThresholdModeAbsolute, ThresholdModePercentPrevious, ThresholdModePercentSinceCreated, ThresholdModePercentYoY:
+		return true
+	}
+	return false
+}
+
+// Threshold directions. Absolute thresholds must rise or fall to their level;
+// percent thresholds may also fire on a move of either sign.
+const (
+	ThresholdDirectionRise   = "rise"
+	ThresholdDirectionFall   = "fall"
+	ThresholdDirectionEither = "either"
+)
+
+func IsKnownThresholdDirection(direction string) bool {
+	switch direction {
+	case ThresholdDirectionRise, ThresholdDirectionFall, ThresholdDirectionEither:
+		return true
+	}
+	return false
+}
+
+// Operators joining the conditions of a compound threshold.
+const (
+	ThresholdOperatorAnd = "and"
+	ThresholdOperatorOr  = "or"
+)
+
+func IsKnownThresholdOperator(operator string) bool {
+	return operator == ThresholdOperatorAnd || operator == ThresholdOperatorOr
+}
+
+// Threshold states. An armed threshold fires when its condition is met and
+// becomes triggered. It stays triggered while the condition holds and cools
+// down once it no longer does, until it is re-armed by the value moving back
+// past the hysteresis band or by the cooldown running out.
+const (
+	ThresholdStateArmed       = "armed"
+	ThresholdStateTriggered   = "triggered"
+	ThresholdStateCoolingDown = "cooling_down"
+)
@@ -0,0 +1,20 @@
+package models
+
+// ThresholdBacktest is the outcome of replaying a threshold over stored
+// history, one step per period of the threshold's own series.
+type ThresholdBacktest struct {
+	From      string           `json:"from"`      // First day replayed, YYYY-MM-DD
+	To        string           `json:"to"`        // Last day replayed, YYYY-MM-DD
+	Evaluated int              `json:"evaluated"` // Periods the threshold was evaluated for
+	Skipped   int              `json:"skipped"`   // Periods without the values needed
+	Firings   []BacktestFiring `json:"firings"`   // Periods the threshold would have fired on
+}
+
+// BacktestFiring is a period on which a backtested threshold would have sent
+// letters, with the values it read.
+type BacktestFiring struct {
+	Date       string            `json:"date"`       // First day of the period, YYYY-MM-DD
+	Period     string            `json:"period"`     // e.g. "March 2024"
+	Comparison string            `json:"comparison"` // e.g. "|10.50%| >= 10.00%"
+	Inputs     []EvaluationInput `json:"inputs"`     // One per condition, or per function call in a rule
+}
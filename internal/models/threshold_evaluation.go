@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+type ThresholdEvaluation struct {
+	EvaluationID  int               `json:"evaluation_id" db:"evaluation_id"`     // Primary Key
+	ThresholdID   int               `json:"threshold_id" db:"threshold_id"`       // Threshold evaluated
+	EvaluatedAt   time.Time         `json:"evaluated_at" db:"evaluated_at"`       // When the check ran
+	Inputs        []EvaluationInput `json:"inputs" db:"inputs"`                   // One per condition, or per function call in a rule
+	Metric        *float64          `json:"metric,omitempty" db:"metric"`         // Observed value or percent change of the first condition
+	Comparison    string            `json:"comparison,omitempty" db:"comparison"` // e.g. "10.50% >= 10.00%"
+	Triggered     bool              `json:"triggered" db:"triggered"`             // Whether the threshold held
+	PreviousState string            `json:"previous_state" db:"previous_state"`   // State before the check
+	State         string            `json:"state" db:"state"`                     // State after the check
+	Notified      bool              `json:"notified" db:"notified"`               // Whether letters were sent
+	BreachPeriods int               `json:"breach_periods" db:"breach_periods"`   // Periods in a row the threshold has held
+	Tier          int               `json:"tier,omitempty" db:"tier"`             // Escalation tier the letters went to, 0 for none
+	Escalated     bool              `json:"escalated,omitempty" db:"escalated"`   // Whether letters went out because a tier was reached
+	Explanation   string            `json:"explanation" db:"explanation"`         // Why the threshold did or did not fire
+	Error         string            `json:"error,omitempty" db:"error"`           // Why the threshold could not be evaluated
+}
+
+// EvaluationInput records the values behind one condition of an evaluation,
+// or one function call of a rule.
+type EvaluationInput struct {
+	Name       string   `json:"name"`                 // Data name, or the function call in a rule
+	SeriesID   string   `json:"series_id,omitempty"`  // Series read
+	Latest     *float64 `json:"latest,omitempty"`     // Latest value
+	Previous   *float64 `json:"previous,omitempty"`   // Value for the previous period
+	YearAgo    *float64 `json:"year_ago,omitempty"`   // Value for the same period a year earlier
+	Baseline   *float64 `json:"baseline,omitempty"`   // Value when the threshold was created
+	Observed   float64  `json:"observed"`             // Value or percent change compared, or the call's result
+	Comparison string   `json:"comparison,omitempty"` // e.g. "4.40 >= 4.25"
+	Met        *bool    `json:"met,omitempty"`        // Whether the condition held
+}
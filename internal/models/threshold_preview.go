@@ -0,0 +1,10 @@
+package models
+
+// ThresholdPreview is a dry run of a threshold check: what the monitor would
+// conclude from the current data and the letters it would send.
+type ThresholdPreview struct {
+	ThresholdID int                 `json:"threshold_id"` // Threshold previewed
+	WouldFire   bool                `json:"would_fire"`   // Whether a check now would send the letters
+	Evaluation  ThresholdEvaluation `json:"evaluation"`   // The evaluation a check now would record
+	Emails      []RenderedEmail     `json:"emails"`       // Letters for the met condition, empty when it is not met
+}
@@ -2,6 +2,7 @@ package routes
 
 import (
 	"megga-backend/handlers"
+	"megga-backend/internal/config"
 	"megga-backend/internal/middleware"
 	"megga-backend/internal/database"
 	"os"
@@ -9,13 +10,20 @@ import (
 	"github.com/gorilla/mux"
 )
 
-func RegisterRoutes(router *mux.Router, db database.DBQuerier) {
+func RegisterRoutes(router *mux.Router, db database.DBQuerier, jobs handlers.JobRunner) {
 	handlers.RegisterUserRoutes(router, db)
 	handlers.RegisterThresholdRoutes(router, db)
 	handlers.RegisterDataRoutes(router, db)
 	handlers.RegisterNotificationRoutes(router, db)
 	handlers.RegisterRecipientRoutes(router, db)
 	handlers.RegisterThresholdRecipientRoutes(router, db)
+	handlers.RegisterCampaignRoutes(router, db)
+
+	adminRouter := router.PathPrefix("/admin").Subrouter()
+	adminRouter.Use(middleware.RequireAdmin(config.AdminEmails()))
+	handlers.RegisterSeriesCatalogRoutes(adminRouter, db)
+	handlers.RegisterJobRoutes(adminRouter, db, jobs)
+	handlers.RegisterOutboxRoutes(adminRouter, db)
 
 	router.Use(middleware.ValidateCognitoToken(middleware.CognitoConfig{
 		UserPoolID: os.Getenv("COGNITO_USER_POOL_ID"),
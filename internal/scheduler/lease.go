@@ -0,0 +1,143 @@
+package scheduler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"megga-backend/internal/database"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+const DefaultLeaseTTL = 30 * time.Second
+
+// LeaderChecker reports whether this instance may run background work.
+type LeaderChecker interface {
+	IsLeader() bool
+}
+
+// Elector keeps a row in the leases table for as long as this instance is the
+// leader. The lease is renewed at a third of its TTL; if the leader stops
+// renewing, another instance takes it over once it has expired. Expiry is
+// judged by the database clock so instances do not need synchronized clocks.
+type Elector struct {
+	db     database.DBQuerier
+	Name   string
+	Holder string
+	TTL    time.Duration
+
+	// OnElected runs each time this instance becomes the leader.
+	OnElected func(ctx context.Context)
+
+	mu     sync.RWMutex
+	leader bool
+}
+
+func NewElector(db database.DBQuerier, name string, ttl time.Duration) *Elector {
+	return &Elector{
+		db:     db,
+		Name:   name,
+		Holder: instanceID(),
+		TTL:    ttl,
+	}
+}
+
+func instanceID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	return fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), hex.EncodeToString(suffix))
+}
+
+func (e *Elector) IsLeader() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.leader
+}
+
+// TryAcquire takes the lease if it is free or expired, or renews it if this
+// instance already holds it.
+func (e *Elector) TryAcquire(ctx context.Context) (bool, error) {
+	var holder string
+	err := e.db.QueryRow(ctx, `
+		INSERT INTO leases (name, holder, acquired_at, expires_at)
+		VALUES ($1, $2, NOW(), NOW() + $3 * INTERVAL '1 millisecond')
+		ON CONFLICT (name) DO UPDATE
+		SET holder = EXCLUDED.holder,
+			acquired_at = CASE WHEN leases.holder = EXCLUDED.holder THEN leases.acquired_at ELSE NOW() END,
+			expires_at = EXCLUDED.expires_at
+		WHERE leases.holder = EXCLUDED.holder OR leases.expires_at < NOW()
+		RETURNING holder`,
+		e.Name, e.Holder, e.TTL.Milliseconds()).Scan(&holder)
+	if err != nil && err != pgx.ErrNoRows {
+		// Without a confirmed renewal this instance can no longer be sure it
+		// still holds the lease.
+		e.setLeader(ctx, false)
+		return false, fmt.Errorf("error acquiring lease %s: %w", e.Name, err)
+	}
+
+	acquired := err == nil
+	e.setLeader(ctx, acquired)
+	return acquired, nil
+}
+
+func (e *Elector) setLeader(ctx context.Context, leader bool) {
+	e.mu.Lock()
+	wasLeader := e.leader
+	e.leader = leader
+	e.mu.Unlock()
+
+	if leader && !wasLeader {
+		log.Printf("👑 %s became leader for %s.", e.Holder, e.Name)
+		if e.OnElected != nil {
+			e.OnElected(ctx)
+		}
+	} else if !leader && wasLeader {
+		log.Printf("🪑 %s lost leadership for %s.", e.Holder, e.Name)
+	}
+}
+
+// Run renews or competes for the lease until ctx is cancelled, then releases
+// it so another instance can take over without waiting for expiry.
+func (e *Elector) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.TTL / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			e.Release(context.Background())
+			return
+		case <-ticker.C:
+			if _, err := e.TryAcquire(ctx); err != nil {
+				log.Printf("❌ %v", err)
+			}
+		}
+	}
+}
+
+func (e *Elector) Release(ctx context.Context) {
+	e.mu.Lock()
+	wasLeader := e.leader
+	e.leader = false
+	e.mu.Unlock()
+
+	if !wasLeader {
+		return
+	}
+
+	_, err := e.db.Exec(ctx, "DELETE FROM leases WHERE name = $1 AND holder = $2", e.Name, e.Holder)
+	if err != nil {
+		log.Printf("❌ Error releasing lease %s: %v", e.Name, err)
+		return
+	}
+	log.Printf("🪑 %s released leadership for %s.", e.Holder, e.Name)
+}
@@ -0,0 +1,342 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"megga-backend/internal/config"
+	"megga-backend/internal/database"
+	"megga-backend/internal/models"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+type JobFunc func(ctx context.Context) error
+
+var (
+	ErrJobRunning = errors.New("job is already running")
+	ErrNotLeader  = errors.New("another instance is the leader")
+)
+
+const DefaultLeaderCheckInterval = time.Second
+
+type jobRunIDKey struct{}
+
+// JobRunID returns the job_runs row a job is executing under.
+func JobRunID(ctx context.Context) (int, bool) {
+	jobRunID, ok := ctx.Value(jobRunIDKey{}).(int)
+	return jobRunID, ok
+}
+
+type job struct {
+	name     string
+	schedule *Schedule
+	run      JobFunc
+	next     time.Time
+	running  sync.Mutex
+}
+
+// Scheduler runs registered jobs on cron schedules in a fixed time zone and
+// records every run in job_runs. On start, a job whose last recorded slot is
+// older than its most recent due time is run once to catch up; several missed
+// slots collapse into a single run.
+type Scheduler struct {
+	db       database.DBQuerier
+	location *time.Location
+	jobs     []*job
+	wg       sync.WaitGroup
+	ctx      context.Context
+
+	// Leader, when set, limits every run, scheduled, caught up or manual, to
+	// the instance holding the lease. A run is cancelled if the lease is lost
+	// while it is under way; leadership is rechecked every
+	// LeaderCheckInterval.
+	Leader              LeaderChecker
+	LeaderCheckInterval time.Duration
+	Now                 func() time.Time
+}
+
+func New(db database.DBQuerier, location *time.Location) *Scheduler {
+	return &Scheduler{
+		db:                  db,
+		location:            location,
+		LeaderCheckInterval: DefaultLeaderCheckInterval,
+		Now:                 time.Now,
+	}
+}
+
+func (s *Scheduler) Register(name, spec string, run JobFunc) error {
+	schedule, err := ParseSchedule(spec)
+	if err != nil {
+		return fmt.Errorf("error registering job %s: %w", name, err)
+	}
+	s.jobs = append(s.jobs, &job{name: name, schedule: schedule, run: run})
+	return nil
+}
+
+func (s *Scheduler) Start(ctx context.Context) {
+	s.ctx = ctx
+	now := s.Now().In(s.location)
+	for _, j := range s.jobs {
+		j.next = j.schedule.Next(now)
+		log.Printf("🗓️ Job %s next runs at %s", j.name, j.next.Format(time.RFC1123))
+	}
+
+	s.CatchUp(ctx)
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.loop(ctx)
+	}()
+}
+
+// Wait blocks until the scheduler loop and any in-flight runs have returned.
+func (s *Scheduler) Wait() {
+	s.wg.Wait()
+}
+
+// CatchUp runs each job once if a scheduled slot was missed while no instance
+// was running. Jobs that have never run are not caught up.
+func (s *Scheduler) CatchUp(ctx context.Context) {
+	if !s.isLeader() {
+		return
+	}
+
+	now := s.Now().In(s.location)
+	for _, j := range s.jobs {
+		last, err := s.lastScheduled(ctx, j.name)
+		if err != nil {
+			log.Printf("❌ Error checking last run of job %s: %v", j.name, err)
+			continue
+		}
+		if last == nil {
+			continue
+		}
+
+		missed := missedSlot(j.schedule, last.In(s.location), now)
+		if missed.IsZero() {
+			continue
+		}
+
+		log.Printf("⏪ Job %s missed its %s run, catching up.", j.name, missed.Format(time.RFC1123))
+		s.dispatch(ctx, j, missed, models.JobTriggerCatchUp)
+	}
+}
+
+// RunNow records a manual run of the named job and starts it in the
+// background, returning the job_runs ID. The run outlives ctx, which is only
+// used to record it. ErrNotLeader is returned on an instance that does not
+// hold the lease.
+func (s *Scheduler) RunNow(ctx context.Context, name string) (int, error) {
+	for _, j := range s.jobs {
+		if j.name != name {
+			continue
+		}
+		if !s.isLeader() {
+			return 0, ErrNotLeader
+		}
+
+		if !j.running.TryLock() {
+			return 0, ErrJobRunning
+		}
+		jobRunID, err := s.startRun(ctx, j.name, models.JobTriggerManual, s.Now().In(s.location))
+		if err != nil {
+			j.running.Unlock()
+			return 0, fmt.Errorf("error recording start of job %s: %w", j.name, err)
+		}
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer j.running.Unlock()
+			s.runRecorded(s.baseContext(), j, jobRunID, models.JobTriggerManual)
+		}()
+		return jobRunID, nil
+	}
+	return 0, fmt.Errorf("unknown job %q", name)
+}
+
+func (s *Scheduler) baseContext() context.Context {
+	if s.ctx == nil {
+		return context.Background()
+	}
+	return s.ctx
+}
+
+func (s *Scheduler) loop(ctx context.Context) {
+	for {
+		next := s.nextDue()
+		if next.IsZero() {
+			log.Println("⚠️ No schedulable jobs registered, scheduler loop exiting.")
+			return
+		}
+
+		timer := time.NewTimer(next.Sub(s.Now()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		now := s.Now()
+		for _, j := range s.jobs {
+			if j.next.IsZero() || j.next.After(now) {
+				continue
+			}
+			scheduledFor := j.next
+			j.next = j.schedule.Next(scheduledFor)
+			s.dispatch(ctx, j, scheduledFor, models.JobTriggerSchedule)
+		}
+	}
+}
+
+func (s *Scheduler) nextDue() time.Time {
+	var next time.Time
+	for _, j := range s.jobs {
+		if j.next.IsZero() {
+			continue
+		}
+		if next.IsZero() || j.next.Before(next) {
+			next = j.next
+		}
+	}
+	return next
+}
+
+func (s *Scheduler) dispatch(ctx context.Context, j *job, scheduledFor time.Time, trigger string) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.execute(ctx, j, scheduledFor, trigger)
+	}()
+}
+
+func (s *Scheduler) isLeader() bool {
+	return s.Leader == nil || s.Leader.IsLeader()
+}
+
+func (s *Scheduler) execute(ctx context.Context, j *job, scheduledFor time.Time, trigger string) {
+	if !s.isLeader() {
+		if config.IsDevelopmentMode() {
+			log.Printf("⏭️ Not the leader, leaving %s run of job %s to another instance.", trigger, j.name)
+		}
+		return
+	}
+
+	if !j.running.TryLock() {
+		log.Printf("⏭️ Job %s is still running, skipping %s run for %s.", j.name, trigger, scheduledFor.Format(time.RFC1123))
+		return
+	}
+	defer j.running.Unlock()
+
+	jobRunID, err := s.startRun(ctx, j.name, trigger, scheduledFor)
+	if err != nil {
+		log.Printf("❌ Error recording start of job %s: %v", j.name, err)
+		return
+	}
+
+	s.runRecorded(ctx, j, jobRunID, trigger)
+}
+
+func (s *Scheduler) runRecorded(ctx context.Context, j *job, jobRunID int, trigger string) {
+	log.Printf("▶️ Running job %s (%s, run %d)", j.name, trigger, jobRunID)
+	ctx, cancel := s.whileLeader(ctx)
+	defer cancel(nil)
+	runErr := j.run(context.WithValue(ctx, jobRunIDKey{}, jobRunID))
+	if runErr != nil && errors.Is(context.Cause(ctx), ErrNotLeader) {
+		runErr = fmt.Errorf("stopped after losing leadership: %w", runErr)
+	}
+	if runErr != nil {
+		log.Printf("❌ Job %s failed: %v", j.name, runErr)
+	} else {
+		log.Printf("✅ Job %s finished.", j.name)
+	}
+
+	if err := s.finishRun(ctx, jobRunID, runErr); err != nil {
+		log.Printf("❌ Error recording end of job %s: %v", j.name, err)
+	}
+}
+
+// whileLeader returns a context that is cancelled with ErrNotLeader once this
+// instance stops holding the lease, so a run that outlasts its leadership
+// stops before it writes anything more.
+func (s *Scheduler) whileLeader(ctx context.Context) (context.Context, context.CancelCauseFunc) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	if s.Leader == nil {
+		return ctx, cancel
+	}
+
+	go func() {
+		ticker := time.NewTicker(s.LeaderCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if !s.Leader.IsLeader() {
+					cancel(ErrNotLeader)
+					return
+				}
+			}
+		}
+	}()
+	return ctx, cancel
+}
+
+// missedSlot returns the most recent scheduled time after last and at or
+// before now, or the zero time if none was missed.
+func missedSlot(schedule *Schedule, last, now time.Time) time.Time {
+	var missed time.Time
+	for next := schedule.Next(last); !next.IsZero() && !next.After(now); next = schedule.Next(next) {
+		missed = next
+	}
+	return missed
+}
+
+// Timestamps are stored in UTC because job_runs uses TIMESTAMP columns.
+func (s *Scheduler) lastScheduled(ctx context.Context, name string) (*time.Time, error) {
+	var last time.Time
+	err := s.db.QueryRow(ctx, `
+		SELECT scheduled_for FROM job_runs
+		WHERE job_name = $1
+		ORDER BY scheduled_for DESC
+		LIMIT 1`, name).Scan(&last)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	last = time.Date(last.Year(), last.Month(), last.Day(), last.Hour(), last.Minute(), last.Second(), last.Nanosecond(), time.UTC)
+	return &last, nil
+}
+
+func (s *Scheduler) startRun(ctx context.Context, name, trigger string, scheduledFor time.Time) (int, error) {
+	var jobRunID int
+	err := s.db.QueryRow(ctx, `
+		INSERT INTO job_runs (job_name, trigger, scheduled_for, status, started_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		RETURNING job_run_id`,
+		name, trigger, scheduledFor.UTC(), models.JobRunning).Scan(&jobRunID)
+	return jobRunID, err
+}
+
+func (s *Scheduler) finishRun(ctx context.Context, jobRunID int, runErr error) error {
+	status, message := models.JobSucceeded, ""
+	if runErr != nil {
+		status, message = models.JobFailed, runErr.Error()
+	}
+
+	// The job's own context may already be cancelled on shutdown; still record
+	// how the run ended.
+	_, err := s.db.Exec(context.Background(), `
+		UPDATE job_runs SET status = $1, error = $2, finished_at = NOW()
+		WHERE job_run_id = $3`,
+		status, message, jobRunID)
+	return err
+}
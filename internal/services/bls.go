@@ -2,17 +2,30 @@ package services
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log"
+	"math"
 	"net/http"
 	"os"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
 	"megga-backend/internal/config"
 	"megga-backend/internal/database"
+	"megga-backend/internal/models"
+)
+
+const (
+	blsMaxYearsPerRequest  = 20
+	blsMaxSeriesPerRequest = 50
+	blsDefaultDailyLimit   = 500
+	blsDefaultQuotaReserve = 25
 )
 
 var BLS_API_URL = getBLSAPIURL()
@@ -42,32 +55,45 @@ func getBLSAPIKey() string {
 	return blsAPIKey
 }
 
+func getBLSDailyLimit() int {
+	return getEnvInt("BLS_DAILY_LIMIT", blsDefaultDailyLimit)
+}
+
+func getBLSQuotaReserve() int {
+	return getEnvInt("BLS_QUOTA_RESERVE", blsDefaultQuotaReserve)
+}
+
 type BLSResponse struct {
-	Status  string `json:"status"`
+	Status  string   `json:"status"`
+	Message []string `json:"message"`
 	Results struct {
 		Series []struct {
 			SeriesID string `json:"seriesID"`
 			Data     []struct {
-				Year   string `json:"year"`
-				Period string `json:"period"`
-				Value  string `json:"value"`
+				Year      string `json:"year"`
+				Period    string `json:"period"`
+				Value     string `json:"value"`
+				Footnotes []struct {
+					Code string `json:"code"`
+					Text string `json:"text"`
+				} `json:"footnotes"`
 			} `json:"data"`
 		} `json:"series"`
 	} `json:"Results"`
 }
 
+const blsStatusSucceeded = "REQUEST_SUCCEEDED"
+
+var blsSeriesMessagePattern = regexp.MustCompile(`(?i)for series\s+([A-Za-z0-9]+)`)
+
 func ParseBLSResponse(body []byte) (map[string]struct {
 	Value  float64
 	Year   string
 	Period string
 }, error) {
-	var blsResponse BLSResponse
-	if err := json.Unmarshal(body, &blsResponse); err != nil {
-		return nil, fmt.Errorf("error parsing JSON: %w", err)
-	}
-
-	if blsResponse.Status != "REQUEST_SUCCEEDED" {
-		return nil, errors.New("BLS API request failed: " + blsResponse.Status)
+	observations, err := ParseBLSObservations(body)
+	if err != nil {
+		return nil, err
 	}
 
 	blsData := make(map[string]struct {
@@ -76,19 +102,17 @@ func ParseBLSResponse(body []byte) (map[string]struct {
 		Period string
 	})
 
-	for _, series := range blsResponse.Results.Series {
-		if len(series.Data) == 0 {
+	for seriesID, series := range observations {
+		if len(series) == 0 {
 			continue
 		}
-		latestEntry := series.Data[0]
-		var latestValue float64
-		fmt.Sscanf(latestEntry.Value, "%f", &latestValue)
-		blsData[series.SeriesID] = struct {
+		latestEntry := series[0]
+		blsData[seriesID] = struct {
 			Value  float64
 			Year   string
 			Period string
 		}{
-			Value:  latestValue,
+			Value:  latestEntry.Value,
 			Year:   latestEntry.Year,
 			Period: latestEntry.Period,
 		}
@@ -97,61 +121,310 @@ func ParseBLSResponse(body []byte) (map[string]struct {
 	return blsData, nil
 }
 
-func FetchLatestBLSData(db database.DBQuerier) error {
-	BLS_API_URL = getBLSAPIURL()
-	log.Println("🌐 Fetching latest BLS data...")
+// ParseBLSObservations groups every parsable observation by series. A response
+// that was not fully successful is only rejected when it carries no data at all.
+func ParseBLSObservations(body []byte) (map[string][]models.DataObservation, error) {
+	result, status, err := parseBLSResult(body, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if status != blsStatusSucceeded && len(result.Observations) == 0 {
+		return nil, errors.New("BLS API request failed: " + status)
+	}
+
+	observations := make(map[string][]models.DataObservation)
+	for _, observation := range result.Observations {
+		observations[observation.SeriesID] = append(observations[observation.SeriesID], observation)
+	}
+	return observations, nil
+}
+
+// ParseBLSResult returns the observations in a BLS response along with an
+// outcome for each requested series, using the response message array to tell
+// invalid series, empty series and throttling apart.
+func ParseBLSResult(body []byte, requested []string) (FetchResult, error) {
+	result, _, err := parseBLSResult(body, requested)
+	return result, err
+}
 
-	seriesIDs := make([]string, 0, len(config.BLS_SERIES_INFO))
-	for seriesID := range config.BLS_SERIES_INFO {
-		seriesIDs = append(seriesIDs, seriesID)
+func parseBLSResult(body []byte, requested []string) (FetchResult, string, error) {
+	var blsResponse BLSResponse
+	if err := json.Unmarshal(body, &blsResponse); err != nil {
+		return FetchResult{}, "", fmt.Errorf("error parsing JSON: %w", err)
 	}
 
+	var result FetchResult
+	counts := make(map[string]int)
+
+	for _, series := range blsResponse.Results.Series {
+		for _, entry := range series.Data {
+			var value float64
+			if _, err := fmt.Sscanf(entry.Value, "%f", &value); err != nil {
+				log.Printf("⚠️ Skipping unparsable value %q for %s %s-%s", entry.Value, series.SeriesID, entry.Year, entry.Period)
+				continue
+			}
+
+			// BLS flags preliminary values with footnote code "P".
+			preliminary := false
+			var footnotes []string
+			for _, footnote := range entry.Footnotes {
+				if footnote.Code == "P" || strings.Contains(strings.ToLower(footnote.Text), "preliminary") {
+					preliminary = true
+				}
+				if footnote.Text != "" {
+					footnotes = append(footnotes, footnote.Text)
+				}
+			}
+
+			result.Observations = append(result.Observations, models.DataObservation{
+				SeriesID:    series.SeriesID,
+				Year:        entry.Year,
+				Period:      entry.Period,
+				Value:       value,
+				Preliminary: preliminary,
+				Footnotes:   strings.Join(footnotes, "; "),
+			})
+			counts[series.SeriesID]++
+		}
+	}
+
+	throttled := false
+	messageOutcomes := make(map[string]models.SeriesOutcome)
+	for _, message := range blsResponse.Message {
+		lower := strings.ToLower(message)
+		if strings.Contains(lower, "threshold") {
+			throttled = true
+			continue
+		}
+
+		match := blsSeriesMessagePattern.FindStringSubmatch(message)
+		if match == nil {
+			continue
+		}
+		outcome := models.OutcomeNoData
+		if strings.Contains(lower, "does not exist") || strings.Contains(lower, "invalid") {
+			outcome = models.OutcomeInvalidSeries
+		}
+		messageOutcomes[match[1]] = models.SeriesOutcome{
+			SeriesID: match[1],
+			Provider: models.ProviderBLS,
+			Outcome:  outcome,
+			Message:  message,
+		}
+	}
+
+	for _, seriesID := range requested {
+		outcome := models.SeriesOutcome{SeriesID: seriesID, Provider: models.ProviderBLS}
+		if fromMessage, exists := messageOutcomes[seriesID]; exists && counts[seriesID] == 0 {
+			outcome = fromMessage
+		} else if counts[seriesID] > 0 {
+			outcome.Outcome = models.OutcomeOK
+			outcome.ObservationCount = counts[seriesID]
+		} else if throttled {
+			outcome.Outcome = models.OutcomeThrottled
+			outcome.Message = strings.Join(blsResponse.Message, "; ")
+		} else if blsResponse.Status != blsStatusSucceeded {
+			outcome.Outcome = models.OutcomeError
+			outcome.Message = blsResponse.Status
+		} else {
+			outcome.Outcome = models.OutcomeNoData
+		}
+		result.Outcomes = append(result.Outcomes, outcome)
+	}
+
+	return result, blsResponse.Status, nil
+}
+
+type BLSProvider struct {
+	URL    string
+	APIKey string
+	Client *http.Client
+	Usage  *UsageTracker
+}
+
+func NewBLSProvider(db database.DBQuerier) *BLSProvider {
+	return &BLSProvider{
+		URL:    getBLSAPIURL(),
+		APIKey: config.BLS_API_KEY,
+		Client: &http.Client{Timeout: 10 * time.Second},
+		Usage:  NewUsageTracker(db, models.ProviderBLS, config.BLS_API_KEY, getBLSDailyLimit()),
+	}
+}
+
+func (p *BLSProvider) Name() string {
+	return models.ProviderBLS
+}
+
+// Fetch asks for the current and previous year rather than only the latest
+// value, so revisions to recent periods are picked up on every run.
+func (p *BLSProvider) Fetch(ctx context.Context, seriesIDs []string) (FetchResult, error) {
+	year := time.Now().Year()
+	return p.FetchRange(ctx, seriesIDs, year-1, year)
+}
+
+func (p *BLSProvider) FetchRange(ctx context.Context, seriesIDs []string, startYear, endYear int) (FetchResult, error) {
+	return p.request(ctx, seriesIDs, map[string]interface{}{
+		"startyear": strconv.Itoa(startYear),
+		"endyear":   strconv.Itoa(endYear),
+	})
+}
+
+func (p *BLSProvider) RequestsNeeded(seriesCount int) int {
+	return (seriesCount + blsMaxSeriesPerRequest - 1) / blsMaxSeriesPerRequest
+}
+
+func (p *BLSProvider) RemainingRequests(ctx context.Context) (int, error) {
+	if p.Usage == nil {
+		return math.MaxInt32, nil
+	}
+	return p.Usage.Remaining(ctx)
+}
+
+// request sends one BLS query per chunk of series. A chunk that fails outright
+// marks its own series as errored; an error is only returned when no chunk
+// could be fetched at all. Once BLS reports throttling, later chunks are not sent.
+func (p *BLSProvider) request(ctx context.Context, seriesIDs []string, params map[string]interface{}) (FetchResult, error) {
+	var result FetchResult
+	var lastErr error
+	succeeded := 0
+	throttled := false
+
+	for start := 0; start < len(seriesIDs); start += blsMaxSeriesPerRequest {
+		end := start + blsMaxSeriesPerRequest
+		if end > len(seriesIDs) {
+			end = len(seriesIDs)
+		}
+		chunkIDs := seriesIDs[start:end]
+
+		if throttled {
+			result.Outcomes = append(result.Outcomes, outcomesFor(models.ProviderBLS, chunkIDs, models.OutcomeThrottled, "skipped after BLS throttled an earlier request")...)
+			continue
+		}
+
+		chunk, err := p.requestChunk(ctx, chunkIDs, params)
+		if err != nil {
+			lastErr = err
+			result.Outcomes = append(result.Outcomes, outcomesFor(models.ProviderBLS, chunkIDs, models.OutcomeError, err.Error())...)
+			continue
+		}
+		succeeded++
+
+		result.Observations = append(result.Observations, chunk.Observations...)
+		result.Outcomes = append(result.Outcomes, chunk.Outcomes...)
+		for _, outcome := range chunk.Outcomes {
+			if outcome.Outcome == models.OutcomeThrottled {
+				throttled = true
+			}
+		}
+	}
+
+	if succeeded == 0 && lastErr != nil {
+		return result, lastErr
+	}
+	return result, nil
+}
+
+func (p *BLSProvider) requestChunk(ctx context.Context, seriesIDs []string, params map[string]interface{}) (FetchResult, error) {
 	payload := map[string]interface{}{
 		"seriesid":        seriesIDs,
-		"latest":          true,
-		"registrationkey": config.BLS_API_KEY,
+		"registrationkey": p.APIKey,
+	}
+	for key, value := range params {
+		payload[key] = value
 	}
 
-	client := &http.Client{Timeout: 10 * time.Second}
 	reqBody, err := json.Marshal(payload)
 	if err != nil {
-		return fmt.Errorf("error encoding request body: %w", err)
+		return FetchResult{}, fmt.Errorf("error encoding request body: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", BLS_API_URL, bytes.NewBuffer(reqBody))
+	req, err := http.NewRequestWithContext(ctx, "POST", p.URL, bytes.NewBuffer(reqBody))
 	if err != nil {
-		return fmt.Errorf("error creating request: %w", err)
+		return FetchResult{}, fmt.Errorf("error creating request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := client.Do(req)
+	resp, err := p.Client.Do(req)
 	if err != nil {
-		return fmt.Errorf("error making request to BLS API: %w", err)
+		return FetchResult{}, fmt.Errorf("error making request to BLS API: %w", err)
 	}
 	defer resp.Body.Close()
 
+	if p.Usage != nil {
+		if err := p.Usage.Record(ctx, 1); err != nil {
+			log.Printf("⚠️ Failed to record BLS API usage: %v", err)
+		}
+	}
+
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return fmt.Errorf("error reading response body: %w", err)
+		return FetchResult{}, fmt.Errorf("error reading response body: %w", err)
 	}
 
 	if config.IsDevelopmentMode() {
 		log.Printf("📥 BLS API response: %s", body)
 	}
 
-	blsData, err := ParseBLSResponse(body)
+	result, err := ParseBLSResult(body, seriesIDs)
 	if err != nil {
-		return fmt.Errorf("error parsing BLS response: %w", err)
+		return FetchResult{}, fmt.Errorf("error parsing BLS response: %w", err)
+	}
+
+	return result, nil
+}
+
+func FetchLatestBLSData(ctx context.Context, db database.DBQuerier) (models.IngestionResult, error) {
+	return IngestLatestData(ctx, db, DefaultProviders(db))
+}
+
+// BackfillBLSData loads the full observation history for every active BLS
+// series between startYear and endYear. Only data_observations is written; the
+// latest values on the data table are left to FetchLatestBLSData.
+func BackfillBLSData(db database.DBQuerier, startYear, endYear int) error {
+	if startYear <= 0 || endYear < startYear {
+		return fmt.Errorf("invalid backfill range %d-%d", startYear, endYear)
 	}
 
-	err = SaveBLSData(db, blsData)
+	log.Printf("🌐 Backfilling BLS data from %d to %d...", startYear, endYear)
+
+	seriesByProvider, err := activeSeriesByProvider(db)
 	if err != nil {
-		return fmt.Errorf("error saving BLS data: %w", err)
+		return err
+	}
+	seriesIDs := seriesIDsOf(seriesByProvider[models.ProviderBLS])
+	if len(seriesIDs) == 0 {
+		log.Println("🔄 No active BLS series to backfill.")
+		return nil
 	}
 
-	log.Println("✅ BLS data saved successfully.")
+	provider := NewBLSProvider(db)
+	totalInserted := 0
+	totalRevised := 0
+	for windowStart := startYear; windowStart <= endYear; windowStart += blsMaxYearsPerRequest {
+		windowEnd := windowStart + blsMaxYearsPerRequest - 1
+		if windowEnd > endYear {
+			windowEnd = endYear
+		}
+
+		result, err := provider.FetchRange(context.Background(), seriesIDs, windowStart, windowEnd)
+		if err != nil {
+			return fmt.Errorf("error backfilling %d-%d: %w", windowStart, windowEnd, err)
+		}
+		for _, outcome := range result.Outcomes {
+			if outcome.Outcome != models.OutcomeOK {
+				log.Printf("⚠️ %s %d-%d: %s %s", outcome.SeriesID, windowStart, windowEnd, outcome.Outcome, outcome.Message)
+			}
+		}
 
-	log.Println("🔍 Checking thresholds against updated BLS data...")
+		inserted, revisions, err := SaveObservations(db, result.Observations)
+		if err != nil {
+			return fmt.Errorf("error saving BLS observations for %d-%d: %w", windowStart, windowEnd, err)
+		}
+		totalInserted += inserted
+		totalRevised += len(revisions)
+	}
 
-	return SaveBLSData(db, blsData)
+	log.Printf("✅ BLS backfill complete. %d new observations stored, %d revised.", totalInserted, totalRevised)
+	return nil
 }
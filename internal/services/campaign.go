@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"megga-backend/internal/database"
+	"megga-backend/internal/models"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// errNoCampaignMembers is returned for a campaign that fires before anyone
+// has joined it, so there is no one to write on behalf of.
+var errNoCampaignMembers = errors.New("campaign has no members")
+
+// campaignLetters renders a fired campaign's letters on behalf of every
+// member, returning them with the number of members.
+func campaignLetters(ctx context.Context, db database.DBQuerier, campaign models.Campaign, threshold models.Threshold, alert Alert, recipients []models.Recipient) ([]models.RenderedEmail, int, error) {
+	members, err := fetchCampaignMembers(ctx, db, campaign.CampaignID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error fetching members of campaign %d: %w", campaign.CampaignID, err)
+	}
+	if len(members) == 0 {
+		return nil, 0, fmt.Errorf("campaign %d: %w", campaign.CampaignID, errNoCampaignMembers)
+	}
+
+	log.Printf("📨 Preparing notifications for campaign ID %d on behalf of %d members", campaign.CampaignID, len(members))
+
+	emails, err := renderCampaignNotifications(campaign, threshold, alert, recipients, members)
+	if err != nil {
+		return nil, 0, err
+	}
+	return emails, len(members), nil
+}
+
+// recordCampaignDelivery records a firing of a campaign for its owner, in the
+// transaction that queues its letters. The letters counted are those queued;
+// the stats count only those the outbox went on to send.
+func recordCampaignDelivery(tx pgx.Tx, campaignID, members int, emails []models.RenderedEmail) error {
+	letters := 0
+	for _, email := range emails {
+		if email.Kind == models.EmailKindRecipient {
+			letters++
+		}
+	}
+
+	_, err := tx.Exec(context.Background(),
+		"INSERT INTO campaign_deliveries (campaign_id, members, letters) VALUES ($1, $2, $3)",
+		campaignID, members, letters)
+	if err != nil {
+		return fmt.Errorf("error recording delivery of campaign %d: %w", campaignID, err)
+	}
+	return nil
+}
+
+func fetchCampaignMembers(ctx context.Context, db database.DBQuerier, campaignID int) ([]models.User, error) {
+	rows, err := db.Query(ctx, `
+		SELECT u.user_id, u.email, COALESCE(u.first_name, ''), COALESCE(u.last_name, '')
+		FROM campaign_members m
+		JOIN users u ON u.user_id = m.user_id
+		WHERE m.campaign_id = $1
+		ORDER BY m.joined_at, u.user_id`, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []models.User
+	for rows.Next() {
+		var member models.User
+		if err := rows.Scan(&member.UserID, &member.Email, &member.FirstName, &member.LastName); err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+	return members, rows.Err()
+}
+
+// GetCampaigns lists every campaign, newest first, with the series it watches
+// and how many users have joined.
+func GetCampaigns(ctx context.Context, db database.DBQuerier) ([]models.Campaign, error) {
+	rows, err := db.Query(ctx, `
+		SELECT c.campaign_id, c.owner_id, c.threshold_id, c.name, c.description, d.name,
+		       (SELECT COUNT(*) FROM campaign_members m WHERE m.campaign_id = c.campaign_id),
+		       c.letter_subject, c.letter_template, c.created_at
+		FROM campaigns c
+		JOIN thresholds t ON t.threshold_id = c.threshold_id
+		JOIN data d ON d.data_id = t.data_id
+		ORDER BY c.created_at DESC, c.campaign_id DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("error querying campaigns: %w", err)
+	}
+	defer rows.Close()
+
+	campaigns := []models.Campaign{}
+	for rows.Next() {
+		var campaign models.Campaign
+		if err := rows.Scan(&campaign.CampaignID, &campaign.OwnerID, &campaign.ThresholdID, &campaign.Name, &campaign.Description,
+			&campaign.DataName, &campaign.Members, &campaign.LetterSubject, &campaign.LetterTemplate, &campaign.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning campaign: %w", err)
+		}
+		campaigns = append(campaigns, campaign)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading campaigns: %w", err)
+	}
+	return campaigns, nil
+}
+
+// GetCampaignStats counts a campaign's members, its firings and the letters
+// to recipients the outbox has sent for it. Letters still waiting or given up
+// on are not counted.
+func GetCampaignStats(ctx context.Context, db database.DBQuerier, campaignID int) (models.CampaignStats, error) {
+	stats := models.CampaignStats{CampaignID: campaignID}
+	err := db.QueryRow(ctx, `
+		SELECT (SELECT COUNT(*) FROM campaign_members WHERE campaign_id = $1),
+		       (SELECT COUNT(*) FROM campaign_deliveries WHERE campaign_id = $1),
+		       COUNT(o.outbox_id), MAX(o.sent_at)
+		FROM notification_outbox o
+		WHERE o.campaign_id = $1 AND o.status = $2 AND o.kind = $3`, campaignID, models.OutboxSent, models.EmailKindRecipient).
+		Scan(&stats.Members, &stats.Deliveries, &stats.LettersSent, &stats.LastDeliveredAt)
+	if err != nil {
+		return stats, fmt.Errorf("error counting deliveries of campaign %d: %w", campaignID, err)
+	}
+	return stats, nil
+}
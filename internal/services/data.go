@@ -8,6 +8,7 @@ import (
 	"megga-backend/internal/config"
 	"megga-backend/internal/database"
 	"megga-backend/internal/models"
+	"sort"
 
 	"github.com/jackc/pgx/v4"
 )
@@ -17,40 +18,52 @@ func roundFloat(val float64, precision int) float64 {
 	return math.Round(val*multiplier) / multiplier
 }
 
+// periodBefore reports whether year/period a is older than b. Pairs that do not
+// parse fall back to string order.
+func periodBefore(yearA, periodA, yearB, periodB string) bool {
+	a, errA := models.ParsePeriod(yearA, periodA)
+	b, errB := models.ParsePeriod(yearB, periodB)
+	if errA != nil || errB != nil {
+		return yearA < yearB || (yearA == yearB && periodA < periodB)
+	}
+	return a.Before(b)
+}
+
+// SortObservations orders observations from oldest to newest period.
+func SortObservations(observations []models.DataObservation) {
+	sort.SliceStable(observations, func(i, j int) bool {
+		return periodBefore(observations[i].Year, observations[i].Period, observations[j].Year, observations[j].Period)
+	})
+}
+
 func SaveBLSData(db database.DBQuerier, blsData map[string]struct {
 	Value  float64
 	Year   string
 	Period string
 }) error {
+	_, err := saveLatestValues(db, blsData)
+	return err
+}
+
+// saveLatestValues updates the data table and returns the series whose latest
+// value changed, either because a newer period arrived or because the current
+// period was revised.
+func saveLatestValues(db database.DBQuerier, blsData map[string]struct {
+	Value  float64
+	Year   string
+	Period string
+}) ([]string, error) {
 	log.Println("💾 Saving BLS data to database...")
 
 	if len(blsData) == 0 {
 		log.Println("🔄 No BLS data to save.")
-		return nil
+		return nil, nil
 	}
 
-	updateQuery := `UPDATE data 
-					SET previous_value = latest_value, 
-						latest_value = $1, 
-						year = $2, 
-						period = $3, 
-						last_updated = NOW() 
-					WHERE data_id = $4`
-
-	insertQuery := `INSERT INTO data (name, series_id, unit, previous_value, latest_value, year, period, last_updated) 
-					VALUES ($1, $2, $3, $4, $5, $6, $7, NOW()) 
-					RETURNING data_id`
-
 	tx, err := db.BeginTx(context.Background(), pgx.TxOptions{})
 	if err != nil {
-		return fmt.Errorf("error starting transaction: %w", err)
+		return nil, fmt.Errorf("error starting transaction: %w", err)
 	}
-	defer func() {
-		if err != nil {
-			tx.Rollback(context.Background())
-		}
-	}()
-
 	defer func() {
 		if p := recover(); p != nil {
 			tx.Rollback(context.Background())
@@ -61,33 +74,77 @@ func SaveBLSData(db database.DBQuerier, blsData map[string]struct {
 		}
 	}()
 
-	changesMade := false
+	changed, err := updateLatestValues(context.Background(), tx, blsData)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(changed) == 0 {
+		log.Println("🔄 No updates were made, rolling back transaction.")
+		return nil, tx.Rollback(context.Background())
+	}
+
+	if err := tx.Commit(context.Background()); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	log.Println("✅ BLS data fetch complete.")
+	return changed, nil
+}
+
+// updateLatestValues applies blsData to the data table inside tx and returns
+// the changed series, sorted. New series are looked up in the catalog inside
+// tx as well.
+func updateLatestValues(ctx context.Context, tx pgx.Tx, blsData map[string]struct {
+	Value  float64
+	Year   string
+	Period string
+}) ([]string, error) {
+	updateQuery := `UPDATE data 
+					SET previous_value = latest_value, 
+						latest_value = $1, 
+						year = $2, 
+						period = $3, 
+						last_updated = NOW() 
+					WHERE data_id = $4`
+
+	reviseQuery := `UPDATE data SET latest_value = $1, last_updated = NOW() WHERE data_id = $2`
+
+	insertQuery := `INSERT INTO data (name, series_id, unit, previous_value, latest_value, year, period, last_updated) 
+					VALUES ($1, $2, $3, $4, $5, $6, $7, NOW()) 
+					RETURNING data_id`
+
+	var changed []string
+	var err error
 
 	for seriesID, data := range blsData {
 		var existing models.Data
-		queryErr := db.QueryRow(context.Background(),
+		queryErr := tx.QueryRow(ctx,
 			"SELECT data_id, latest_value, previous_value, year, period FROM data WHERE series_id = $1",
 			seriesID).Scan(&existing.DataID, &existing.LatestValue, &existing.PreviousValue, &existing.Year, &existing.Period)
 
 		if queryErr == pgx.ErrNoRows {
 			log.Printf("⚠️ No existing record found for series: %s, inserting new record.", seriesID)
 
-			info, exists := config.BLS_SERIES_INFO[seriesID]
-			if !exists {
-				log.Printf("❌ No series info found for %s, skipping.", seriesID)
+			info, infoErr := getActiveSeriesInTx(ctx, tx, seriesID)
+			if infoErr == pgx.ErrNoRows {
+				log.Printf("❌ No active catalog entry found for %s, skipping.", seriesID)
 				continue
+			} else if infoErr != nil {
+				err = fmt.Errorf("❌ Catalog lookup error: %w", infoErr)
+				return nil, err
 			}
 
 			roundedValue := roundFloat(data.Value, 2)
 
-			_, err = tx.Exec(context.Background(), insertQuery, info.Name, seriesID, info.Unit, roundedValue, roundedValue, data.Year, data.Period)
+			_, err = tx.Exec(ctx, insertQuery, info.Name, seriesID, info.Unit, roundedValue, roundedValue, data.Year, data.Period)
 
 			if err != nil {
 				log.Printf("❌ Error inserting new record for %s: %v", seriesID, err)
-				return fmt.Errorf("error inserting new record for series %s: %w", seriesID, err)
+				return nil, fmt.Errorf("error inserting new record for series %s: %w", seriesID, err)
 			}
 
-			changesMade = true
+			changed = append(changed, seriesID)
 			if config.IsDevelopmentMode() {
 				log.Printf("✅ Successfully inserted new record for %s", seriesID)
 			}
@@ -95,27 +152,46 @@ func SaveBLSData(db database.DBQuerier, blsData map[string]struct {
 
 		} else if queryErr != nil {
 			err = fmt.Errorf("❌ Database query error: %w", queryErr)
-			return err
+			return nil, err
 		}
 
 		if data.Year == existing.Year && data.Period == existing.Period {
+			roundedValue := roundFloat(data.Value, 2)
+			if roundedValue == roundFloat(existing.LatestValue, 2) {
+				if config.IsDevelopmentMode() {
+					log.Printf("✅ No update needed for %s: %s-%s already exists. Skipping update.", seriesID, data.Year, data.Period)
+				}
+				continue
+			}
+
+			log.Printf("✏️ %s %s-%s was revised from %.2f to %.2f", seriesID, data.Year, data.Period, existing.LatestValue, roundedValue)
+			_, err = tx.Exec(ctx, reviseQuery, roundedValue, existing.DataID)
+			if err != nil {
+				log.Printf("❌ Error executing revision query for %s: %v", seriesID, err)
+				return nil, fmt.Errorf("error revising series %s: %w", seriesID, err)
+			}
+			changed = append(changed, seriesID)
+			continue
+		}
+
+		if periodBefore(data.Year, data.Period, existing.Year, existing.Period) {
 			if config.IsDevelopmentMode() {
-				log.Printf("✅ No update needed for %s: %s-%s already exists. Skipping update.", seriesID, data.Year, data.Period)
+				log.Printf("✅ Ignoring %s %s-%s: already have the newer period %s-%s.", seriesID, data.Year, data.Period, existing.Year, existing.Period)
 			}
 			continue
 		}
 
-		changesMade = true
+		changed = append(changed, seriesID)
 		roundedValue := roundFloat(data.Value, 2)
 
 		if config.IsDevelopmentMode() {
 			log.Printf("🔄 Updating %s with value: %.2f, Year: %s, Period: %s", seriesID, roundedValue, data.Year, data.Period)
 		}
-		_, err = tx.Exec(context.Background(), updateQuery, roundedValue, data.Year, data.Period, existing.DataID)
+		_, err = tx.Exec(ctx, updateQuery, roundedValue, data.Year, data.Period, existing.DataID)
 
 		if err != nil {
 			log.Printf("❌ Error executing update query for %s: %v", seriesID, err)
-			return fmt.Errorf("error updating series %s: %w", seriesID, err)
+			return nil, fmt.Errorf("error updating series %s: %w", seriesID, err)
 		}
 
 		if config.IsDevelopmentMode() {
@@ -123,15 +199,101 @@ func SaveBLSData(db database.DBQuerier, blsData map[string]struct {
 		}
 	}
 
-	if !changesMade {
-		log.Println("🔄 No updates were made, rolling back transaction.")
-		return tx.Rollback(context.Background())
+	sort.Strings(changed)
+	return changed, nil
+}
+
+// SaveObservations stores new periods and overwrites periods whose value,
+// preliminary flag or footnotes changed. A changed value is also recorded in
+// data_revisions and returned, so owners can be told about the correction.
+func SaveObservations(db database.DBQuerier, observations []models.DataObservation) (int, []models.DataRevision, error) {
+	if len(observations) == 0 {
+		log.Println("🔄 No observations to save.")
+		return 0, nil, nil
+	}
+
+	tx, err := db.BeginTx(context.Background(), pgx.TxOptions{})
+	if err != nil {
+		return 0, nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(context.Background())
+
+	inserted, revisions, err := saveObservations(context.Background(), tx, observations)
+	if err != nil {
+		return 0, nil, err
 	}
 
 	if err := tx.Commit(context.Background()); err != nil {
-		return fmt.Errorf("error committing transaction: %w", err)
+		return 0, nil, fmt.Errorf("error committing transaction: %w", err)
 	}
+	return inserted, revisions, nil
+}
 
-	log.Println("✅ BLS data fetch complete.")
-	return nil
+// saveObservations is SaveObservations inside a transaction the caller owns.
+func saveObservations(ctx context.Context, tx pgx.Tx, observations []models.DataObservation) (int, []models.DataRevision, error) {
+	upsertQuery := `WITH previous AS (
+						SELECT value FROM data_observations
+						WHERE series_id = $1 AND year = $2 AND period = $3
+					), saved AS (
+						INSERT INTO data_observations (series_id, year, period, value, preliminary, footnotes, recorded_at)
+						VALUES ($1, $2, $3, $4, $5, $6, NOW())
+						ON CONFLICT (series_id, year, period) DO UPDATE
+						SET value = EXCLUDED.value, preliminary = EXCLUDED.preliminary, footnotes = EXCLUDED.footnotes, recorded_at = NOW()
+						WHERE (data_observations.value, data_observations.preliminary, data_observations.footnotes)
+							IS DISTINCT FROM (EXCLUDED.value, EXCLUDED.preliminary, EXCLUDED.footnotes)
+						RETURNING value
+					)
+					SELECT (SELECT value FROM previous), EXISTS (SELECT 1 FROM saved)`
+
+	revisionQuery := `INSERT INTO data_revisions (series_id, year, period, previous_value, revised_value, preliminary, detected_at)
+					VALUES ($1, $2, $3, $4, $5, $6, NOW())
+					RETURNING revision_id, detected_at`
+
+	inserted := 0
+	var revisions []models.DataRevision
+	for _, observation := range observations {
+		value := roundFloat(observation.Value, 2)
+
+		var previous *float64
+		var saved bool
+		err := tx.QueryRow(ctx, upsertQuery,
+			observation.SeriesID, observation.Year, observation.Period, value, observation.Preliminary, observation.Footnotes).Scan(&previous, &saved)
+		if err != nil {
+			return 0, nil, fmt.Errorf("error saving observation %s %s-%s: %w", observation.SeriesID, observation.Year, observation.Period, err)
+		}
+
+		if previous == nil {
+			if saved {
+				inserted++
+			}
+			continue
+		}
+		if !saved || *previous == value {
+			continue
+		}
+
+		revision := models.DataRevision{
+			SeriesID:      observation.SeriesID,
+			Year:          observation.Year,
+			Period:        observation.Period,
+			PreviousValue: *previous,
+			RevisedValue:  value,
+			Preliminary:   observation.Preliminary,
+		}
+		err = tx.QueryRow(ctx, revisionQuery,
+			revision.SeriesID, revision.Year, revision.Period, revision.PreviousValue, revision.RevisedValue, revision.Preliminary).
+			Scan(&revision.RevisionID, &revision.DetectedAt)
+		if err != nil {
+			return 0, nil, fmt.Errorf("error recording revision for %s %s-%s: %w", observation.SeriesID, observation.Year, observation.Period, err)
+		}
+		revisions = append(revisions, revision)
+	}
+
+	if len(revisions) > 0 {
+		log.Printf("✏️ Detected %d revised observations.", len(revisions))
+	}
+	if config.IsDevelopmentMode() {
+		log.Printf("✅ Stored %d new observations out of %d received.", inserted, len(observations))
+	}
+	return inserted, revisions, nil
 }
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"megga-backend/internal/database"
+	"megga-backend/internal/models"
+
+	"github.com/jackc/pgx/v4"
+)
+
+type revisionSubscriber struct {
+	UserID    int
+	Email     string
+	FirstName string
+	DataName  string
+}
+
+// ProcessRevisions queues a notice to the owners of thresholds on a revised
+// series that the number was corrected. Each revision is marked as processed
+// in the transaction that queues its notices, so a notice is neither lost nor
+// queued twice. A revision whose notices cannot be rendered is left for the
+// next run.
+func ProcessRevisions(db database.DBQuerier) (int, error) {
+	revisions, err := fetchUnprocessedRevisions(db)
+	if err != nil {
+		return 0, err
+	}
+
+	processed := 0
+	for _, revision := range revisions {
+		subscribers, err := fetchRevisionSubscribers(db, revision.SeriesID)
+		if err != nil {
+			log.Printf("❌ Error fetching subscribers for %s: %v", revision.SeriesID, err)
+			continue
+		}
+
+		emails, err := renderRevisionNotices(revision, subscribers)
+		if err != nil {
+			log.Printf("❌ Error formatting revision email for revision %d: %v", revision.RevisionID, err)
+			continue
+		}
+
+		if err := queueRevisionNotices(db, revision.RevisionID, emails); err != nil {
+			return processed, err
+		}
+		processed++
+	}
+
+	return processed, nil
+}
+
+func renderRevisionNotices(revision models.DataRevision, subscribers []revisionSubscriber) ([]models.RenderedEmail, error) {
+	preliminaryNote := ""
+	if revision.Preliminary {
+		preliminaryNote = "The revised value is still marked preliminary and may change again."
+	}
+
+	emails := make([]models.RenderedEmail, 0, len(subscribers))
+	for _, subscriber := range subscribers {
+		message, err := formatEmailFromTemplate("data_revision.txt", map[string]string{
+			"User First Name":  subscriber.FirstName,
+			"Data Name":        subscriber.DataName,
+			"Period":           models.PeriodLabel(revision.Year, revision.Period),
+			"Previous Value":   fmt.Sprintf("%.2f", revision.PreviousValue),
+			"Revised Value":    fmt.Sprintf("%.2f", revision.RevisedValue),
+			"Preliminary Note": preliminaryNote,
+		})
+		if err != nil {
+			return nil, err
+		}
+		emails = append(emails, models.RenderedEmail{Kind: models.EmailKindUser, To: subscriber.Email, Subject: "A Number Behind Your MEGGA Threshold Was Corrected", Body: message})
+	}
+	return emails, nil
+}
+
+func queueRevisionNotices(db database.DBQuerier, revisionID int, emails []models.RenderedEmail) error {
+	tx, err := db.BeginTx(context.Background(), pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(context.Background())
+
+	if err := queueEmails(tx, nil, nil, emails); err != nil {
+		return fmt.Errorf("error queueing notices for revision %d: %w", revisionID, err)
+	}
+
+	_, err = tx.Exec(context.Background(),
+		"UPDATE data_revisions SET processed_at = NOW() WHERE revision_id = $1", revisionID)
+	if err != nil {
+		return fmt.Errorf("error marking revision %d as processed: %w", revisionID, err)
+	}
+
+	if err := tx.Commit(context.Background()); err != nil {
+		return fmt.Errorf("error committing revision %d: %w", revisionID, err)
+	}
+	return nil
+}
+
+func fetchUnprocessedRevisions(db database.DBQuerier) ([]models.DataRevision, error) {
+	rows, err := db.Query(context.Background(), `
+		SELECT revision_id, series_id, year, period, previous_value, revised_value, preliminary, detected_at
+		FROM data_revisions
+		WHERE processed_at IS NULL
+		ORDER BY detected_at, revision_id`)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching data revisions: %w", err)
+	}
+	defer rows.Close()
+
+	var revisions []models.DataRevision
+	for rows.Next() {
+		var revision models.DataRevision
+		if err := rows.Scan(&revision.RevisionID, &revision.SeriesID, &revision.Year, &revision.Period,
+			&revision.PreviousValue, &revision.RevisedValue, &revision.Preliminary, &revision.DetectedAt); err != nil {
+			return nil, fmt.Errorf("error scanning data revision: %w", err)
+		}
+		revisions = append(revisions, revision)
+	}
+	return revisions, rows.Err()
+}
+
+func fetchRevisionSubscribers(db database.DBQuerier, seriesID string) ([]revisionSubscriber, error) {
+	rows, err := db.Query(context.Background(), `
+		SELECT DISTINCT u.user_id, u.email, u.first_name, d.name
+		FROM thresholds t
+		JOIN data d ON t.data_id = d.data_id
+		JOIN users u ON t.user_id = u.user_id
+		WHERE d.series_id = $1 AND t.notify_user = TRUE`, seriesID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subscribers []revisionSubscriber
+	for rows.Next() {
+		var subscriber revisionSubscriber
+		if err := rows.Scan(&subscriber.UserID, &subscriber.Email, &subscriber.FirstName, &subscriber.DataName); err != nil {
+			return nil, err
+		}
+		subscribers = append(subscribers, subscriber)
+	}
+	return subscribers, rows.Err()
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"megga-backend/internal/config"
+	"megga-backend/internal/models"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultEIAAPIURL = "https://api.eia.gov/v2/seriesid/"
+
+type EIAProvider struct {
+	URL    string
+	APIKey string
+	Client *http.Client
+}
+
+func NewEIAProvider() *EIAProvider {
+	eiaURL := os.Getenv("EIA_API_URL")
+	if eiaURL == "" {
+		eiaURL = defaultEIAAPIURL
+	}
+	return &EIAProvider{
+		URL:    eiaURL,
+		APIKey: os.Getenv("EIA_API_KEY"),
+		Client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type EIAResponse struct {
+	Response struct {
+		Data []struct {
+			Period string      `json:"period"`
+			Value  interface{} `json:"value"`
+		} `json:"data"`
+	} `json:"response"`
+}
+
+func (p *EIAProvider) Name() string {
+	return models.ProviderEIA
+}
+
+func (p *EIAProvider) Fetch(ctx context.Context, seriesIDs []string) (FetchResult, error) {
+	return fetchEach(models.ProviderEIA, seriesIDs, func(seriesID string) ([]models.DataObservation, error) {
+		return p.fetchSeries(ctx, seriesID)
+	})
+}
+
+func (p *EIAProvider) fetchSeries(ctx context.Context, seriesID string) ([]models.DataObservation, error) {
+	params := url.Values{}
+	params.Set("api_key", p.APIKey)
+	params.Set("sort[0][column]", "period")
+	params.Set("sort[0][direction]", "desc")
+	params.Set("length", "120")
+
+	endpoint := strings.TrimSuffix(p.URL, "/") + "/" + url.PathEscape(seriesID) + "?" + params.Encode()
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error making request to EIA API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &httpStatusError{Provider: "EIA", SeriesID: seriesID, StatusCode: resp.StatusCode}
+	}
+
+	if config.IsDevelopmentMode() {
+		log.Printf("📥 EIA API response for %s: %s", seriesID, body)
+	}
+
+	return ParseEIAResponse(seriesID, body)
+}
+
+func ParseEIAResponse(seriesID string, body []byte) ([]models.DataObservation, error) {
+	var eiaResponse EIAResponse
+	if err := json.Unmarshal(body, &eiaResponse); err != nil {
+		return nil, fmt.Errorf("error parsing JSON: %w", err)
+	}
+
+	var observations []models.DataObservation
+	seen := make(map[string]bool)
+	for _, entry := range eiaResponse.Response.Data {
+		var value float64
+		switch v := entry.Value.(type) {
+		case float64:
+			value = v
+		case string:
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			value = parsed
+		default:
+			continue
+		}
+
+		year, period, err := normalizeDatePeriod(entry.Period)
+		if err != nil {
+			log.Printf("⚠️ Skipping EIA observation for %s: %v", seriesID, err)
+			continue
+		}
+		if seen[year+period] {
+			continue
+		}
+		seen[year+period] = true
+
+		observations = append(observations, models.DataObservation{
+			SeriesID: seriesID,
+			Year:     year,
+			Period:   period,
+			Value:    value,
+		})
+	}
+
+	return observations, nil
+}
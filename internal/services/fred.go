@@ -0,0 +1,147 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"megga-backend/internal/config"
+	"megga-backend/internal/models"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+)
+
+const defaultFREDAPIURL = "https://api.stlouisfed.org/fred/series/observations"
+
+type FREDProvider struct {
+	URL    string
+	APIKey string
+	Client *http.Client
+}
+
+func NewFREDProvider() *FREDProvider {
+	fredURL := os.Getenv("FRED_API_URL")
+	if fredURL == "" {
+		fredURL = defaultFREDAPIURL
+	}
+	return &FREDProvider{
+		URL:    fredURL,
+		APIKey: os.Getenv("FRED_API_KEY"),
+		Client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type FREDResponse struct {
+	Observations []struct {
+		Date  string `json:"date"`
+		Value string `json:"value"`
+	} `json:"observations"`
+}
+
+func (p *FREDProvider) Name() string {
+	return models.ProviderFRED
+}
+
+// Fetch issues one request per series because the FRED observations endpoint
+// does not accept multiple series IDs.
+func (p *FREDProvider) Fetch(ctx context.Context, seriesIDs []string) (FetchResult, error) {
+	return fetchEach(models.ProviderFRED, seriesIDs, func(seriesID string) ([]models.DataObservation, error) {
+		return p.fetchSeries(ctx, seriesID)
+	})
+}
+
+func (p *FREDProvider) fetchSeries(ctx context.Context, seriesID string) ([]models.DataObservation, error) {
+	params := url.Values{}
+	params.Set("series_id", seriesID)
+	params.Set("api_key", p.APIKey)
+	params.Set("file_type", "json")
+	params.Set("sort_order", "desc")
+	params.Set("limit", "24")
+
+	req, err := http.NewRequestWithContext(ctx, "GET", p.URL+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error making request to FRED API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &httpStatusError{Provider: "FRED", SeriesID: seriesID, StatusCode: resp.StatusCode}
+	}
+
+	if config.IsDevelopmentMode() {
+		log.Printf("📥 FRED API response for %s: %s", seriesID, body)
+	}
+
+	return ParseFREDResponse(seriesID, body)
+}
+
+func ParseFREDResponse(seriesID string, body []byte) ([]models.DataObservation, error) {
+	var fredResponse FREDResponse
+	if err := json.Unmarshal(body, &fredResponse); err != nil {
+		return nil, fmt.Errorf("error parsing JSON: %w", err)
+	}
+
+	var observations []models.DataObservation
+	seen := make(map[string]bool)
+	for _, entry := range fredResponse.Observations {
+		value, err := strconv.ParseFloat(entry.Value, 64)
+		if err != nil {
+			// FRED reports missing values as "."
+			continue
+		}
+
+		year, period, err := normalizeDatePeriod(entry.Date)
+		if err != nil {
+			log.Printf("⚠️ Skipping FRED observation for %s: %v", seriesID, err)
+			continue
+		}
+		if seen[year+period] {
+			continue
+		}
+		seen[year+period] = true
+
+		observations = append(observations, models.DataObservation{
+			SeriesID: seriesID,
+			Year:     year,
+			Period:   period,
+			Value:    value,
+		})
+	}
+
+	return observations, nil
+}
+
+// normalizeDatePeriod maps provider dates onto BLS year/period codes. Daily and
+// weekly dates collapse into their month.
+func normalizeDatePeriod(date string) (string, string, error) {
+	for _, layout := range []string{"2006-01-02", "2006-01"} {
+		if parsed, err := time.Parse(layout, date); err == nil {
+			return strconv.Itoa(parsed.Year()), fmt.Sprintf("M%02d", parsed.Month()), nil
+		}
+	}
+
+	var year, quarter int
+	if n, _ := fmt.Sscanf(date, "%d-Q%d", &year, &quarter); n == 2 && quarter >= 1 && quarter <= 4 {
+		return strconv.Itoa(year), fmt.Sprintf("Q%02d", quarter), nil
+	}
+
+	if parsed, err := time.Parse("2006", date); err == nil {
+		return strconv.Itoa(parsed.Year()), "A01", nil
+	}
+
+	return "", "", fmt.Errorf("unrecognized date %q", date)
+}
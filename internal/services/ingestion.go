@@ -0,0 +1,262 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"megga-backend/internal/database"
+	"megga-backend/internal/models"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// IngestLatestData fetches every active series from its provider and stores
+// the results. The run is recorded in ingestion_runs together with one outcome
+// per series, so series that failed or came back empty stay visible. When the
+// latest value of a series changes or one of its periods is revised, the
+// thresholds on it are evaluated and the counts are recorded with the run.
+func IngestLatestData(ctx context.Context, db database.DBQuerier, providers map[string]DataProvider) (models.IngestionResult, error) {
+	log.Println("🌐 Fetching latest data from providers...")
+
+	result, err := startIngestionRun(ctx, db)
+	if err != nil {
+		return result, err
+	}
+
+	var changedSeries []string
+	observations, runErr := fetchFromProviders(ctx, db, providers, &result)
+	if runErr == nil {
+		changedSeries, result.Revisions, runErr = saveIngestedData(ctx, db, observations)
+	}
+	if runErr == nil && len(result.Revisions) > 0 {
+		if _, err := ProcessRevisions(db); err != nil {
+			log.Printf("❌ Error processing data revisions: %v", err)
+		}
+	}
+	if runErr == nil && len(changedSeries) > 0 {
+		check, err := CheckThresholdsForSeries(ctx, db, changedSeries)
+		if err != nil {
+			log.Printf("❌ Error checking thresholds: %v", err)
+			check.Error = err.Error()
+		}
+		result.Thresholds = &check
+	} else if runErr == nil {
+		log.Println("🔄 No series changed, skipping threshold checks.")
+	}
+
+	finishErr := finishIngestionRun(ctx, db, &result, runErr)
+	if runErr != nil {
+		return result, runErr
+	}
+	return result, finishErr
+}
+
+func fetchFromProviders(ctx context.Context, db database.DBQuerier, providers map[string]DataProvider, result *models.IngestionResult) ([]models.DataObservation, error) {
+	seriesByProvider, err := activeSeriesByProvider(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var observations []models.DataObservation
+	var failures []error
+
+	for providerName, series := range seriesByProvider {
+		provider, exists := providers[providerName]
+		if !exists {
+			log.Printf("⚠️ No provider configured for %q, skipping %d series.", providerName, len(series))
+			result.Series = append(result.Series, outcomesFor(providerName, seriesIDsOf(series), models.OutcomeError, "no provider configured")...)
+			continue
+		}
+
+		seriesIDs := seriesIDsOf(series)
+		if limited, ok := provider.(QuotaLimitedProvider); ok {
+			remaining, err := limited.RemainingRequests(ctx)
+			if err != nil {
+				log.Printf("❌ Error checking %s quota: %v", providerName, err)
+				failures = append(failures, fmt.Errorf("%s: %w", providerName, err))
+				result.Series = append(result.Series, outcomesFor(providerName, seriesIDs, models.OutcomeError, err.Error())...)
+				continue
+			}
+
+			var deferred []string
+			seriesIDs, deferred = planQuotaDeferrals(series, remaining, getBLSQuotaReserve(), limited.RequestsNeeded)
+			if len(deferred) > 0 {
+				log.Printf("⏸️ %s quota is low (%d requests left). Deferred %d series: %v", providerName, remaining, len(deferred), deferred)
+				result.Series = append(result.Series, outcomesFor(providerName, deferred, models.OutcomeDeferred, "daily request quota is low")...)
+			}
+			if len(seriesIDs) == 0 {
+				continue
+			}
+		}
+
+		fetched, err := provider.Fetch(ctx, seriesIDs)
+		if err != nil {
+			log.Printf("❌ Error fetching data from %s: %v", providerName, err)
+			failures = append(failures, fmt.Errorf("%s: %w", providerName, err))
+		}
+		observations = append(observations, fetched.Observations...)
+		result.Series = append(result.Series, completeOutcomes(providerName, seriesIDs, fetched.Outcomes, err)...)
+	}
+
+	sort.Slice(result.Series, func(i, j int) bool {
+		return result.Series[i].SeriesID < result.Series[j].SeriesID
+	})
+	for _, outcome := range result.Series {
+		if outcome.Outcome != models.OutcomeOK {
+			log.Printf("⚠️ %s (%s): %s %s", outcome.SeriesID, outcome.Provider, outcome.Outcome, outcome.Message)
+		}
+	}
+
+	if len(observations) == 0 && len(failures) > 0 {
+		return nil, fmt.Errorf("error fetching provider data: %w", errors.Join(failures...))
+	}
+	return observations, nil
+}
+
+// completeOutcomes makes sure every requested series has an outcome, even when
+// the provider stopped early or did not report on it.
+func completeOutcomes(providerName string, seriesIDs []string, outcomes []models.SeriesOutcome, fetchErr error) []models.SeriesOutcome {
+	reported := make(map[string]bool)
+	for _, outcome := range outcomes {
+		reported[outcome.SeriesID] = true
+	}
+
+	for _, seriesID := range seriesIDs {
+		if reported[seriesID] {
+			continue
+		}
+		outcome := models.SeriesOutcome{SeriesID: seriesID, Provider: providerName, Outcome: models.OutcomeNoData}
+		if fetchErr != nil {
+			outcome.Outcome = models.OutcomeError
+			outcome.Message = fetchErr.Error()
+		}
+		outcomes = append(outcomes, outcome)
+	}
+	return outcomes
+}
+
+// saveIngestedData stores the latest values and the observation history in
+// one transaction. It returns the series to re-evaluate, those whose latest
+// value changed or whose earlier periods were revised, along with the
+// revisions themselves.
+func saveIngestedData(ctx context.Context, db database.DBQuerier, observations []models.DataObservation) ([]string, []models.DataRevision, error) {
+	if len(observations) == 0 {
+		log.Println("🔄 No observations to save.")
+		return nil, nil, nil
+	}
+
+	tx, err := db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(context.Background())
+
+	changedSeries, err := updateLatestValues(ctx, tx, latestObservations(observations))
+	if err != nil {
+		return nil, nil, fmt.Errorf("error saving BLS data: %w", err)
+	}
+
+	_, revisions, err := saveObservations(ctx, tx, observations)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error saving observations: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	log.Println("✅ BLS data saved successfully.")
+	return seriesToCheck(changedSeries, revisions), revisions, nil
+}
+
+// seriesToCheck merges the changed series with every revised series, since a
+// corrected earlier period can change a percent or year-over-year comparison.
+func seriesToCheck(changedSeries []string, revisions []models.DataRevision) []string {
+	seen := make(map[string]bool, len(changedSeries))
+	for _, seriesID := range changedSeries {
+		seen[seriesID] = true
+	}
+	for _, revision := range revisions {
+		if !seen[revision.SeriesID] {
+			seen[revision.SeriesID] = true
+			changedSeries = append(changedSeries, revision.SeriesID)
+		}
+	}
+	sort.Strings(changedSeries)
+	return changedSeries
+}
+
+func ingestionStatus(series []models.SeriesOutcome, runErr error) string {
+	if runErr != nil {
+		return models.IngestionFailed
+	}
+	for _, outcome := range series {
+		if outcome.Outcome != models.OutcomeOK {
+			return models.IngestionPartial
+		}
+	}
+	return models.IngestionSucceeded
+}
+
+func startIngestionRun(ctx context.Context, db database.DBQuerier) (models.IngestionResult, error) {
+	result := models.IngestionResult{Status: models.IngestionRunning}
+	err := db.QueryRow(ctx, `
+		INSERT INTO ingestion_runs (status, started_at)
+		VALUES ($1, NOW())
+		RETURNING ingestion_run_id, started_at`,
+		result.Status).Scan(&result.IngestionRunID, &result.StartedAt)
+	if err != nil {
+		return result, fmt.Errorf("error creating ingestion run: %w", err)
+	}
+	return result, nil
+}
+
+func finishIngestionRun(ctx context.Context, db database.DBQuerier, result *models.IngestionResult, runErr error) error {
+	result.Status = ingestionStatus(result.Series, runErr)
+	if runErr != nil {
+		result.Error = runErr.Error()
+	}
+
+	tx, err := db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	for _, outcome := range result.Series {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO ingestion_series_results (ingestion_run_id, series_id, provider, outcome, message, observation_count)
+			VALUES ($1, $2, $3, $4, $5, $6)`,
+			result.IngestionRunID, outcome.SeriesID, outcome.Provider, outcome.Outcome, outcome.Message, outcome.ObservationCount)
+		if err != nil {
+			return fmt.Errorf("error recording outcome for %s: %w", outcome.SeriesID, err)
+		}
+	}
+
+	// Threshold counts stay NULL when no evaluation ran.
+	var checked, triggered interface{}
+	if result.Thresholds != nil {
+		checked, triggered = result.Thresholds.Checked, result.Thresholds.Triggered
+	}
+
+	var finishedAt time.Time
+	err = tx.QueryRow(ctx, `
+		UPDATE ingestion_runs SET status = $1, error = $2, thresholds_checked = $3, thresholds_triggered = $4, finished_at = NOW()
+		WHERE ingestion_run_id = $5
+		RETURNING finished_at`,
+		result.Status, result.Error, checked, triggered, result.IngestionRunID).Scan(&finishedAt)
+	if err != nil {
+		return fmt.Errorf("error finishing ingestion run: %w", err)
+	}
+	result.FinishedAt = &finishedAt
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	log.Printf("📋 Ingestion run %d finished with status %s (%d series).", result.IngestionRunID, result.Status, len(result.Series))
+	return nil
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"megga-backend/internal/database"
+	"megga-backend/internal/models"
+	"megga-backend/internal/scheduler"
+)
+
+const IngestJobName = "ingest"
+
+// RunIngestJob is the body of the ingest job. When it runs under the scheduler
+// the ingestion run is linked to its job run, so the admin job endpoints can
+// show per-series outcomes.
+func RunIngestJob(ctx context.Context, db database.DBQuerier) error {
+	result, err := FetchLatestBLSData(ctx, db)
+
+	if jobRunID, ok := scheduler.JobRunID(ctx); ok && result.IngestionRunID != 0 {
+		_, linkErr := db.Exec(ctx, "UPDATE job_runs SET ingestion_run_id = $1 WHERE job_run_id = $2", result.IngestionRunID, jobRunID)
+		if linkErr != nil {
+			log.Printf("❌ Error linking ingestion run %d to job run %d: %v", result.IngestionRunID, jobRunID, linkErr)
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+	log.Printf("✅ Successfully updated BLS data (ingestion run %d, %s).", result.IngestionRunID, result.Status)
+	if result.Thresholds != nil {
+		log.Printf("📋 Ingestion run %d checked %d thresholds on %d changed series, %d triggered.",
+			result.IngestionRunID, result.Thresholds.Checked, len(result.Thresholds.SeriesIDs), result.Thresholds.Triggered)
+	}
+	return nil
+}
+
+const jobRunColumns = `job_run_id, job_name, trigger, scheduled_for, status, error, started_at, finished_at, ingestion_run_id`
+
+// GetJobRuns returns the most recent job runs, newest first, with the outcome
+// of any ingestion they started.
+func GetJobRuns(ctx context.Context, db database.DBQuerier, limit int) ([]models.JobRun, error) {
+	rows, err := db.Query(ctx, `
+		SELECT `+jobRunColumns+`
+		FROM job_runs
+		ORDER BY started_at DESC, job_run_id DESC
+		LIMIT $1`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error querying job runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := []models.JobRun{}
+	for rows.Next() {
+		var run models.JobRun
+		if err := rows.Scan(&run.JobRunID, &run.JobName, &run.Trigger, &run.ScheduledFor, &run.Status, &run.Error, &run.StartedAt, &run.FinishedAt, &run.IngestionRunID); err != nil {
+			return nil, fmt.Errorf("error scanning job run: %w", err)
+		}
+		runs = append(runs, run)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading job runs: %w", err)
+	}
+
+	if err := attachIngestions(ctx, db, runs); err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
+
+// GetJobRun returns a single job run. pgx.ErrNoRows is wrapped when it does
+// not exist.
+func GetJobRun(ctx context.Context, db database.DBQuerier, jobRunID int) (models.JobRun, error) {
+	var run models.JobRun
+	err := db.QueryRow(ctx, `
+		SELECT `+jobRunColumns+`
+		FROM job_runs
+		WHERE job_run_id = $1`, jobRunID).
+		Scan(&run.JobRunID, &run.JobName, &run.Trigger, &run.ScheduledFor, &run.Status, &run.Error, &run.StartedAt, &run.FinishedAt, &run.IngestionRunID)
+	if err != nil {
+		return run, fmt.Errorf("error fetching job run %d: %w", jobRunID, err)
+	}
+
+	runs := []models.JobRun{run}
+	if err := attachIngestions(ctx, db, runs); err != nil {
+		return run, err
+	}
+	return runs[0], nil
+}
+
+// attachIngestions fills in durations and loads linked ingestion runs with
+// their series outcomes in two queries, however many runs there are.
+func attachIngestions(ctx context.Context, db database.DBQuerier, runs []models.JobRun) error {
+	var ingestionRunIDs []int
+	for i := range runs {
+		if runs[i].FinishedAt != nil {
+			duration := runs[i].FinishedAt.Sub(runs[i].StartedAt).Seconds()
+			runs[i].DurationSeconds = &duration
+		}
+		if runs[i].IngestionRunID != nil {
+			ingestionRunIDs = append(ingestionRunIDs, *runs[i].IngestionRunID)
+		}
+	}
+	if len(ingestionRunIDs) == 0 {
+		return nil
+	}
+
+	rows, err := db.Query(ctx, `
+		SELECT ingestion_run_id, status, error, started_at, finished_at, thresholds_checked, thresholds_triggered
+		FROM ingestion_runs
+		WHERE ingestion_run_id = ANY($1)`, ingestionRunIDs)
+	if err != nil {
+		return fmt.Errorf("error querying ingestion runs: %w", err)
+	}
+	ingestions := make(map[int]*models.IngestionResult)
+	for rows.Next() {
+		ingestion := &models.IngestionResult{Series: []models.SeriesOutcome{}}
+		var checked, triggered *int
+		if err := rows.Scan(&ingestion.IngestionRunID, &ingestion.Status, &ingestion.Error, &ingestion.StartedAt, &ingestion.FinishedAt, &checked, &triggered); err != nil {
+			rows.Close()
+			return fmt.Errorf("error scanning ingestion run: %w", err)
+		}
+		if checked != nil && triggered != nil {
+			ingestion.Thresholds = &models.ThresholdCheck{Checked: *checked, Triggered: *triggered}
+		}
+		ingestions[ingestion.IngestionRunID] = ingestion
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error reading ingestion runs: %w", err)
+	}
+
+	rows, err = db.Query(ctx, `
+		SELECT ingestion_run_id, series_id, provider, outcome, message, observation_count
+		FROM ingestion_series_results
+		WHERE ingestion_run_id = ANY($1)
+		ORDER BY ingestion_run_id, series_id`, ingestionRunIDs)
+	if err != nil {
+		return fmt.Errorf("error querying ingestion series results: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var ingestionRunID int
+		var outcome models.SeriesOutcome
+		if err := rows.Scan(&ingestionRunID, &outcome.SeriesID, &outcome.Provider, &outcome.Outcome, &outcome.Message, &outcome.ObservationCount); err != nil {
+			return fmt.Errorf("error scanning ingestion series result: %w", err)
+		}
+		if ingestion, ok := ingestions[ingestionRunID]; ok {
+			ingestion.Series = append(ingestion.Series, outcome)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error reading ingestion series results: %w", err)
+	}
+
+	for i := range runs {
+		if runs[i].IngestionRunID != nil {
+			runs[i].Ingestion = ingestions[*runs[i].IngestionRunID]
+		}
+	}
+	return nil
+}
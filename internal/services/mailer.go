@@ -0,0 +1,206 @@
+package services
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"megga-backend/internal/config"
+	"megga-backend/internal/models"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+)
+
+// Mailer delivers rendered letters. Letters are sent from the monitor's
+// workers, so implementations must be safe for concurrent use.
+type Mailer interface {
+	Send(email models.RenderedEmail) error
+}
+
+// mailer is the backend the outbox dispatcher sends through. It is set once at startup.
+var mailer Mailer = LogMailer{}
+
+// SetMailer makes m the backend letters are sent through.
+func SetMailer(m Mailer) {
+	mailer = m
+}
+
+// NewMailer returns the backend the configuration selects.
+func NewMailer(cfg config.MailConfig) (Mailer, error) {
+	switch cfg.Backend {
+	case config.MailBackendLog:
+		return LogMailer{}, nil
+	case config.MailBackendSMTP:
+		if cfg.SMTPHost == "" || cfg.From == "" {
+			return nil, fmt.Errorf("the smtp mail backend needs SMTP_HOST and SENDER_EMAIL")
+		}
+		return SMTPMailer{
+			Addr:     net.JoinHostPort(cfg.SMTPHost, cfg.SMTPPort),
+			Host:     cfg.SMTPHost,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.From,
+		}, nil
+	case config.MailBackendFile:
+		return FileMailer{Dir: cfg.DropDir, From: cfg.From}, nil
+	}
+	return nil, fmt.Errorf("unknown mail backend %q", cfg.Backend)
+}
+
+// LogMailer writes letters to the log instead of sending them.
+type LogMailer struct{}
+
+func (LogMailer) Send(email models.RenderedEmail) error {
+	log.Printf("📧 [MOCK EMAIL] To: %s | Subject: %s", email.To, email.Subject)
+	log.Println("📧 Email Body:")
+	log.Println(email.Body)
+	return nil
+}
+
+// smtpTimeout bounds a whole SMTP session, from dialling to QUIT. It is well
+// under the outbox's claim lease, so a stuck server fails the attempt before
+// another dispatcher could claim the letter again.
+const smtpTimeout = time.Minute
+
+// SMTPMailer sends letters through an SMTP server, upgrading to TLS when the
+// server offers it.
+type SMTPMailer struct {
+	Addr     string // host:port
+	Host     string // Server name for TLS and plain auth
+	Username string // Plain auth is used only when this is set
+	Password string
+	From     string
+	Timeout  time.Duration // Limit on each session; smtpTimeout when zero
+}
+
+func (m SMTPMailer) Send(email models.RenderedEmail) error {
+	message, err := formatMessage(m.From, email, time.Now())
+	if err != nil {
+		return err
+	}
+	if err := m.deliver(email.To, message); err != nil {
+		return fmt.Errorf("error sending through %s: %w", m.Addr, err)
+	}
+	log.Printf("📧 Sent email to %s | Subject: %s", email.To, email.Subject)
+	return nil
+}
+
+// deliver runs one SMTP session under a deadline, doing what smtp.SendMail
+// does but without letting a silent server hold the letter's claim.
+func (m SMTPMailer) deliver(to string, message []byte) error {
+	timeout := m.Timeout
+	if timeout == 0 {
+		timeout = smtpTimeout
+	}
+	conn, err := net.DialTimeout("tcp", m.Addr, timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return err
+	}
+
+	client, err := smtp.NewClient(conn, m.Host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: m.Host}); err != nil {
+			return err
+		}
+	}
+	if m.Username != "" {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return fmt.Errorf("server does not support authentication")
+		}
+		if err := client.Auth(smtp.PlainAuth("", m.Username, m.Password, m.Host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(m.From); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+	data, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := data.Write(message); err != nil {
+		return err
+	}
+	if err := data.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// FileMailer writes each letter to its own .eml file in Dir, named so the
+// files sort in the order they were sent.
+type FileMailer struct {
+	Dir  string
+	From string
+}
+
+func (m FileMailer) Send(email models.RenderedEmail) error {
+	now := time.Now()
+	message, err := formatMessage(m.From, email, now)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return fmt.Errorf("error creating mail directory %s: %w", m.Dir, err)
+	}
+	file, err := os.CreateTemp(m.Dir, now.UTC().Format("20060102T150405.000000000")+"-*.eml")
+	if err != nil {
+		return fmt.Errorf("error creating email file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(message); err != nil {
+		return fmt.Errorf("error writing email file %s: %w", file.Name(), err)
+	}
+	log.Printf("📧 Wrote email to %s | File: %s", email.To, file.Name())
+	return nil
+}
+
+// formatMessage renders a letter as an RFC 5322 message with a
+// quoted-printable UTF-8 text body.
+func formatMessage(from string, email models.RenderedEmail, date time.Time) ([]byte, error) {
+	var message bytes.Buffer
+	headers := [][2]string{
+		{"From", from},
+		{"To", email.To},
+		{"Subject", mime.QEncoding.Encode("utf-8", email.Subject)},
+		{"Date", date.Format(time.RFC1123Z)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "text/plain; charset=UTF-8"},
+		{"Content-Transfer-Encoding", "quoted-printable"},
+	}
+	for _, header := range headers {
+		if strings.ContainsAny(header[1], "\r\n") {
+			return nil, fmt.Errorf("invalid %s header %q", header[0], header[1])
+		}
+		fmt.Fprintf(&message, "%s: %s\r\n", header[0], header[1])
+	}
+	message.WriteString("\r\n")
+
+	body := quotedprintable.NewWriter(&message)
+	if _, err := body.Write([]byte(strings.ReplaceAll(strings.ReplaceAll(email.Body, "\r\n", "\n"), "\n", "\r\n"))); err != nil {
+		return nil, err
+	}
+	if err := body.Close(); err != nil {
+		return nil, err
+	}
+	return message.Bytes(), nil
+}
@@ -1,76 +1,176 @@
 package services
 
 import (
-	"context"
+	"errors"
 	"fmt"
 	"log"
-	"megga-backend/internal/database"
+	"math"
 	"megga-backend/internal/models"
 	"os"
 	"path/filepath"
 	"strings"
 )
 
-type EmailPayload struct {
-	APIKey    string `json:"apikey"`
-	Namespace string `json:"namespace"`
-	To        string `json:"to"`
-	Subject   string `json:"subject"`
-	Body      string `json:"body"`
+// Alert describes what set off a threshold for its letters. DataName, Latest
+// and Change describe the threshold's own condition, or for compound
+// thresholds the first condition that was met; AlsoMet describes any other met
+// conditions and is listed after the change. Explanation is the evaluation's
+// account of why the threshold fired and goes to the threshold's owner. Tier
+// is the escalation tier in effect, whose letter, if it has one, replaces the
+// usual letter to recipients.
+type Alert struct {
+	DataName    string
+	Latest      float64
+	Change      float64
+	AlsoMet     []string
+	Explanation string
+	Tier        *models.EscalationTier
 }
 
-func SendNotifications(threshold models.Threshold, dataName string, percentChange float64, recipients []models.Recipient, userEmail string) {
-	log.Println("📨 Preparing mock notifications for threshold ID:", threshold.ThresholdID)
+// RenderNotifications fills in the letters for a triggered threshold: one per
+// recipient, then one to the threshold's owner if they opted in. Letters whose
+// template cannot be read are logged and left out.
+func RenderNotifications(threshold models.Threshold, alert Alert, recipients []models.Recipient, userEmail string) []models.RenderedEmail {
+	emails, err := renderNotifications(threshold, alert, recipients, userEmail)
+	if err != nil {
+		log.Printf("❌ %v", err)
+	}
+	return emails
+}
 
+// renderNotifications is RenderNotifications, reporting the letters it left
+// out as an error.
+func renderNotifications(threshold models.Threshold, alert Alert, recipients []models.Recipient, userEmail string) ([]models.RenderedEmail, error) {
+	emails := []models.RenderedEmail{}
+	var errs []error
 	if len(recipients) > 0 {
-		thresholdExceeded := percentChange > threshold.ThresholdValue
 		var emailTemplate string
-		if thresholdExceeded {
+		if isRise(threshold.PrimaryCondition(), alert.Change) {
 			emailTemplate = "recipient_notification_bad.txt"
 		} else {
 			emailTemplate = "recipient_notification_good.txt"
 		}
+		sender := models.User{
+			FirstName: os.Getenv("SENDER_FIRST_NAME"),
+			LastName:  os.Getenv("SENDER_LAST_NAME"),
+			Email:     os.Getenv("SENDER_EMAIL"),
+		}
 
 		for _, recipient := range recipients {
-			subject := fmt.Sprintf("Urgent: %s Economic Data Alert", dataName)
-			message, err := formatEmailFromTemplate(emailTemplate, map[string]string{
-				"Recipient Name":    recipient.FirstName + " " + recipient.LastName,
-				"Threshold Name":    dataName,
-				"Change Percentage": fmt.Sprintf("%.2f", percentChange),
-				"User First Name":   os.Getenv("SENDER_FIRST_NAME"),
-				"User Last Name":    os.Getenv("SENDER_LAST_NAME"),
-				"User Email":        os.Getenv("SENDER_EMAIL"),
-			})
+			subject := fmt.Sprintf("Urgent: %s Economic Data Alert", alert.DataName)
+			replacements := recipientReplacements(recipient, alert, sender)
+			if tierSubject, tierMessage, ok := tierLetter(alert.Tier, subject, replacements); ok {
+				emails = append(emails, models.RenderedEmail{Kind: models.EmailKindRecipient, To: recipient.Email, Subject: tierSubject, Body: tierMessage})
+				continue
+			}
+			message, err := formatEmailFromTemplate(emailTemplate, replacements)
 			if err != nil {
-				log.Printf("❌ Error formatting recipient email: %v", err)
+				errs = append(errs, fmt.Errorf("error formatting recipient email: %w", err))
 				continue
 			}
 
-			log.Printf("📧 [MOCK EMAIL] To: %s | Subject: %s", recipient.Email, subject)
-			log.Println("📧 Email Body:")
-			log.Println(message)
+			emails = append(emails, models.RenderedEmail{Kind: models.EmailKindRecipient, To: recipient.Email, Subject: subject, Body: message})
 		}
 	}
 
 	if threshold.NotifyUser {
-		userMessage, err := formatEmailFromTemplate("user_notification.txt", map[string]string{
-			"User First Name":   os.Getenv("SENDER_FIRST_NAME"),
-			"Threshold Name":    dataName,
-			"New Value":         fmt.Sprintf("%.2f", percentChange),
-			"Threshold Value":   fmt.Sprintf("%.2f", threshold.ThresholdValue),
-			"Change Percentage": fmt.Sprintf("%.2f", percentChange),
-			"Good/Bad":          determineChangeDirection(percentChange, threshold.ThresholdValue),
-			"Recipient List":    formatRecipientList(recipients),
-		})
+		email, err := renderUserNotification(threshold, alert, recipients, os.Getenv("SENDER_FIRST_NAME"), userEmail)
 		if err != nil {
-			log.Printf("❌ Error formatting user email: %v", err)
-			return
+			errs = append(errs, fmt.Errorf("error formatting user email: %w", err))
+		} else {
+			emails = append(emails, email)
 		}
+	}
+	return emails, errors.Join(errs...)
+}
+
+// RenderCampaignNotifications fills in the letters for a campaign that fired.
+// For each member in turn, the campaign's letter goes to every recipient
+// signed with the member's name, followed by the member's own notification.
+// An escalation tier's letter takes the place of the campaign's. Member
+// notifications that cannot be rendered are logged and left out.
+func RenderCampaignNotifications(campaign models.Campaign, threshold models.Threshold, alert Alert, recipients []models.Recipient, members []models.User) []models.RenderedEmail {
+	emails, err := renderCampaignNotifications(campaign, threshold, alert, recipients, members)
+	if err != nil {
+		log.Printf("❌ %v", err)
+	}
+	return emails
+}
+
+// renderCampaignNotifications is RenderCampaignNotifications, reporting the
+// letters it left out as an error.
+func renderCampaignNotifications(campaign models.Campaign, threshold models.Threshold, alert Alert, recipients []models.Recipient, members []models.User) ([]models.RenderedEmail, error) {
+	emails := []models.RenderedEmail{}
+	var errs []error
+	for _, member := range members {
+		for _, recipient := range recipients {
+			replacements := recipientReplacements(recipient, alert, member)
+			subject, message, ok := tierLetter(alert.Tier, campaign.LetterSubject, replacements)
+			if !ok {
+				subject, message = fillTemplate(campaign.LetterSubject, replacements), fillTemplate(campaign.LetterTemplate, replacements)
+			}
+			emails = append(emails, models.RenderedEmail{
+				Kind:    models.EmailKindRecipient,
+				To:      recipient.Email,
+				Subject: subject,
+				Body:    message,
+			})
+		}
+
+		email, err := renderUserNotification(threshold, alert, recipients, member.FirstName, member.Email)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error formatting email to campaign member %d: %w", member.UserID, err))
+			continue
+		}
+		emails = append(emails, email)
+	}
+	return emails, errors.Join(errs...)
+}
+
+// tierLetter fills in an escalation tier's own letter, keeping the given
+// subject when the tier has none. It reports false when there is no tier or
+// the tier only adds recipients.
+func tierLetter(tier *models.EscalationTier, subject string, replacements map[string]string) (string, string, bool) {
+	if tier == nil || tier.LetterTemplate == "" {
+		return "", "", false
+	}
+	if tier.LetterSubject != "" {
+		subject = tier.LetterSubject
+	}
+	return fillTemplate(subject, replacements), fillTemplate(tier.LetterTemplate, replacements), true
+}
+
+// recipientReplacements fills in a letter to a recipient, signed by sender.
+func recipientReplacements(recipient models.Recipient, alert Alert, sender models.User) map[string]string {
+	return map[string]string{
+		"Recipient Name":    recipient.FirstName + " " + recipient.LastName,
+		"Threshold Name":    alert.DataName,
+		"Change Percentage": fmt.Sprintf("%.2f", math.Abs(alert.Change)),
+		"Conditions Met":    formatConditionsMet(alert.AlsoMet),
+		"User First Name":   sender.FirstName,
+		"User Last Name":    sender.LastName,
+		"User Email":        sender.Email,
+	}
+}
 
-		log.Printf("📧 [MOCK EMAIL] To: %s | Subject: %s", userEmail, "Your MEGGA Threshold Was Hit - Here's What to Do Next")
-		log.Println("📧 Email Body:")
-		log.Println(userMessage)
+// renderUserNotification fills in the letter telling a user that a threshold
+// fired and who was written to on their behalf.
+func renderUserNotification(threshold models.Threshold, alert Alert, recipients []models.Recipient, firstName, email string) (models.RenderedEmail, error) {
+	message, err := formatEmailFromTemplate("user_notification.txt", map[string]string{
+		"User First Name":   firstName,
+		"Threshold Name":    alert.DataName,
+		"New Value":         fmt.Sprintf("%.2f", alert.Latest),
+		"Threshold Value":   fmt.Sprintf("%.2f", threshold.ThresholdValue),
+		"Change Percentage": fmt.Sprintf("%.2f", alert.Change),
+		"Good/Bad":          determineChangeDirection(isRise(threshold.PrimaryCondition(), alert.Change)),
+		"Conditions Met":    formatConditionsMet(alert.AlsoMet),
+		"Explanation":       alert.Explanation,
+		"Recipient List":    formatRecipientList(recipients),
+	})
+	if err != nil {
+		return models.RenderedEmail{}, err
 	}
+	return models.RenderedEmail{Kind: models.EmailKindUser, To: email, Subject: "Your MEGGA Threshold Was Hit - Here's What to Do Next", Body: message}, nil
 }
 
 func formatRecipientList(recipients []models.Recipient) string {
@@ -81,8 +181,50 @@ func formatRecipientList(recipients []models.Recipient) string {
 	return recipientList.String()
 }
 
-func determineChangeDirection(percentChange, thresholdValue float64) (direction string) {
-	if percentChange > thresholdValue {
+// formatConditionsMet lists the other met conditions of a compound threshold
+// as a paragraph that follows the change; it is empty when there are none.
+func formatConditionsMet(alsoMet []string) string {
+	if len(alsoMet) == 0 {
+		return ""
+	}
+	var conditions strings.Builder
+	conditions.WriteString("\n\nAt the same time:")
+	for _, description := range alsoMet {
+		conditions.WriteString("\n- " + description)
+	}
+	return conditions.String()
+}
+
+// describeCondition puts a met condition in words, such as "Eggs, Grade A,
+// Large rose 10.50% from the previous period".
+func describeCondition(name string, result ConditionResult) string {
+	condition := result.Condition
+	if condition.Mode == models.ThresholdModeAbsolute {
+		bound := "at or above"
+		if condition.Direction == models.ThresholdDirectionFall {
+			bound = "at or below"
+		}
+		return fmt.Sprintf("%s is at %.2f, %s %.2f", name, result.Observed, bound, condition.Value)
+	}
+
+	verb := "rose"
+	if result.Observed < 0 {
+		verb = "fell"
+	}
+	var since string
+	switch condition.Mode {
+	case models.ThresholdModePercentSinceCreated:
+		since = "since the threshold was set"
+	case models.ThresholdModePercentYoY:
+		since = "from a year earlier"
+	default:
+		since = "from the previous period"
+	}
+	return fmt.Sprintf("%s %s %.2f%% %s", name, verb, math.Abs(result.Observed), since)
+}
+
+func determineChangeDirection(rising bool) (direction string) {
+	if rising {
 		direction = "bad"
 	} else {
 		direction = "good"
@@ -102,43 +244,15 @@ func formatEmailFromTemplate(templateFile string, replacements map[string]string
 		return "", fmt.Errorf("failed to read email template %s: %w", templatePath, err)
 	}
 
-	message := string(content)
-	for placeholder, value := range replacements {
-		message = strings.ReplaceAll(message, "["+placeholder+"]", value)
-	}
-	return message, nil
-}
-
-func fetchRecipientsForThreshold(db database.DBQuerier, thresholdID int) ([]models.Recipient, error) {
-	var recipients []models.Recipient
-	rows, err := db.Query(context.Background(),
-		`SELECT r.recipient_id, r.email, r.first_name, r.last_name, r.designation
-		FROM recipients r
-		JOIN threshold_recipients tr ON r.recipient_id = tr.recipient_id
-		WHERE tr.threshold_id = $1`, thresholdID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var recipient models.Recipient
-		if err := rows.Scan(&recipient.RecipientID, &recipient.Email, &recipient.FirstName, &recipient.LastName, &recipient.Designation); err != nil {
-			return nil, err
-		}
-		recipients = append(recipients, recipient)
-	}
-	return recipients, nil
+	return fillTemplate(string(content), replacements), nil
 }
 
-func fetchUserEmail(db database.DBQuerier, userID int) string {
-	var email string
-	err := db.QueryRow(context.Background(), "SELECT email FROM users WHERE user_id = $1", userID).Scan(&email)
-	if err != nil {
-		log.Printf("❌ Failed to fetch user email for user ID %d: %v", userID, err)
-		return ""
+// fillTemplate replaces each [Placeholder] in a letter with its value.
+func fillTemplate(message string, replacements map[string]string) string {
+	for placeholder, value := range replacements {
+		message = strings.ReplaceAll(message, "["+placeholder+"]", value)
 	}
-	return email
+	return message
 }
 
 func findProjectRoot() string {
@@ -0,0 +1,261 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"megga-backend/internal/config"
+	"megga-backend/internal/database"
+	"megga-backend/internal/models"
+	"megga-backend/internal/scheduler"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+const (
+	maxOutboxRetryDelay = 6 * time.Hour
+	// outboxClaimLease is how long a claimed letter is left to the dispatcher
+	// that claimed it before another may try it.
+	outboxClaimLease = 5 * time.Minute
+)
+
+// thresholdLetters are the letters a firing queues, with the campaign whose
+// delivery they make up, if any.
+type thresholdLetters struct {
+	emails   []models.RenderedEmail
+	campaign *models.Campaign
+	members  int
+}
+
+// enqueueNotifications writes a firing's letters to the outbox, in the
+// transaction that stores the threshold's new state.
+func enqueueNotifications(tx pgx.Tx, thresholdID int, letters thresholdLetters) error {
+	var campaignID *int
+	if letters.campaign != nil {
+		campaignID = &letters.campaign.CampaignID
+		if err := recordCampaignDelivery(tx, letters.campaign.CampaignID, letters.members, letters.emails); err != nil {
+			return err
+		}
+	}
+
+	return queueEmails(tx, &thresholdID, campaignID, letters.emails)
+}
+
+// queueEmails writes letters to the outbox for the dispatcher to send.
+// Letters that are not about a threshold or campaign leave those IDs unset.
+func queueEmails(tx pgx.Tx, thresholdID, campaignID *int, emails []models.RenderedEmail) error {
+	for _, email := range emails {
+		_, err := tx.Exec(context.Background(), `
+			INSERT INTO notification_outbox (threshold_id, campaign_id, kind, to_address, subject, body)
+			VALUES ($1, $2, $3, $4, $5, $6)`,
+			thresholdID, campaignID, email.Kind, email.To, email.Subject, email.Body)
+		if err != nil {
+			return fmt.Errorf("error queueing email to %s: %w", email.To, err)
+		}
+	}
+	return nil
+}
+
+// OutboxRetryDelay is how long a letter waits after its nth failed attempt:
+// the configured delay, doubled for each failure before it, up to six hours.
+func OutboxRetryDelay(attempts int) time.Duration {
+	delay := config.OutboxRetryDelay()
+	for i := 1; i < attempts && delay < maxOutboxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxOutboxRetryDelay {
+		return maxOutboxRetryDelay
+	}
+	return delay
+}
+
+// RunOutboxDispatcher sends queued letters every interval until ctx is
+// cancelled, skipping the ticks on which leader says this instance is not the
+// leader. Each letter is also claimed before it is sent, so an instance that
+// loses the lease mid-run does not send a letter the new leader has taken.
+func RunOutboxDispatcher(ctx context.Context, db database.DBQuerier, leader scheduler.LeaderChecker, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !leader.IsLeader() {
+				continue
+			}
+			dispatch, err := DispatchOutbox(ctx, db)
+			if err != nil {
+				log.Printf("❌ %v", err)
+			}
+			if dispatch.Sent+dispatch.Retried+dispatch.Dead > 0 {
+				log.Printf("📬 Outbox: %d sent, %d to retry, %d dead.", dispatch.Sent, dispatch.Retried, dispatch.Dead)
+			}
+		}
+	}
+}
+
+// DispatchOutbox sends the letters that are due, one at a time, until none
+// are left or ctx is cancelled.
+func DispatchOutbox(ctx context.Context, db database.DBQuerier) (models.OutboxDispatch, error) {
+	var dispatch models.OutboxDispatch
+	for ctx.Err() == nil {
+		sent, err := dispatchOutboxLetter(ctx, db, &dispatch)
+		if err != nil || !sent {
+			return dispatch, err
+		}
+	}
+	return dispatch, nil
+}
+
+// dispatchOutboxLetter claims the next due letter, tries it once and records
+// the outcome, reporting false when no letter was due. Claiming and recording
+// are statements of their own, so no row is locked while the letter is sent
+// and each outcome is kept whatever happens to the letters after it. A letter
+// is sent again only if the process stops between sending it and recording
+// that, once its claim has lapsed. The claim counts the attempt, so a letter
+// whose sends keep crashing the process is given up on like one that fails.
+func dispatchOutboxLetter(ctx context.Context, db database.DBQuerier, dispatch *models.OutboxDispatch) (bool, error) {
+	letter, err := claimOutboxLetter(ctx, db)
+	if err != nil {
+		return false, fmt.Errorf("error claiming outbox letter: %w", err)
+	}
+	if letter == nil {
+		return false, nil
+	}
+
+	// A letter claimed more often than it may be tried has had its last
+	// attempt cut short, so it is not sent again.
+	attempts, maxAttempts := letter.Attempts, config.OutboxMaxAttempts()
+	var sendErr error
+	if attempts > maxAttempts {
+		sendErr = fmt.Errorf("no outcome was recorded for the last attempt")
+	} else {
+		email := models.RenderedEmail{Kind: letter.Kind, To: letter.To, Subject: letter.Subject, Body: letter.Body}
+		sendErr = mailer.Send(email)
+	}
+
+	// The letter has been tried, so its outcome is recorded even if ctx was
+	// cancelled meanwhile.
+	recordCtx := context.WithoutCancel(ctx)
+	switch {
+	case sendErr == nil:
+		_, err = db.Exec(recordCtx, `
+			UPDATE notification_outbox
+			SET status = $2, attempts = $3, sent_at = NOW(), last_error = ''
+			WHERE outbox_id = $1`, letter.OutboxID, models.OutboxSent, attempts)
+		dispatch.Sent++
+	case attempts >= maxAttempts:
+		log.Printf("❌ Giving up on outbox letter %d to %s after %d attempts: %v", letter.OutboxID, letter.To, attempts, sendErr)
+		_, err = db.Exec(recordCtx, `
+			UPDATE notification_outbox
+			SET status = $2, attempts = $3, last_error = $4
+			WHERE outbox_id = $1`, letter.OutboxID, models.OutboxDead, attempts, sendErr.Error())
+		dispatch.Dead++
+	default:
+		log.Printf("⚠️ Outbox letter %d to %s failed (attempt %d), retrying: %v", letter.OutboxID, letter.To, attempts, sendErr)
+		_, err = db.Exec(recordCtx, `
+			UPDATE notification_outbox
+			SET attempts = $2, last_error = $3, next_attempt_at = NOW() + make_interval(secs => $4)
+			WHERE outbox_id = $1`, letter.OutboxID, attempts, sendErr.Error(), OutboxRetryDelay(attempts).Seconds())
+		dispatch.Retried++
+	}
+	if err != nil {
+		return false, fmt.Errorf("error recording outcome of outbox letter %d: %w", letter.OutboxID, err)
+	}
+	return true, nil
+}
+
+const outboxColumns = `outbox_id, threshold_id, campaign_id, kind, to_address, subject, body, status, attempts, next_attempt_at, last_error, created_at, sent_at`
+
+// claimOutboxLetter takes the next due letter by pushing its next attempt
+// past the claim lease, so other dispatchers skip it while it is sent, and
+// counts the attempt. It returns nil when no letter is due.
+func claimOutboxLetter(ctx context.Context, db database.DBQuerier) (*models.OutboxNotification, error) {
+	rows, err := db.Query(ctx, `
+		UPDATE notification_outbox
+		SET attempts = attempts + 1, next_attempt_at = NOW() + make_interval(secs => $2)
+		WHERE outbox_id = (
+			SELECT outbox_id
+			FROM notification_outbox
+			WHERE status = $1 AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at, outbox_id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED)
+		RETURNING `+outboxColumns, models.OutboxPending, outboxClaimLease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	letters, err := scanOutboxLetters(rows)
+	if err != nil || len(letters) == 0 {
+		return nil, err
+	}
+	return &letters[0], nil
+}
+
+func scanOutboxLetters(rows pgx.Rows) ([]models.OutboxNotification, error) {
+	letters := []models.OutboxNotification{}
+	for rows.Next() {
+		var letter models.OutboxNotification
+		if err := rows.Scan(&letter.OutboxID, &letter.ThresholdID, &letter.CampaignID, &letter.Kind, &letter.To, &letter.Subject, &letter.Body,
+			&letter.Status, &letter.Attempts, &letter.NextAttemptAt, &letter.LastError, &letter.CreatedAt, &letter.SentAt); err != nil {
+			return nil, err
+		}
+		letters = append(letters, letter)
+	}
+	return letters, rows.Err()
+}
+
+// GetOutbox returns the most recent letters in the outbox, newest first,
+// optionally only those with the given status.
+func GetOutbox(ctx context.Context, db database.DBQuerier, status string, limit int) ([]models.OutboxNotification, error) {
+	rows, err := db.Query(ctx, `
+		SELECT `+outboxColumns+`
+		FROM notification_outbox
+		WHERE $1 = '' OR status = $1
+		ORDER BY created_at DESC, outbox_id DESC
+		LIMIT $2`, status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error querying outbox: %w", err)
+	}
+	defer rows.Close()
+
+	letters, err := scanOutboxLetters(rows)
+	if err != nil {
+		return nil, fmt.Errorf("error reading outbox: %w", err)
+	}
+	return letters, nil
+}
+
+// RequeueDeadLetter gives a dead letter a fresh set of attempts, starting now.
+// pgx.ErrNoRows is wrapped when there is no dead letter with that ID.
+func RequeueDeadLetter(ctx context.Context, db database.DBQuerier, outboxID int) error {
+	tag, err := db.Exec(ctx, `
+		UPDATE notification_outbox
+		SET status = $2, attempts = 0, next_attempt_at = NOW()
+		WHERE outbox_id = $1 AND status = $3`, outboxID, models.OutboxPending, models.OutboxDead)
+	if err != nil {
+		return fmt.Errorf("error requeueing outbox letter %d: %w", outboxID, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("no dead letter %d: %w", outboxID, pgx.ErrNoRows)
+	}
+	return nil
+}
+
+// DiscardDeadLetter removes a dead letter from the outbox. pgx.ErrNoRows is
+// wrapped when there is no dead letter with that ID.
+func DiscardDeadLetter(ctx context.Context, db database.DBQuerier, outboxID int) error {
+	tag, err := db.Exec(ctx, "DELETE FROM notification_outbox WHERE outbox_id = $1 AND status = $2", outboxID, models.OutboxDead)
+	if err != nil {
+		return fmt.Errorf("error discarding outbox letter %d: %w", outboxID, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("no dead letter %d: %w", outboxID, pgx.ErrNoRows)
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"megga-backend/internal/database"
+	"megga-backend/internal/models"
+	"net/http"
+	"os"
+	"sort"
+)
+
+// DataProvider fetches the most recent observations for the given series from
+// an upstream source and normalizes them into BLS-style year/period pairs.
+// Fetch should report an outcome for every requested series and only return an
+// error when nothing could be fetched at all.
+type DataProvider interface {
+	Name() string
+	Fetch(ctx context.Context, seriesIDs []string) (FetchResult, error)
+}
+
+type FetchResult struct {
+	Observations []models.DataObservation
+	Outcomes     []models.SeriesOutcome
+}
+
+// QuotaLimitedProvider is implemented by providers whose API key has a daily
+// request budget, so ingestion can defer series before the budget runs out.
+type QuotaLimitedProvider interface {
+	DataProvider
+	RemainingRequests(ctx context.Context) (int, error)
+	RequestsNeeded(seriesCount int) int
+}
+
+func DefaultProviders(db database.DBQuerier) map[string]DataProvider {
+	providers := map[string]DataProvider{
+		models.ProviderBLS: NewBLSProvider(db),
+	}
+
+	if os.Getenv("FRED_API_KEY") != "" {
+		providers[models.ProviderFRED] = NewFREDProvider()
+	}
+	if os.Getenv("EIA_API_KEY") != "" {
+		providers[models.ProviderEIA] = NewEIAProvider()
+	}
+
+	return providers
+}
+
+func activeSeriesByProvider(db database.DBQuerier) (map[string][]models.Series, error) {
+	series, err := GetActiveSeries(db)
+	if err != nil {
+		return nil, err
+	}
+
+	seriesByProvider := make(map[string][]models.Series)
+	for _, entry := range series {
+		seriesByProvider[entry.Provider] = append(seriesByProvider[entry.Provider], entry)
+	}
+	return seriesByProvider, nil
+}
+
+func seriesIDsOf(series []models.Series) []string {
+	seriesIDs := make([]string, 0, len(series))
+	for _, entry := range series {
+		seriesIDs = append(seriesIDs, entry.SeriesID)
+	}
+	return seriesIDs
+}
+
+// planQuotaDeferrals decides which series to fetch given the requests left on
+// a key. Once fetching everything would dip into the reserve, low-priority
+// series are deferred; if the remaining budget still cannot cover the rest,
+// series are kept in priority order until it runs out.
+func planQuotaDeferrals(series []models.Series, remaining, reserve int, requestsNeeded func(int) int) ([]string, []string) {
+	if remaining-requestsNeeded(len(series)) >= reserve {
+		return seriesIDsOf(series), nil
+	}
+
+	ordered := make([]models.Series, len(series))
+	copy(ordered, series)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return models.PriorityRank(ordered[i].Priority) > models.PriorityRank(ordered[j].Priority)
+	})
+
+	var fetch, deferred []string
+	for _, entry := range ordered {
+		if entry.Priority == models.PriorityLow || requestsNeeded(len(fetch)+1) > remaining {
+			deferred = append(deferred, entry.SeriesID)
+			continue
+		}
+		fetch = append(fetch, entry.SeriesID)
+	}
+	return fetch, deferred
+}
+
+func latestObservations(observations []models.DataObservation) map[string]struct {
+	Value  float64
+	Year   string
+	Period string
+} {
+	latest := make(map[string]struct {
+		Value  float64
+		Year   string
+		Period string
+	})
+
+	for _, observation := range observations {
+		current, exists := latest[observation.SeriesID]
+		if exists && !periodBefore(current.Year, current.Period, observation.Year, observation.Period) {
+			continue
+		}
+		latest[observation.SeriesID] = struct {
+			Value  float64
+			Year   string
+			Period string
+		}{
+			Value:  observation.Value,
+			Year:   observation.Year,
+			Period: observation.Period,
+		}
+	}
+
+	return latest
+}
+
+type httpStatusError struct {
+	Provider   string
+	SeriesID   string
+	StatusCode int
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("%s API request for %s failed with status %d", e.Provider, e.SeriesID, e.StatusCode)
+}
+
+// fetchEach runs fetchSeries for one series at a time, for providers whose API
+// takes a single series per request. Rejected series are recorded as invalid,
+// and a rate limit response stops the remaining requests.
+func fetchEach(providerName string, seriesIDs []string, fetchSeries func(string) ([]models.DataObservation, error)) (FetchResult, error) {
+	var result FetchResult
+	var lastErr error
+	succeeded := 0
+
+	for i, seriesID := range seriesIDs {
+		observations, err := fetchSeries(seriesID)
+		if err != nil {
+			var statusErr *httpStatusError
+			statusCode := 0
+			if errors.As(err, &statusErr) {
+				statusCode = statusErr.StatusCode
+			}
+
+			outcome := models.OutcomeError
+			switch statusCode {
+			case http.StatusBadRequest, http.StatusNotFound:
+				outcome = models.OutcomeInvalidSeries
+				succeeded++
+			case http.StatusTooManyRequests:
+				result.Outcomes = append(result.Outcomes, outcomesFor(providerName, seriesIDs[i:], models.OutcomeThrottled, err.Error())...)
+				return result, nil
+			default:
+				lastErr = err
+			}
+			result.Outcomes = append(result.Outcomes, models.SeriesOutcome{SeriesID: seriesID, Provider: providerName, Outcome: outcome, Message: err.Error()})
+			continue
+		}
+		succeeded++
+
+		outcome := models.SeriesOutcome{SeriesID: seriesID, Provider: providerName, Outcome: models.OutcomeOK, ObservationCount: len(observations)}
+		if len(observations) == 0 {
+			outcome.Outcome = models.OutcomeNoData
+		}
+		result.Outcomes = append(result.Outcomes, outcome)
+		result.Observations = append(result.Observations, observations...)
+	}
+
+	if succeeded == 0 && lastErr != nil {
+		return result, lastErr
+	}
+	return result, nil
+}
+
+func outcomesFor(providerName string, seriesIDs []string, outcome, message string) []models.SeriesOutcome {
+	outcomes := make([]models.SeriesOutcome, 0, len(seriesIDs))
+	for _, seriesID := range seriesIDs {
+		outcomes = append(outcomes, models.SeriesOutcome{
+			SeriesID: seriesID,
+			Provider: providerName,
+			Outcome:  outcome,
+			Message:  message,
+		})
+	}
+	return outcomes
+}
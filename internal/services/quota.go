@@ -0,0 +1,93 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"megga-backend/internal/database"
+	"os"
+	"strconv"
+	"time"
+)
+
+// UsageTracker persists per-key daily request counts so quota accounting
+// survives restarts. Keys are stored as a short hash, never in plain text.
+type UsageTracker struct {
+	db         database.DBQuerier
+	Provider   string
+	KeyID      string
+	DailyLimit int
+	Now        func() time.Time
+}
+
+func NewUsageTracker(db database.DBQuerier, provider, apiKey string, dailyLimit int) *UsageTracker {
+	return &UsageTracker{
+		db:         db,
+		Provider:   provider,
+		KeyID:      hashAPIKey(apiKey),
+		DailyLimit: dailyLimit,
+		Now:        time.Now,
+	}
+}
+
+func (t *UsageTracker) usageDate() string {
+	return t.Now().Format("2006-01-02")
+}
+
+func (t *UsageTracker) Used(ctx context.Context) (int, error) {
+	var used int
+	err := t.db.QueryRow(ctx, `
+		SELECT COALESCE(SUM(request_count), 0)
+		FROM api_usage
+		WHERE provider = $1 AND key_id = $2 AND usage_date = $3`,
+		t.Provider, t.KeyID, t.usageDate()).Scan(&used)
+	if err != nil {
+		return 0, fmt.Errorf("error fetching API usage: %w", err)
+	}
+	return used, nil
+}
+
+func (t *UsageTracker) Remaining(ctx context.Context) (int, error) {
+	used, err := t.Used(ctx)
+	if err != nil {
+		return 0, err
+	}
+	remaining := t.DailyLimit - used
+	if remaining < 0 {
+		remaining = 0
+	}
+	return remaining, nil
+}
+
+func (t *UsageTracker) Record(ctx context.Context, requests int) error {
+	_, err := t.db.Exec(ctx, `
+		INSERT INTO api_usage (provider, key_id, usage_date, request_count, updated_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (provider, key_id, usage_date)
+		DO UPDATE SET request_count = api_usage.request_count + EXCLUDED.request_count, updated_at = NOW()`,
+		t.Provider, t.KeyID, t.usageDate(), requests)
+	if err != nil {
+		return fmt.Errorf("error recording API usage: %w", err)
+	}
+	return nil
+}
+
+func hashAPIKey(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+func getEnvInt(key string, fallback int) int {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		log.Printf("⚠️ Invalid value for %s (%q), using default %d", key, raw, fallback)
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"megga-backend/internal/database"
+	"megga-backend/internal/models"
+
+	"github.com/jackc/pgx/v4"
+)
+
+func GetActiveSeries(db database.DBQuerier) ([]models.Series, error) {
+	rows, err := db.Query(context.Background(), `
+		SELECT series_id, name, unit, category, active, provider, priority, created_at, updated_at
+		FROM series_catalog
+		WHERE active = TRUE
+		ORDER BY series_id`)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching active series: %w", err)
+	}
+	defer rows.Close()
+
+	var series []models.Series
+	for rows.Next() {
+		var entry models.Series
+		if err := rows.Scan(&entry.SeriesID, &entry.Name, &entry.Unit, &entry.Category, &entry.Active, &entry.Provider, &entry.Priority, &entry.CreatedAt, &entry.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning series row: %w", err)
+		}
+		series = append(series, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over series rows: %w", err)
+	}
+	return series, nil
+}
+
+// GetActiveSeriesByID returns pgx.ErrNoRows when the series is missing from
+// the catalog or has been deactivated.
+func GetActiveSeriesByID(db database.DBQuerier, seriesID string) (models.Series, error) {
+	return scanActiveSeries(db.QueryRow(context.Background(), activeSeriesQuery, seriesID))
+}
+
+// getActiveSeriesInTx is GetActiveSeriesByID inside a transaction the caller
+// owns.
+func getActiveSeriesInTx(ctx context.Context, tx pgx.Tx, seriesID string) (models.Series, error) {
+	return scanActiveSeries(tx.QueryRow(ctx, activeSeriesQuery, seriesID))
+}
+
+const activeSeriesQuery = `
+		SELECT series_id, name, unit, category, active, provider, priority, created_at, updated_at
+		FROM series_catalog
+		WHERE series_id = $1 AND active = TRUE`
+
+func scanActiveSeries(row pgx.Row) (models.Series, error) {
+	var entry models.Series
+	err := row.Scan(&entry.SeriesID, &entry.Name, &entry.Unit, &entry.Category, &entry.Active, &entry.Provider, &entry.Priority, &entry.CreatedAt, &entry.UpdatedAt)
+	if err != nil {
+		return models.Series{}, err
+	}
+	return entry, nil
+}
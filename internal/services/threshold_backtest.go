@@ -0,0 +1,327 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"megga-backend/internal/database"
+	"megga-backend/internal/expression"
+	"megga-backend/internal/models"
+	"sort"
+	"time"
+)
+
+const backtestDateLayout = "2006-01-02"
+
+var ErrUnknownData = errors.New("unknown data ID")
+
+// BacktestThreshold replays a threshold definition over the stored
+// observations, one step per period of the threshold's own series that starts
+// between from and to. Each step is evaluated the way the monitor would have
+// seen it once that period was published, and the threshold moves through
+// its states as it would have, so hysteresis and cooldown apply. A
+// percent_since_created condition without a baseline is measured from the
+// value at the first step.
+func BacktestThreshold(db database.DBQuerier, threshold models.Threshold, from, to time.Time) (models.ThresholdBacktest, error) {
+	backtest := models.ThresholdBacktest{
+		From:    from.Format(backtestDateLayout),
+		To:      to.Format(backtestDateLayout),
+		Firings: []models.BacktestFiring{},
+	}
+
+	var rule *expression.Expression
+	refs := map[string]SeriesRef{}
+	dataIDs := []int{threshold.DataID}
+	if threshold.Expression != "" {
+		var err error
+		if rule, err = expression.Parse(threshold.Expression); err != nil {
+			return backtest, fmt.Errorf("invalid rule: %w", err)
+		}
+		if refs, err = ResolveSeriesRefs(db, rule.Series()); err != nil {
+			return backtest, err
+		}
+		for _, name := range rule.Series() {
+			if dataID := refs[name].DataID; dataID != threshold.DataID {
+				dataIDs = append(dataIDs, dataID)
+			}
+		}
+	} else {
+		for _, condition := range threshold.Conditions {
+			dataIDs = append(dataIDs, condition.DataID)
+		}
+	}
+
+	data, err := fetchBacktestData(db, dataIDs)
+	if err != nil {
+		return backtest, err
+	}
+	seen := make(map[string]bool)
+	var seriesIDs []string
+	for _, row := range data {
+		if !seen[row.SeriesID] {
+			seen[row.SeriesID] = true
+			seriesIDs = append(seriesIDs, row.SeriesID)
+		}
+	}
+	sort.Strings(seriesIDs)
+	histories, err := fetchSeriesHistories(context.Background(), db, seriesIDs)
+	if err != nil {
+		return backtest, err
+	}
+	own := histories[data[threshold.DataID].SeriesID]
+	if own == nil {
+		return backtest, nil
+	}
+
+	replay := threshold
+	replay.State = models.ThresholdStateArmed
+	replay.LastTriggeredAt = nil
+	replay.Conditions = append([]models.ThresholdCondition(nil), threshold.Conditions...)
+	started := false
+
+	for _, period := range own.periods {
+		if period.Date().Before(from) || period.Date().After(to) {
+			continue
+		}
+		published := period.End()
+		if !started {
+			started = true
+			setBacktestBaselines(&replay, data, histories, published)
+		}
+
+		var evaluation models.ThresholdEvaluation
+		var result ThresholdResult
+		if rule != nil {
+			result, err = backtestRule(replay, rule, refs, data, histories, published, &evaluation)
+		} else {
+			result, err = backtestConditions(replay, data, histories, published, &evaluation)
+		}
+		if err != nil {
+			backtest.Skipped++
+			continue
+		}
+		backtest.Evaluated++
+
+		state, notify := NextThresholdState(replay, result, published)
+		replay.State = state
+		if notify {
+			replay.LastTriggeredAt = &published
+			backtest.Firings = append(backtest.Firings, models.BacktestFiring{
+				Date:       period.Date().Format(backtestDateLayout),
+				Period:     period.Label(),
+				Comparison: evaluation.Comparison,
+				Inputs:     evaluation.Inputs,
+			})
+		}
+	}
+	return backtest, nil
+}
+
+func backtestConditions(threshold models.Threshold, data map[int]models.Data, histories map[string]*seriesHistory, published time.Time, evaluation *models.ThresholdEvaluation) (ThresholdResult, error) {
+	conditions := threshold.AllConditions()
+	inputs := make([]ThresholdInput, len(conditions))
+	latest := make([]models.Data, len(conditions))
+	for i, condition := range conditions {
+		latest[i] = data[condition.DataID]
+		input, ok := histories[latest[i].SeriesID].inputAsOf(published)
+		if !ok {
+			return ThresholdResult{}, fmt.Errorf("%w: %s has no value yet", ErrMissingComparisonValue, latest[i].Name)
+		}
+		inputs[i] = input
+	}
+	return evaluateConditionInputs(threshold, latest, inputs, evaluation)
+}
+
+func backtestRule(threshold models.Threshold, rule *expression.Expression, refs map[string]SeriesRef, data map[int]models.Data, histories map[string]*seriesHistory, published time.Time, evaluation *models.ThresholdEvaluation) (ThresholdResult, error) {
+	evaluation.Comparison = rule.String()
+	holds, err := traceRule(rule, &historySource{histories: histories, refs: refs, published: published}, refs, evaluation)
+	if err != nil {
+		return ThresholdResult{}, err
+	}
+
+	headline := ruleHeadline(threshold)
+	headline.Name = data[threshold.DataID].Name
+	input, ok := histories[data[threshold.DataID].SeriesID].inputAsOf(published)
+	if !ok {
+		return ThresholdResult{}, fmt.Errorf("%w: %s has no value yet", ErrMissingComparisonValue, headline.Name)
+	}
+	return ruleResult(headline, input, holds), nil
+}
+
+// setBacktestBaselines gives percent_since_created conditions without a
+// baseline the value their series had at the first step.
+func setBacktestBaselines(threshold *models.Threshold, data map[int]models.Data, histories map[string]*seriesHistory, published time.Time) {
+	baseline := func(dataID int) *float64 {
+		input, ok := histories[data[dataID].SeriesID].inputAsOf(published)
+		if !ok {
+			return nil
+		}
+		return &input.Latest
+	}
+	if threshold.Mode == models.ThresholdModePercentSinceCreated && threshold.BaselineValue == nil {
+		threshold.BaselineValue = baseline(threshold.DataID)
+	}
+	for i := range threshold.Conditions {
+		condition := &threshold.Conditions[i]
+		if condition.Mode == models.ThresholdModePercentSinceCreated && condition.BaselineValue == nil {
+			condition.BaselineValue = baseline(condition.DataID)
+		}
+	}
+}
+
+func fetchBacktestData(db database.DBQuerier, dataIDs []int) (map[int]models.Data, error) {
+	rows, err := db.Query(context.Background(),
+		"SELECT data_id, series_id, name FROM data WHERE data_id = ANY($1)", dataIDs)
+	if err != nil {
+		return nil, fmt.Errorf("error querying data: %w", err)
+	}
+	defer rows.Close()
+
+	data := make(map[int]models.Data)
+	for rows.Next() {
+		var row models.Data
+		if err := rows.Scan(&row.DataID, &row.SeriesID, &row.Name); err != nil {
+			return nil, fmt.Errorf("error scanning data: %w", err)
+		}
+		data[row.DataID] = row
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading data: %w", err)
+	}
+
+	for _, dataID := range dataIDs {
+		if _, ok := data[dataID]; !ok {
+			return nil, fmt.Errorf("%w: %d", ErrUnknownData, dataID)
+		}
+	}
+	return data, nil
+}
+
+// seriesHistory is a series' stored observations in period order, without
+// annual averages.
+type seriesHistory struct {
+	periods []models.Period
+	values  map[models.Period]float64
+}
+
+func fetchSeriesHistories(ctx context.Context, db database.DBQuerier, seriesIDs []string) (map[string]*seriesHistory, error) {
+	histories := make(map[string]*seriesHistory)
+	if len(seriesIDs) == 0 {
+		return histories, nil
+	}
+
+	rows, err := db.Query(ctx, `
+		SELECT series_id, year, period, value
+		FROM data_observations
+		WHERE series_id = ANY($1)`, seriesIDs)
+	if err != nil {
+		return nil, fmt.Errorf("error querying observations: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var seriesID, year, code string
+		var value float64
+		if err := rows.Scan(&seriesID, &year, &code, &value); err != nil {
+			return nil, fmt.Errorf("error scanning observation: %w", err)
+		}
+		period, err := models.ParsePeriod(year, code)
+		if err != nil || models.IsAnnualAverage(code) {
+			continue
+		}
+		history := histories[seriesID]
+		if history == nil {
+			history = &seriesHistory{values: make(map[models.Period]float64)}
+			histories[seriesID] = history
+		}
+		history.periods = append(history.periods, period)
+		history.values[period] = value
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading observations: %w", err)
+	}
+
+	for _, history := range histories {
+		periods := history.periods
+		sort.Slice(periods, func(i, j int) bool { return periods[i].Before(periods[j]) })
+	}
+	return histories, nil
+}
+
+// recent returns the last n values, newest first.
+func (h *seriesHistory) recent(n int) []float64 {
+	if h == nil {
+		return nil
+	}
+	var values []float64
+	for i := len(h.periods) - 1; i >= 0 && len(values) < n; i-- {
+		values = append(values, h.values[h.periods[i]])
+	}
+	return values
+}
+
+// latestIndex returns the index of the last period published by the given
+// time, taking a period to be published once it has ended, or -1.
+func (h *seriesHistory) latestIndex(published time.Time) int {
+	if h == nil {
+		return -1
+	}
+	return sort.Search(len(h.periods), func(i int) bool { return h.periods[i].End().After(published) }) - 1
+}
+
+// inputAsOf returns the values a condition would have been compared against
+// at the given time.
+func (h *seriesHistory) inputAsOf(published time.Time) (ThresholdInput, bool) {
+	i := h.latestIndex(published)
+	if i < 0 {
+		return ThresholdInput{}, false
+	}
+	period := h.periods[i]
+	return ThresholdInput{
+		Latest:   h.values[period],
+		Previous: h.value(period.Previous()),
+		YearAgo:  h.value(period.YearAgo()),
+	}, true
+}
+
+func (h *seriesHistory) value(period models.Period) *float64 {
+	if h == nil {
+		return nil
+	}
+	value, ok := h.values[period]
+	if !ok {
+		return nil
+	}
+	return &value
+}
+
+// historySource reads rule inputs from the observations published by a
+// point in a backtest.
+type historySource struct {
+	histories map[string]*seriesHistory
+	refs      map[string]SeriesRef
+	published time.Time
+}
+
+func (s *historySource) Recent(series string, n int) ([]float64, error) {
+	history := s.histories[s.refs[series].SeriesID]
+	var values []float64
+	for i := history.latestIndex(s.published); i >= 0 && len(values) < n; i-- {
+		values = append(values, history.values[history.periods[i]])
+	}
+	return values, nil
+}
+
+func (s *historySource) YearAgo(series string) (float64, error) {
+	history := s.histories[s.refs[series].SeriesID]
+	i := history.latestIndex(s.published)
+	if i < 0 {
+		return 0, fmt.Errorf("%w: %s has no values yet", expression.ErrNotEnoughData, series)
+	}
+	yearAgo := history.periods[i].YearAgo()
+	value := history.value(yearAgo)
+	if value == nil {
+		return 0, fmt.Errorf("%w: %s has no value for %s", expression.ErrNotEnoughData, series, yearAgo.Code())
+	}
+	return *value, nil
+}
@@ -0,0 +1,371 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"megga-backend/internal/database"
+	"megga-backend/internal/expression"
+	"megga-backend/internal/models"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// checkBatch holds what a run of checks reads about its thresholds, loaded
+// with one query per table rather than per threshold. Once loaded it is only
+// read, so the checks can share it across workers. Rules read the histories
+// of the series they name, which are loaded with the rest.
+type checkBatch struct {
+	data         map[int]models.Data // By data ID
+	activeSeries map[string]bool
+	observations map[observationKey]float64
+	seriesRefs   map[string]SeriesRef            // By the names rules use
+	histories    map[string]*seriesHistory       // By series ID, for the series rules name
+	tiers        map[int][]models.EscalationTier // By threshold ID
+	recipients   map[int][]models.Recipient      // By threshold ID
+	campaigns    map[int]models.Campaign         // By threshold ID
+	emails       map[int]string                  // By user ID
+}
+
+type observationKey struct {
+	seriesID, year, period string
+}
+
+func keyFor(seriesID string, period models.Period) observationKey {
+	return observationKey{seriesID: seriesID, year: period.YearString(), period: period.Code()}
+}
+
+// loadCheckBatch loads everything the checks of the given thresholds read,
+// which must already have their conditions attached.
+func loadCheckBatch(ctx context.Context, db database.DBQuerier, thresholds []models.Threshold) (*checkBatch, error) {
+	batch := &checkBatch{}
+	thresholdIDs := make([]int, len(thresholds))
+	var dataIDs, userIDs []int
+	seenData, seenUsers := make(map[int]bool), make(map[int]bool)
+	for i, threshold := range thresholds {
+		thresholdIDs[i] = threshold.ThresholdID
+		if !seenUsers[threshold.UserID] {
+			seenUsers[threshold.UserID] = true
+			userIDs = append(userIDs, threshold.UserID)
+		}
+		for _, condition := range conditionsRead(threshold) {
+			if !seenData[condition.DataID] {
+				seenData[condition.DataID] = true
+				dataIDs = append(dataIDs, condition.DataID)
+			}
+		}
+	}
+
+	// The series rules name are read like any other, so a rule's year-ago
+	// value is measured from its series' latest period.
+	names := ruleSeriesNames(thresholds)
+	var err error
+	if batch.seriesRefs, err = lookupSeriesRefs(ctx, db, names); err != nil {
+		return nil, err
+	}
+	var ruleSeriesIDs []string
+	seenSeries := make(map[string]bool)
+	for _, name := range names {
+		ref, ok := batch.seriesRefs[name]
+		if !ok || seenSeries[ref.SeriesID] {
+			continue
+		}
+		seenSeries[ref.SeriesID] = true
+		ruleSeriesIDs = append(ruleSeriesIDs, ref.SeriesID)
+		if !seenData[ref.DataID] {
+			seenData[ref.DataID] = true
+			dataIDs = append(dataIDs, ref.DataID)
+		}
+	}
+
+	if batch.data, batch.activeSeries, err = fetchBatchData(ctx, db, dataIDs); err != nil {
+		return nil, fmt.Errorf("error fetching latest values: %w", err)
+	}
+	if batch.observations, err = fetchBatchObservations(ctx, db, batch.comparisonKeys(thresholds)); err != nil {
+		return nil, fmt.Errorf("error fetching comparison values: %w", err)
+	}
+	if batch.histories, err = fetchSeriesHistories(ctx, db, ruleSeriesIDs); err != nil {
+		return nil, fmt.Errorf("error fetching rule series: %w", err)
+	}
+	if batch.tiers, err = GetEscalationTiers(ctx, db, thresholdIDs); err != nil {
+		return nil, err
+	}
+	if batch.recipients, err = fetchBatchRecipients(ctx, db, thresholdIDs); err != nil {
+		return nil, fmt.Errorf("error fetching recipients: %w", err)
+	}
+	if batch.emails, err = fetchBatchEmails(ctx, db, userIDs); err != nil {
+		return nil, fmt.Errorf("error fetching owners: %w", err)
+	}
+	if batch.campaigns, err = fetchBatchCampaigns(ctx, db, thresholdIDs); err != nil {
+		return nil, fmt.Errorf("error fetching campaigns: %w", err)
+	}
+	return batch, nil
+}
+
+// conditionsRead returns the conditions whose series a threshold's check
+// reads directly: its own and any extra ones, or for a rule the headline
+// condition its letters report.
+func conditionsRead(threshold models.Threshold) []models.ThresholdCondition {
+	if threshold.Expression != "" {
+		return []models.ThresholdCondition{ruleHeadline(threshold)}
+	}
+	return threshold.AllConditions()
+}
+
+// ruleSeriesNames lists the series named by the thresholds' rules, each once.
+// Rules that do not parse are left for their checks to report.
+func ruleSeriesNames(thresholds []models.Threshold) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, threshold := range thresholds {
+		if threshold.Expression == "" {
+			continue
+		}
+		rule, err := expression.Parse(threshold.Expression)
+		if err != nil {
+			continue
+		}
+		for _, name := range rule.Series() {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// comparisonKeys lists the stored periods the thresholds' conditions compare
+// against. Periods that cannot be parsed are left for the check to report.
+func (b *checkBatch) comparisonKeys(thresholds []models.Threshold) []observationKey {
+	seen := make(map[observationKey]bool)
+	var keys []observationKey
+	for _, threshold := range thresholds {
+		for _, condition := range conditionsRead(threshold) {
+			latest, ok := b.data[condition.DataID]
+			if !ok || (condition.Mode != models.ThresholdModePercentPrevious && condition.Mode != models.ThresholdModePercentYoY) {
+				continue
+			}
+			period, err := models.ParsePeriod(latest.Year, latest.Period)
+			if err != nil {
+				continue
+			}
+			key := keyFor(latest.SeriesID, period.Previous())
+			if condition.Mode == models.ThresholdModePercentYoY {
+				key = keyFor(latest.SeriesID, period.YearAgo())
+			}
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys
+}
+
+// conditionInput returns the values one condition is compared against, along
+// with the series' latest row. The previous period falls back to the data
+// table's previous_value when the observation was never stored.
+func (b *checkBatch) conditionInput(condition models.ThresholdCondition) (ThresholdInput, models.Data, error) {
+	latest, ok := b.data[condition.DataID]
+	if !ok {
+		return ThresholdInput{}, latest, fmt.Errorf("error fetching latest value for data ID %d: %w", condition.DataID, pgx.ErrNoRows)
+	}
+	if !b.activeSeries[latest.SeriesID] {
+		return ThresholdInput{}, latest, fmt.Errorf("series %s of data ID %d is not active in the series catalog", latest.SeriesID, condition.DataID)
+	}
+
+	input := ThresholdInput{Latest: latest.LatestValue}
+	if condition.Mode != models.ThresholdModePercentPrevious && condition.Mode != models.ThresholdModePercentYoY {
+		return input, latest, nil
+	}
+	period, err := models.ParsePeriod(latest.Year, latest.Period)
+	if err != nil {
+		return ThresholdInput{}, latest, fmt.Errorf("error fetching comparison values for data ID %d: %w", condition.DataID, err)
+	}
+
+	if condition.Mode == models.ThresholdModePercentPrevious {
+		previous := latest.PreviousValue
+		if value, ok := b.observations[keyFor(latest.SeriesID, period.Previous())]; ok {
+			previous = value
+		}
+		input.Previous = &previous
+		return input, latest, nil
+	}
+	if value, ok := b.observations[keyFor(latest.SeriesID, period.YearAgo())]; ok {
+		input.YearAgo = &value
+	}
+	return input, latest, nil
+}
+
+// ruleRefs returns the series a rule names, failing as ResolveSeriesRefs
+// does when one of them is unknown or inactive.
+func (b *checkBatch) ruleRefs(names []string) (map[string]SeriesRef, error) {
+	refs := make(map[string]SeriesRef, len(names))
+	for _, name := range names {
+		if ref, ok := b.seriesRefs[name]; ok {
+			refs[name] = ref
+		}
+	}
+	return refs, missingSeries(refs, names)
+}
+
+// campaign returns the campaign a threshold sets off, or nil when the
+// threshold is a user's own.
+func (b *checkBatch) campaign(thresholdID int) *models.Campaign {
+	campaign, ok := b.campaigns[thresholdID]
+	if !ok {
+		return nil
+	}
+	return &campaign
+}
+
+func (b *checkBatch) userEmail(userID int) string {
+	email, ok := b.emails[userID]
+	if !ok {
+		log.Printf("❌ Failed to fetch user email for user ID %d: no such user", userID)
+	}
+	return email
+}
+
+// fetchBatchData loads the latest row of each data ID, and which of their
+// series are active in the catalog.
+func fetchBatchData(ctx context.Context, db database.DBQuerier, dataIDs []int) (map[int]models.Data, map[string]bool, error) {
+	data := make(map[int]models.Data)
+	active := make(map[string]bool)
+	if len(dataIDs) == 0 {
+		return data, active, nil
+	}
+
+	rows, err := db.Query(ctx, `
+		SELECT d.data_id, d.series_id, d.name, d.latest_value, d.previous_value, d.year, d.period, COALESCE(s.active, FALSE)
+		FROM data d
+		LEFT JOIN series_catalog s ON s.series_id = d.series_id
+		WHERE d.data_id = ANY($1)`, dataIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row models.Data
+		var isActive bool
+		if err := rows.Scan(&row.DataID, &row.SeriesID, &row.Name, &row.LatestValue, &row.PreviousValue, &row.Year, &row.Period, &isActive); err != nil {
+			return nil, nil, err
+		}
+		data[row.DataID] = row
+		active[row.SeriesID] = isActive
+	}
+	return data, active, rows.Err()
+}
+
+func fetchBatchObservations(ctx context.Context, db database.DBQuerier, keys []observationKey) (map[observationKey]float64, error) {
+	observations := make(map[observationKey]float64)
+	if len(keys) == 0 {
+		return observations, nil
+	}
+
+	seriesIDs := make([]string, len(keys))
+	years := make([]string, len(keys))
+	periods := make([]string, len(keys))
+	for i, key := range keys {
+		seriesIDs[i], years[i], periods[i] = key.seriesID, key.year, key.period
+	}
+
+	rows, err := db.Query(ctx, `
+		SELECT o.series_id, o.year, o.period, o.value
+		FROM data_observations o
+		JOIN UNNEST($1::TEXT[], $2::TEXT[], $3::TEXT[]) AS k(series_id, year, period)
+		  ON o.series_id = k.series_id AND o.year = k.year AND o.period = k.period`, seriesIDs, years, periods)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var key observationKey
+		var value float64
+		if err := rows.Scan(&key.seriesID, &key.year, &key.period, &value); err != nil {
+			return nil, err
+		}
+		observations[key] = value
+	}
+	return observations, rows.Err()
+}
+
+func fetchBatchRecipients(ctx context.Context, db database.DBQuerier, thresholdIDs []int) (map[int][]models.Recipient, error) {
+	recipients := make(map[int][]models.Recipient)
+	if len(thresholdIDs) == 0 {
+		return recipients, nil
+	}
+
+	rows, err := db.Query(ctx, `
+		SELECT tr.threshold_id, r.recipient_id, r.email, r.first_name, r.last_name, r.designation
+		FROM threshold_recipients tr
+		JOIN recipients r ON r.recipient_id = tr.recipient_id
+		WHERE tr.threshold_id = ANY($1)
+		ORDER BY tr.threshold_id, r.recipient_id`, thresholdIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var thresholdID int
+		var recipient models.Recipient
+		if err := rows.Scan(&thresholdID, &recipient.RecipientID, &recipient.Email, &recipient.FirstName, &recipient.LastName, &recipient.Designation); err != nil {
+			return nil, err
+		}
+		recipients[thresholdID] = append(recipients[thresholdID], recipient)
+	}
+	return recipients, rows.Err()
+}
+
+func fetchBatchEmails(ctx context.Context, db database.DBQuerier, userIDs []int) (map[int]string, error) {
+	emails := make(map[int]string)
+	if len(userIDs) == 0 {
+		return emails, nil
+	}
+
+	rows, err := db.Query(ctx, "SELECT user_id, email FROM users WHERE user_id = ANY($1)", userIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var userID int
+		var email string
+		if err := rows.Scan(&userID, &email); err != nil {
+			return nil, err
+		}
+		emails[userID] = email
+	}
+	return emails, rows.Err()
+}
+
+func fetchBatchCampaigns(ctx context.Context, db database.DBQuerier, thresholdIDs []int) (map[int]models.Campaign, error) {
+	campaigns := make(map[int]models.Campaign)
+	if len(thresholdIDs) == 0 {
+		return campaigns, nil
+	}
+
+	rows, err := db.Query(ctx, `
+		SELECT campaign_id, owner_id, threshold_id, name, description, letter_subject, letter_template, created_at
+		FROM campaigns
+		WHERE threshold_id = ANY($1)`, thresholdIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var campaign models.Campaign
+		if err := rows.Scan(&campaign.CampaignID, &campaign.OwnerID, &campaign.ThresholdID, &campaign.Name, &campaign.Description,
+			&campaign.LetterSubject, &campaign.LetterTemplate, &campaign.CreatedAt); err != nil {
+			return nil, err
+		}
+		campaigns[campaign.ThresholdID] = campaign
+	}
+	return campaigns, rows.Err()
+}
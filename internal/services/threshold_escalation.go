@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"megga-backend/internal/database"
+	"megga-backend/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// Breach is how long a threshold has held, counted in periods of its own
+// series, with the last period counted.
+type Breach struct {
+	Periods      int
+	Year, Period string
+}
+
+// NextBreach counts the periods in a row a threshold has held. A period is
+// counted once however often it is checked, and the count starts over as soon
+// as the threshold stops holding. It reports whether a new period was counted.
+func NextBreach(threshold models.Threshold, result ThresholdResult) (Breach, bool) {
+	current := Breach{Periods: threshold.BreachPeriods, Year: threshold.BreachYear, Period: threshold.BreachPeriod}
+	if !result.Triggered {
+		return Breach{}, false
+	}
+	if current.Periods > 0 && current.Year == result.Year && current.Period == result.Period {
+		return current, false
+	}
+	return Breach{Periods: current.Periods + 1, Year: result.Year, Period: result.Period}, true
+}
+
+// EscalationTierFor returns the number of the tier in effect for a breach of
+// the given length, 1 for the first, or 0 when none has been reached. The
+// tiers must be ordered by AfterPeriods.
+func EscalationTierFor(tiers []models.EscalationTier, periods int) int {
+	tier := 0
+	for i, escalation := range tiers {
+		if periods >= escalation.AfterPeriods {
+			tier = i + 1
+		}
+	}
+	return tier
+}
+
+// dispatchPlan is what a check decided after an evaluation: the threshold's
+// new state and breach, whether letters go out, and the escalation tier
+// they use.
+type dispatchPlan struct {
+	state         string
+	breach        Breach
+	breachChanged bool
+	notify        bool // Fired through the state machine
+	escalate      bool // Fired because the breach reached a new tier
+	tiers         []models.EscalationTier
+	tier          int
+}
+
+func (p dispatchPlan) fired() bool {
+	return p.notify || p.escalate
+}
+
+// planDispatch decides what a check does with an evaluation's result, given
+// the threshold's escalation tiers. A threshold that stays triggered fires
+// again on the period its breach reaches a tier.
+func planDispatch(threshold models.Threshold, tiers []models.EscalationTier, result ThresholdResult, evaluation models.ThresholdEvaluation) dispatchPlan {
+	plan := dispatchPlan{tiers: tiers}
+	plan.state, plan.notify = NextThresholdState(threshold, result, evaluation.EvaluatedAt)
+	var advanced bool
+	plan.breach, advanced = NextBreach(threshold, result)
+	plan.breachChanged = advanced || plan.breach.Periods != threshold.BreachPeriods
+
+	plan.tier = EscalationTierFor(tiers, plan.breach.Periods)
+	plan.escalate = !plan.notify && advanced && plan.tier > 0 && tiers[plan.tier-1].AfterPeriods == plan.breach.Periods
+	return plan
+}
+
+// escalationTier returns the tier the plan's letters use, or nil.
+func (p dispatchPlan) escalationTier() *models.EscalationTier {
+	if p.tier == 0 {
+		return nil
+	}
+	return &p.tiers[p.tier-1]
+}
+
+// dispatchRecipients returns a threshold's own recipients followed by those
+// added by each tier up to the one in effect, each listed once.
+func dispatchRecipients(ctx context.Context, db database.DBQuerier, recipients []models.Recipient, plan dispatchPlan) ([]models.Recipient, error) {
+	if plan.tier == 0 {
+		return recipients, nil
+	}
+
+	seen := make(map[int64]bool)
+	for _, recipient := range recipients {
+		seen[int64(recipient.RecipientID)] = true
+	}
+	var added []int64
+	for _, tier := range plan.tiers[:plan.tier] {
+		for _, recipientID := range tier.Recipients {
+			if !seen[recipientID] {
+				seen[recipientID] = true
+				added = append(added, recipientID)
+			}
+		}
+	}
+	if len(added) == 0 {
+		return recipients, nil
+	}
+
+	escalated, err := fetchRecipientsByID(ctx, db, added)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching escalation recipients: %w", err)
+	}
+	return append(recipients, escalated...), nil
+}
+
+// GetEscalationTiers returns the escalation tiers of the given thresholds,
+// keyed by threshold ID and ordered by the periods they wait for.
+func GetEscalationTiers(ctx context.Context, db database.DBQuerier, thresholdIDs []int) (map[int][]models.EscalationTier, error) {
+	tiers := make(map[int][]models.EscalationTier)
+	if len(thresholdIDs) == 0 {
+		return tiers, nil
+	}
+
+	rows, err := db.Query(ctx, `
+		SELECT escalation_id, threshold_id, after_periods, recipient_ids, letter_subject, letter_template
+		FROM threshold_escalations
+		WHERE threshold_id = ANY($1)
+		ORDER BY threshold_id, after_periods`, thresholdIDs)
+	if err != nil {
+		return nil, fmt.Errorf("error querying escalation tiers: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tier models.EscalationTier
+		if err := rows.Scan(&tier.EscalationID, &tier.ThresholdID, &tier.AfterPeriods, pq.Array(&tier.Recipients),
+			&tier.LetterSubject, &tier.LetterTemplate); err != nil {
+			return nil, fmt.Errorf("error scanning escalation tier: %w", err)
+		}
+		tiers[tier.ThresholdID] = append(tiers[tier.ThresholdID], tier)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading escalation tiers: %w", err)
+	}
+	return tiers, nil
+}
+
+func fetchRecipientsByID(ctx context.Context, db database.DBQuerier, recipientIDs []int64) ([]models.Recipient, error) {
+	rows, err := db.Query(ctx, `
+		SELECT recipient_id, email, first_name, last_name, designation
+		FROM recipients
+		WHERE recipient_id = ANY($1)
+		ORDER BY recipient_id`, recipientIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var recipients []models.Recipient
+	for rows.Next() {
+		var recipient models.Recipient
+		if err := rows.Scan(&recipient.RecipientID, &recipient.Email, &recipient.FirstName, &recipient.LastName, &recipient.Designation); err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, recipient)
+	}
+	return recipients, rows.Err()
+}
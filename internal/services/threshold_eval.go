@@ -0,0 +1,223 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"megga-backend/internal/models"
+	"megga-backend/internal/utils"
+	"time"
+)
+
+// ThresholdInput holds the values a threshold is compared against. Previous
+// and YearAgo are the series values for the period before the latest one and
+// for the same period a year earlier; they are nil when not stored.
+type ThresholdInput struct {
+	Latest   float64
+	Previous *float64
+	YearAgo  *float64
+}
+
+// ThresholdResult is the outcome of an evaluation. Observed is the latest
+// value for absolute thresholds and the percent change for the others; for
+// compound thresholds it belongs to the first condition, and Conditions holds
+// the outcome of each one. Year and Period are the latest period of the
+// threshold's own series, which breaches are counted in.
+type ThresholdResult struct {
+	Observed     float64
+	Triggered    bool
+	Conditions   []ConditionResult
+	Year, Period string
+}
+
+// ConditionResult is the outcome of one condition of a threshold. Latest is
+// the series value the condition read.
+type ConditionResult struct {
+	Condition models.ThresholdCondition
+	Latest    float64
+	Observed  float64
+	Triggered bool
+}
+
+var ErrMissingComparisonValue = errors.New("no value to compare against")
+
+// EvaluateThreshold evaluates the threshold's own condition.
+func EvaluateThreshold(threshold models.Threshold, input ThresholdInput) (ThresholdResult, error) {
+	return EvaluateCondition(threshold.PrimaryCondition(), input)
+}
+
+// EvaluateCondition applies a condition's mode and direction to the input.
+// Absolute conditions trigger once the latest value is at or above the level,
+// or at or below it for falling ones; percent conditions trigger when the
+// change reaches the percentage in the chosen direction.
+func EvaluateCondition(condition models.ThresholdCondition, input ThresholdInput) (ThresholdResult, error) {
+	if condition.Mode == models.ThresholdModeAbsolute {
+		triggered := input.Latest >= condition.Value
+		if condition.Direction == models.ThresholdDirectionFall {
+			triggered = input.Latest <= condition.Value
+		}
+		return ThresholdResult{Observed: input.Latest, Triggered: triggered}, nil
+	}
+
+	base, err := comparisonBase(condition, input)
+	if err != nil {
+		return ThresholdResult{}, err
+	}
+	if *base == 0 {
+		return ThresholdResult{}, fmt.Errorf("%w: base value is zero", ErrMissingComparisonValue)
+	}
+
+	percentChange := utils.CalculatePercentChange(*base, input.Latest)
+	rose := percentChange >= condition.Value
+	fell := percentChange <= -condition.Value
+
+	var triggered bool
+	switch condition.Direction {
+	case models.ThresholdDirectionRise:
+		triggered = rose
+	case models.ThresholdDirectionFall:
+		triggered = fell
+	default:
+		triggered = rose || fell
+	}
+	return ThresholdResult{Observed: percentChange, Triggered: triggered}, nil
+}
+
+// EvaluateCompound evaluates every condition of a threshold, taking one input
+// per condition in AllConditions order, and joins the outcomes with the
+// threshold's operator. A condition that cannot be evaluated fails the whole
+// threshold.
+func EvaluateCompound(threshold models.Threshold, inputs []ThresholdInput) (ThresholdResult, error) {
+	conditions := threshold.AllConditions()
+	if len(inputs) != len(conditions) {
+		return ThresholdResult{}, fmt.Errorf("got %d inputs for %d conditions", len(inputs), len(conditions))
+	}
+
+	anyOf := threshold.Operator == models.ThresholdOperatorOr
+	result := ThresholdResult{Triggered: !anyOf}
+	for i, condition := range conditions {
+		conditionResult, err := EvaluateCondition(condition, inputs[i])
+		if err != nil {
+			return ThresholdResult{}, fmt.Errorf("condition on data ID %d: %w", condition.DataID, err)
+		}
+		if i == 0 {
+			result.Observed = conditionResult.Observed
+		}
+		if anyOf {
+			result.Triggered = result.Triggered || conditionResult.Triggered
+		} else {
+			result.Triggered = result.Triggered && conditionResult.Triggered
+		}
+		result.Conditions = append(result.Conditions, ConditionResult{
+			Condition: condition,
+			Latest:    inputs[i].Latest,
+			Observed:  conditionResult.Observed,
+			Triggered: conditionResult.Triggered,
+		})
+	}
+	return result, nil
+}
+
+// isRise reports whether a met condition was set off by a rising value,
+// which decides between the "increased" and "decreased" letters.
+func isRise(condition models.ThresholdCondition, observed float64) bool {
+	if condition.Mode == models.ThresholdModeAbsolute {
+		return condition.Direction != models.ThresholdDirectionFall
+	}
+	return observed > 0
+}
+
+func comparisonBase(condition models.ThresholdCondition, input ThresholdInput) (*float64, error) {
+	var base *float64
+	switch condition.Mode {
+	case models.ThresholdModePercentPrevious:
+		base = input.Previous
+	case models.ThresholdModePercentSinceCreated:
+		base = condition.BaselineValue
+	case models.ThresholdModePercentYoY:
+		base = input.YearAgo
+	default:
+		return nil, fmt.Errorf("unknown threshold mode %q", condition.Mode)
+	}
+
+	if base == nil {
+		return nil, fmt.Errorf("%w for %s threshold", ErrMissingComparisonValue, condition.Mode)
+	}
+	return base, nil
+}
+
+// NextThresholdState moves a threshold through armed, triggered and cooling
+// down after an evaluation, and reports whether notifications should be sent.
+// A threshold fires only on the evaluation that finds it armed, or once its
+// cooldown has run out while the condition still holds.
+func NextThresholdState(threshold models.Threshold, result ThresholdResult, now time.Time) (string, bool) {
+	if threshold.State == models.ThresholdStateTriggered || threshold.State == models.ThresholdStateCoolingDown {
+		rearmed := cooldownElapsed(threshold, now) || clearedHysteresis(threshold, result)
+		switch {
+		case rearmed && result.Triggered:
+			return models.ThresholdStateTriggered, true
+		case rearmed:
+			return models.ThresholdStateArmed, false
+		case result.Triggered:
+			return models.ThresholdStateTriggered, false
+		default:
+			return models.ThresholdStateCoolingDown, false
+		}
+	}
+
+	if result.Triggered {
+		return models.ThresholdStateTriggered, true
+	}
+	return models.ThresholdStateArmed, false
+}
+
+func cooldownElapsed(threshold models.Threshold, now time.Time) bool {
+	if threshold.CooldownHours <= 0 || threshold.LastTriggeredAt == nil {
+		return false
+	}
+	return !now.Before(threshold.LastTriggeredAt.Add(time.Duration(threshold.CooldownHours) * time.Hour))
+}
+
+// clearedHysteresis reports whether the result has moved back inside the
+// threshold's limits by more than its hysteresis. With no hysteresis this is
+// simply the threshold no longer holding. An AND threshold clears as soon as
+// one condition does; an OR threshold only once all of them have. Rules have
+// no band and clear as soon as they stop holding.
+func clearedHysteresis(threshold models.Threshold, result ThresholdResult) bool {
+	if threshold.Expression != "" {
+		return !result.Triggered
+	}
+	if len(result.Conditions) == 0 {
+		return conditionCleared(threshold.PrimaryCondition(), threshold.Hysteresis, result.Observed)
+	}
+
+	anyOf := threshold.Operator == models.ThresholdOperatorOr
+	for _, conditionResult := range result.Conditions {
+		cleared := conditionCleared(conditionResult.Condition, threshold.Hysteresis, conditionResult.Observed)
+		if cleared && !anyOf {
+			return true
+		}
+		if !cleared && anyOf {
+			return false
+		}
+	}
+	return anyOf
+}
+
+func conditionCleared(condition models.ThresholdCondition, hysteresis, observed float64) bool {
+	if condition.Mode == models.ThresholdModeAbsolute {
+		if condition.Direction == models.ThresholdDirectionFall {
+			return observed > condition.Value+hysteresis
+		}
+		return observed < condition.Value-hysteresis
+	}
+
+	limit := condition.Value - hysteresis
+	switch condition.Direction {
+	case models.ThresholdDirectionRise:
+		return observed < limit
+	case models.ThresholdDirectionFall:
+		return observed > -limit
+	default:
+		return observed < limit && observed > -limit
+	}
+}
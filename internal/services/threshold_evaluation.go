@@ -0,0 +1,158 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"megga-backend/internal/database"
+	"megga-backend/internal/models"
+	"strings"
+)
+
+// evaluationInput records the values one condition was compared against.
+func evaluationInput(latest models.Data, input ThresholdInput, result ConditionResult) models.EvaluationInput {
+	met := result.Triggered
+	evaluationInput := models.EvaluationInput{
+		Name:       latest.Name,
+		SeriesID:   latest.SeriesID,
+		Latest:     &input.Latest,
+		Previous:   input.Previous,
+		YearAgo:    input.YearAgo,
+		Observed:   result.Observed,
+		Comparison: describeComparison(result.Condition, result.Observed),
+		Met:        &met,
+	}
+	if result.Condition.Mode == models.ThresholdModePercentSinceCreated {
+		evaluationInput.Baseline = result.Condition.BaselineValue
+	}
+	return evaluationInput
+}
+
+// describeComparison writes out the test a condition applied, such as
+// "4.40 >= 4.25" or "|-12.00%| >= 10.00%".
+func describeComparison(condition models.ThresholdCondition, observed float64) string {
+	if condition.Mode == models.ThresholdModeAbsolute {
+		if condition.Direction == models.ThresholdDirectionFall {
+			return fmt.Sprintf("%.2f <= %.2f", observed, condition.Value)
+		}
+		return fmt.Sprintf("%.2f >= %.2f", observed, condition.Value)
+	}
+
+	switch condition.Direction {
+	case models.ThresholdDirectionRise:
+		return fmt.Sprintf("%.2f%% >= %.2f%%", observed, condition.Value)
+	case models.ThresholdDirectionFall:
+		return fmt.Sprintf("%.2f%% <= -%.2f%%", observed, condition.Value)
+	default:
+		return fmt.Sprintf("|%.2f%%| >= %.2f%%", observed, condition.Value)
+	}
+}
+
+// explainEvaluation puts an evaluation in words: one line per condition, or
+// the rule and the values it read, followed by what the check did with the
+// threshold's state.
+func explainEvaluation(threshold models.Threshold, evaluation models.ThresholdEvaluation, notify bool) string {
+	var lines []string
+	if threshold.Expression != "" {
+		verb := "does not hold"
+		if evaluation.Triggered {
+			verb = "holds"
+		}
+		var values []string
+		for _, input := range evaluation.Inputs {
+			values = append(values, fmt.Sprintf("%s = %.2f", input.Name, input.Observed))
+		}
+		lines = append(lines, fmt.Sprintf("The rule %q %s, with %s.", threshold.Expression, verb, strings.Join(values, ", ")))
+	} else {
+		for _, input := range evaluation.Inputs {
+			outcome := "not met"
+			if input.Met != nil && *input.Met {
+				outcome = "met"
+			}
+			lines = append(lines, fmt.Sprintf("%s: %s, %s.", input.Name, input.Comparison, outcome))
+		}
+		if len(evaluation.Inputs) > 1 {
+			if threshold.Operator == models.ThresholdOperatorOr {
+				lines = append(lines, "Any one of the conditions has to be met.")
+			} else {
+				lines = append(lines, "All of the conditions have to be met.")
+			}
+		}
+	}
+	lines = append(lines, explainOutcome(evaluation, notify))
+	if notify && evaluation.Tier > 0 && !evaluation.Escalated {
+		lines = append(lines, fmt.Sprintf("It has held for %d periods in a row, so its letters go to escalation tier %d.", evaluation.BreachPeriods, evaluation.Tier))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func explainOutcome(evaluation models.ThresholdEvaluation, notify bool) string {
+	wasArmed := evaluation.PreviousState == models.ThresholdStateArmed
+	switch {
+	case evaluation.Escalated:
+		return fmt.Sprintf("The threshold has held for %d periods in a row, which reaches escalation tier %d, so it fired again.", evaluation.BreachPeriods, evaluation.Tier)
+	case notify && wasArmed:
+		return "The threshold was armed and is now met, so it fired."
+	case notify:
+		return "The threshold re-armed and is still met, so it fired again."
+	case evaluation.Triggered:
+		return "The threshold is still met but already fired, so it did not fire again. It fires again once it re-arms."
+	case evaluation.State == models.ThresholdStateCoolingDown:
+		return "The threshold is no longer met but has not cleared its hysteresis, so it has not re-armed yet."
+	case !wasArmed:
+		return "The threshold is no longer met and has re-armed."
+	default:
+		return "The threshold is not met, so it did not fire."
+	}
+}
+
+func saveThresholdEvaluation(ctx context.Context, db database.DBQuerier, evaluation models.ThresholdEvaluation) error {
+	inputs, err := json.Marshal(evaluation.Inputs)
+	if err != nil {
+		return fmt.Errorf("error encoding evaluation inputs: %w", err)
+	}
+	_, err = db.Exec(ctx, `
+		INSERT INTO threshold_evaluations
+			(threshold_id, evaluated_at, inputs, metric, comparison, triggered, previous_state, state, notified, explanation, error,
+			 breach_periods, tier, escalated)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`,
+		evaluation.ThresholdID, evaluation.EvaluatedAt, string(inputs), evaluation.Metric, evaluation.Comparison, evaluation.Triggered,
+		evaluation.PreviousState, evaluation.State, evaluation.Notified, evaluation.Explanation, evaluation.Error,
+		evaluation.BreachPeriods, evaluation.Tier, evaluation.Escalated)
+	return err
+}
+
+// GetThresholdEvaluations returns a threshold's most recent evaluations,
+// newest first.
+func GetThresholdEvaluations(ctx context.Context, db database.DBQuerier, thresholdID, limit int) ([]models.ThresholdEvaluation, error) {
+	rows, err := db.Query(ctx, `
+		SELECT evaluation_id, threshold_id, evaluated_at, inputs, metric, comparison, triggered, previous_state, state, notified, explanation, error,
+		       breach_periods, tier, escalated
+		FROM threshold_evaluations
+		WHERE threshold_id = $1
+		ORDER BY evaluated_at DESC, evaluation_id DESC
+		LIMIT $2`, thresholdID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error querying threshold evaluations: %w", err)
+	}
+	defer rows.Close()
+
+	evaluations := []models.ThresholdEvaluation{}
+	for rows.Next() {
+		var evaluation models.ThresholdEvaluation
+		var inputs []byte
+		if err := rows.Scan(&evaluation.EvaluationID, &evaluation.ThresholdID, &evaluation.EvaluatedAt, &inputs, &evaluation.Metric,
+			&evaluation.Comparison, &evaluation.Triggered, &evaluation.PreviousState, &evaluation.State, &evaluation.Notified,
+			&evaluation.Explanation, &evaluation.Error, &evaluation.BreachPeriods, &evaluation.Tier, &evaluation.Escalated); err != nil {
+			return nil, fmt.Errorf("error scanning threshold evaluation: %w", err)
+		}
+		if err := json.Unmarshal(inputs, &evaluation.Inputs); err != nil {
+			return nil, fmt.Errorf("error decoding inputs of evaluation %d: %w", evaluation.EvaluationID, err)
+		}
+		evaluations = append(evaluations, evaluation)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading threshold evaluations: %w", err)
+	}
+	return evaluations, nil
+}
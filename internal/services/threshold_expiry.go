@@ -0,0 +1,164 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"megga-backend/internal/database"
+	"megga-backend/internal/models"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+const ExpireThresholdsJobName = "expire_thresholds"
+
+type expiredThreshold struct {
+	ThresholdID  int
+	UserID       int
+	Email        string
+	FirstName    string
+	DataName     string
+	ExpiresAt    time.Time
+	CampaignID   *int // Campaign built on the threshold, if any
+	CampaignName string
+}
+
+// RunExpireThresholdsJob is the body of the expire_thresholds job.
+func RunExpireThresholdsJob(ctx context.Context, db database.DBQuerier) error {
+	removed, err := ExpireThresholds(db)
+	if err != nil {
+		return err
+	}
+	log.Printf("✅ Removed %d expired thresholds.", removed)
+	return nil
+}
+
+// ExpireThresholds deletes the thresholds whose expiry has passed. The monitor
+// already skips them, so this only tidies up. The owner's notice, and for a
+// campaign's threshold a notice to each member that the campaign ended, are
+// queued in the outbox in the transaction that deletes the threshold.
+func ExpireThresholds(db database.DBQuerier) (int, error) {
+	thresholds, err := fetchExpiredThresholds(db)
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, threshold := range thresholds {
+		emails, err := renderExpiryNotices(db, threshold)
+		if err != nil {
+			// Keep the threshold so its notices are tried again next run.
+			log.Printf("❌ Error preparing expiry notices for threshold %d: %v", threshold.ThresholdID, err)
+			continue
+		}
+
+		deleted, err := deleteExpiredThreshold(db, threshold.ThresholdID, emails)
+		if err != nil {
+			return removed, err
+		}
+		if deleted {
+			removed++
+		}
+	}
+
+	return removed, nil
+}
+
+// renderExpiryNotices renders the owner's notice and, if a campaign was built
+// on the threshold, a notice to every other member that the campaign ended.
+func renderExpiryNotices(db database.DBQuerier, threshold expiredThreshold) ([]models.RenderedEmail, error) {
+	expiryDate := threshold.ExpiresAt.Format("January 2, 2006")
+	message, err := formatEmailFromTemplate("threshold_expired.txt", map[string]string{
+		"User First Name": threshold.FirstName,
+		"Data Name":       threshold.DataName,
+		"Expiry Date":     expiryDate,
+	})
+	if err != nil {
+		return nil, err
+	}
+	emails := []models.RenderedEmail{{Kind: models.EmailKindUser, To: threshold.Email, Subject: "Your MEGGA Threshold Has Expired", Body: message}}
+
+	if threshold.CampaignID == nil {
+		return emails, nil
+	}
+
+	members, err := fetchCampaignMembers(context.Background(), db, *threshold.CampaignID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching members of campaign %d: %w", *threshold.CampaignID, err)
+	}
+	for _, member := range members {
+		if member.UserID == threshold.UserID {
+			continue
+		}
+		message, err := formatEmailFromTemplate("campaign_ended.txt", map[string]string{
+			"User First Name": member.FirstName,
+			"Campaign Name":   threshold.CampaignName,
+			"Data Name":       threshold.DataName,
+			"Expiry Date":     expiryDate,
+		})
+		if err != nil {
+			return nil, err
+		}
+		emails = append(emails, models.RenderedEmail{Kind: models.EmailKindUser, To: member.Email, Subject: "A MEGGA Campaign You Joined Has Ended", Body: message})
+	}
+	return emails, nil
+}
+
+// deleteExpiredThreshold queues the notices and deletes the threshold, and
+// with it any campaign built on it, in one transaction. It reports false,
+// queueing nothing, if the threshold was already gone.
+func deleteExpiredThreshold(db database.DBQuerier, thresholdID int, emails []models.RenderedEmail) (bool, error) {
+	ctx := context.Background()
+	tx, err := db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return false, fmt.Errorf("error starting expiry of threshold %d: %w", thresholdID, err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, "DELETE FROM thresholds WHERE threshold_id = $1", thresholdID)
+	if err != nil {
+		return false, fmt.Errorf("error deleting expired threshold %d: %w", thresholdID, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	// The threshold and campaign are gone once this commits, so the letters
+	// are queued without them.
+	if err := queueEmails(tx, nil, nil, emails); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("error committing expiry of threshold %d: %w", thresholdID, err)
+	}
+	return true, nil
+}
+
+func fetchExpiredThresholds(db database.DBQuerier) ([]expiredThreshold, error) {
+	rows, err := db.Query(context.Background(), `
+		SELECT t.threshold_id, t.user_id, u.email, COALESCE(u.first_name, ''), d.name, t.expires_at,
+		       c.campaign_id, COALESCE(c.name, '')
+		FROM thresholds t
+		JOIN users u ON t.user_id = u.user_id
+		JOIN data d ON t.data_id = d.data_id
+		LEFT JOIN campaigns c ON c.threshold_id = t.threshold_id
+		WHERE t.expires_at <= NOW()
+		ORDER BY t.expires_at, t.threshold_id`)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching expired thresholds: %w", err)
+	}
+	defer rows.Close()
+
+	var thresholds []expiredThreshold
+	for rows.Next() {
+		var threshold expiredThreshold
+		if err := rows.Scan(&threshold.ThresholdID, &threshold.UserID, &threshold.Email, &threshold.FirstName, &threshold.DataName, &threshold.ExpiresAt,
+			&threshold.CampaignID, &threshold.CampaignName); err != nil {
+			return nil, fmt.Errorf("error scanning expired threshold: %w", err)
+		}
+		thresholds = append(thresholds, threshold)
+	}
+	return thresholds, rows.Err()
+}
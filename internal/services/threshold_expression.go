@@ -0,0 +1,180 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"megga-backend/internal/database"
+	"megga-backend/internal/expression"
+	"megga-backend/internal/models"
+	"megga-backend/internal/utils"
+	"strings"
+
+	"github.com/jackc/pgx/v4"
+)
+
+var ErrUnknownSeries = errors.New("unknown or inactive series")
+
+// SeriesRef is a series named in a threshold rule.
+type SeriesRef struct {
+	SeriesID string
+	DataID   int
+}
+
+// ResolveSeriesRefs looks up the series a rule names, by catalog alias or by
+// series ID. Every name must be an active catalog series with a data row.
+func ResolveSeriesRefs(db database.DBQuerier, names []string) (map[string]SeriesRef, error) {
+	refs, err := lookupSeriesRefs(context.Background(), db, names)
+	if err != nil {
+		return nil, err
+	}
+	if err := missingSeries(refs, names); err != nil {
+		return nil, err
+	}
+	return refs, nil
+}
+
+// lookupSeriesRefs looks up whichever of the names are active catalog series,
+// by both alias and series ID.
+func lookupSeriesRefs(ctx context.Context, db database.DBQuerier, names []string) (map[string]SeriesRef, error) {
+	refs := make(map[string]SeriesRef)
+	if len(names) == 0 {
+		return refs, nil
+	}
+
+	rows, err := db.Query(ctx, `
+		SELECT s.series_id, COALESCE(s.alias, ''), d.data_id
+		FROM series_catalog s
+		JOIN data d ON d.series_id = s.series_id
+		WHERE s.active = TRUE AND (s.alias = ANY($1) OR s.series_id = ANY($1))`, names)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving series: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var seriesID, alias string
+		var dataID int
+		if err := rows.Scan(&seriesID, &alias, &dataID); err != nil {
+			return nil, fmt.Errorf("error scanning series: %w", err)
+		}
+		refs[seriesID] = SeriesRef{SeriesID: seriesID, DataID: dataID}
+		if alias != "" {
+			refs[alias] = SeriesRef{SeriesID: seriesID, DataID: dataID}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading series: %w", err)
+	}
+	return refs, nil
+}
+
+// missingSeries wraps ErrUnknownSeries with the names that have no ref.
+func missingSeries(refs map[string]SeriesRef, names []string) error {
+	var missing []string
+	for _, name := range names {
+		if _, ok := refs[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s", ErrUnknownSeries, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// expressionSource reads rule inputs from the series histories loaded with a
+// check batch.
+type expressionSource struct {
+	batch *checkBatch
+	refs  map[string]SeriesRef
+}
+
+// Recent returns the series' last n values, newest first, in period order
+// rather than by their stored codes.
+func (s *expressionSource) Recent(series string, n int) ([]float64, error) {
+	return s.batch.histories[s.refs[series].SeriesID].recent(n), nil
+}
+
+// YearAgo returns the series' value a year before its latest period.
+func (s *expressionSource) YearAgo(series string) (float64, error) {
+	latest, ok := s.batch.data[s.refs[series].DataID]
+	if !ok {
+		return 0, fmt.Errorf("error fetching latest value for %s: %w", series, pgx.ErrNoRows)
+	}
+	period, err := models.ParsePeriod(latest.Year, latest.Period)
+	if err != nil {
+		return 0, err
+	}
+	value := s.batch.histories[latest.SeriesID].value(period.YearAgo())
+	if value == nil {
+		return 0, fmt.Errorf("%w: %s has no value for %s", expression.ErrNotEnoughData, series, period.YearAgo().Code())
+	}
+	return *value, nil
+}
+
+// evaluateRule evaluates a threshold's rule, recording each function call it
+// made. The result's observed value is the change in the threshold's own
+// series from the previous period, which is what the letters report.
+func evaluateRule(batch *checkBatch, threshold models.Threshold, evaluation *models.ThresholdEvaluation) (ThresholdResult, error) {
+	rule, err := expression.Parse(threshold.Expression)
+	if err != nil {
+		return ThresholdResult{}, fmt.Errorf("invalid rule: %w", err)
+	}
+	evaluation.Comparison = rule.String()
+	refs, err := batch.ruleRefs(rule.Series())
+	if err != nil {
+		return ThresholdResult{}, err
+	}
+	holds, err := traceRule(rule, &expressionSource{batch: batch, refs: refs}, refs, evaluation)
+	if err != nil {
+		return ThresholdResult{}, err
+	}
+
+	headline := ruleHeadline(threshold)
+	input, latest, err := batch.conditionInput(headline)
+	if err != nil {
+		return ThresholdResult{}, err
+	}
+	headline.Name = latest.Name
+	result := ruleResult(headline, input, holds)
+	result.Year, result.Period = latest.Year, latest.Period
+	return result, nil
+}
+
+// traceRule evaluates a rule against src, recording each function call it
+// made, including the ones made before an error.
+func traceRule(rule *expression.Expression, src expression.Source, refs map[string]SeriesRef, evaluation *models.ThresholdEvaluation) (bool, error) {
+	holds, calls, err := rule.Trace(src)
+	for _, call := range calls {
+		evaluation.Inputs = append(evaluation.Inputs, models.EvaluationInput{
+			Name:     call.Expr(),
+			SeriesID: refs[call.Series].SeriesID,
+			Observed: call.Value,
+		})
+	}
+	return holds, err
+}
+
+// ruleHeadline is the condition a rule's letters describe: the change in the
+// threshold's own series from the previous period.
+func ruleHeadline(threshold models.Threshold) models.ThresholdCondition {
+	return models.ThresholdCondition{
+		ThresholdID: threshold.ThresholdID,
+		DataID:      threshold.DataID,
+		Mode:        models.ThresholdModePercentPrevious,
+		Direction:   models.ThresholdDirectionEither,
+	}
+}
+
+func ruleResult(headline models.ThresholdCondition, input ThresholdInput, holds bool) ThresholdResult {
+	var change float64
+	if input.Previous != nil {
+		change = utils.CalculatePercentChange(*input.Previous, input.Latest)
+	}
+	return ThresholdResult{
+		Observed:   change,
+		Triggered:  holds,
+		Conditions: []ConditionResult{{Condition: headline, Latest: input.Latest, Observed: change, Triggered: holds}},
+	}
+}
@@ -2,73 +2,368 @@ package services
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"megga-backend/internal/config"
 	"megga-backend/internal/database"
 	"megga-backend/internal/models"
-	"megga-backend/internal/utils"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v4"
 )
 
 func CheckThresholdsAndNotify(db database.DBQuerier) {
-	MonitorThresholds(db)
+	MonitorThresholds(context.Background(), db)
 }
 
-func MonitorThresholds(db database.DBQuerier) {
+// MonitorThresholds evaluates every active threshold.
+func MonitorThresholds(ctx context.Context, db database.DBQuerier) (models.ThresholdCheck, error) {
 	log.Println("🔍 Running scheduled threshold checks...")
 
-	thresholds, err := fetchAllThresholds(db)
+	var check models.ThresholdCheck
+	thresholds, err := fetchAllThresholds(ctx, db)
 	if err != nil {
 		log.Printf("❌ Failed to fetch thresholds: %v", err)
-		return
+		return check, err
+	}
+
+	err = checkThresholds(ctx, db, thresholds, &check)
+	log.Printf("✅ Checked %d thresholds, %d triggered.", check.Checked, check.Triggered)
+	return check, err
+}
+
+// CheckThresholdsForSeries evaluates only the thresholds on the given series,
+// which after an ingestion are the ones whose stored value changed. Rule
+// thresholds are always included, since their series are only known once the
+// rule is parsed.
+func CheckThresholdsForSeries(ctx context.Context, db database.DBQuerier, seriesIDs []string) (models.ThresholdCheck, error) {
+	check := models.ThresholdCheck{SeriesIDs: seriesIDs}
+	if len(seriesIDs) == 0 {
+		return check, nil
+	}
+
+	log.Printf("🔍 Checking thresholds for %d changed series...", len(seriesIDs))
+
+	thresholds, err := fetchThresholdsForSeries(ctx, db, seriesIDs)
+	if err != nil {
+		return check, fmt.Errorf("error fetching thresholds: %w", err)
+	}
+
+	err = checkThresholds(ctx, db, thresholds, &check)
+	log.Printf("✅ Checked %d thresholds, %d triggered.", check.Checked, check.Triggered)
+	return check, err
+}
+
+// checkThresholds loads what the thresholds' checks read and runs them in a
+// pool of THRESHOLD_WORKERS workers, adding to check's counts. Once ctx is
+// cancelled no further checks start, checks under way commit nothing, and its
+// error is returned.
+func checkThresholds(ctx context.Context, db database.DBQuerier, thresholds []models.Threshold, check *models.ThresholdCheck) error {
+	if len(thresholds) == 0 {
+		return nil
+	}
+	batch, err := loadCheckBatch(ctx, db, thresholds)
+	if err != nil {
+		return err
+	}
+
+	workers := config.ThresholdWorkers()
+	if workers > len(thresholds) {
+		workers = len(thresholds)
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	queue := make(chan models.Threshold)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for threshold := range queue {
+				checked, triggered := checkThreshold(ctx, db, batch, threshold)
+				mu.Lock()
+				if checked {
+					check.Checked++
+				}
+				if triggered {
+					check.Triggered++
+				}
+				mu.Unlock()
+			}
+		}()
 	}
 
 	for _, threshold := range thresholds {
-		log.Printf("🔍 Fetching latest value for Data ID: %d", threshold.DataID)
-		latestValue, err := fetchLatestDataValue(db, threshold.DataID)
-		if err != nil {
-			log.Printf("❌ Error fetching latest value for Data ID %d: %v", threshold.DataID, err)
-			continue
+		if ctx.Err() != nil {
+			break
 		}
+		select {
+		case queue <- threshold:
+		case <-ctx.Done():
+		}
+	}
+	close(queue)
+	wg.Wait()
+	return ctx.Err()
+}
 
-		seriesID, err := fetchSeriesIDForData(db, threshold.DataID)
-		if err != nil {
-			log.Printf("❌ Error fetching series ID for Data ID %d: %v", threshold.DataID, err)
-			continue
+// checkThreshold evaluates a threshold's conditions against their series and
+// sends notifications when the threshold is met. Every check is recorded in
+// threshold_evaluations, including the ones that could not be evaluated, which
+// are reported as not checked. Its queries use ctx, so a cancelled run stops
+// issuing them.
+func checkThreshold(ctx context.Context, db database.DBQuerier, batch *checkBatch, threshold models.Threshold) (checked, triggered bool) {
+	evaluation := models.ThresholdEvaluation{
+		ThresholdID:   threshold.ThresholdID,
+		EvaluatedAt:   time.Now().UTC(),
+		Inputs:        []models.EvaluationInput{},
+		PreviousState: threshold.State,
+		State:         threshold.State,
+	}
+	checked, triggered = runThresholdCheck(ctx, db, batch, threshold, &evaluation)
+	if err := saveThresholdEvaluation(ctx, db, evaluation); err != nil {
+		log.Printf("❌ Error recording evaluation of Threshold ID %d: %v", threshold.ThresholdID, err)
+	}
+	return checked, triggered
+}
+
+func runThresholdCheck(ctx context.Context, db database.DBQuerier, batch *checkBatch, threshold models.Threshold, evaluation *models.ThresholdEvaluation) (checked, triggered bool) {
+	result, err := evaluateThreshold(batch, threshold, evaluation)
+	if err != nil {
+		log.Printf("⚠️ Skipping Threshold ID %d: %v", threshold.ThresholdID, err)
+		evaluation.Error = err.Error()
+		evaluation.Explanation = "The threshold could not be evaluated, so its state was left unchanged."
+		return false, false
+	}
+	evaluation.Triggered = result.Triggered
+
+	plan := planDispatch(threshold, batch.tiers[threshold.ThresholdID], result, *evaluation)
+	evaluation.BreachPeriods = plan.breach.Periods
+	var letters thresholdLetters
+	if plan.fired() {
+		fired := *evaluation
+		fired.State, fired.Escalated, fired.Tier = plan.state, plan.escalate, plan.tier
+		letters, err = prepareLetters(ctx, db, batch, threshold, result, plan, explainEvaluation(threshold, fired, true))
+		if errors.Is(err, errNoCampaignMembers) {
+			log.Printf("⏭️ Threshold ID %d is met, but its campaign has no members yet", threshold.ThresholdID)
+			evaluation.Explanation = "The campaign has no members yet, so nothing was sent and the state was left unchanged."
+			return true, false
+		} else if err != nil {
+			log.Printf("❌ Error preparing letters for Threshold ID %d: %v", threshold.ThresholdID, err)
+			evaluation.Error = err.Error()
+			evaluation.Explanation = "The letters could not be prepared, so the threshold did not fire and will be checked again."
+			return true, false
 		}
+	}
 
-		if _, exists := config.BLS_SERIES_INFO[seriesID]; !exists {
-			log.Printf("⚠️ Skipping Data ID %d as its series ID is not in BLS_SERIES_INFO", threshold.DataID)
-			continue
+	// The letters are queued in the transaction that stores the new state, so
+	// a firing is never saved without them and a restart cannot repeat it.
+	if plan.state != threshold.State || plan.fired() || plan.breachChanged {
+		err := commitThresholdCheck(ctx, db, threshold, plan, evaluation.EvaluatedAt, letters)
+		if errors.Is(err, errThresholdChanged) {
+			log.Printf("⏭️ Threshold ID %d changed while it was checked, leaving it to the check that changed it", threshold.ThresholdID)
+			evaluation.Explanation = "Another check saved a new state for the threshold first, so this one saved and sent nothing."
+			return true, false
+		} else if err != nil {
+			log.Printf("❌ Error saving state for Threshold ID %d: %v", threshold.ThresholdID, err)
+			evaluation.Error = fmt.Sprintf("error saving state: %v", err)
+			evaluation.Explanation = "The new state could not be saved, so the threshold did not fire."
+			return true, false
 		}
+		if config.IsDevelopmentMode() && plan.state != threshold.State {
+			log.Printf("🔁 Threshold ID %d moved from %s to %s", threshold.ThresholdID, threshold.State, plan.state)
+		}
+	}
+	evaluation.State = plan.state
+	evaluation.Escalated = plan.escalate
+	if plan.fired() {
+		evaluation.Tier = plan.tier
+	}
+	evaluation.Explanation = explainEvaluation(threshold, *evaluation, plan.fired())
+	if !plan.fired() {
+		return true, false
+	}
 
-		percentChange := utils.CalculatePercentChange(threshold.ThresholdValue, latestValue)
-		if percentChange >= threshold.ThresholdValue || percentChange <= -threshold.ThresholdValue {
-			log.Printf("⚠️ Threshold exceeded for Threshold ID %d (Data ID: %d) - Triggering notifications", threshold.ThresholdID, threshold.DataID)
+	if plan.escalate {
+		log.Printf("📈 Threshold ID %d has held for %d periods - Escalated to tier %d, %d letters queued", threshold.ThresholdID, plan.breach.Periods, plan.tier, len(letters.emails))
+	} else {
+		log.Printf("⚠️ Threshold exceeded for Threshold ID %d (Data ID: %d) - %d letters queued", threshold.ThresholdID, threshold.DataID, len(letters.emails))
+	}
+	evaluation.Notified = true
+	return true, true
+}
 
-			dataName, err := utils.FetchDataName(db, threshold.DataID)
-			if err != nil {
-				log.Printf("❌ Failed to fetch data name for Data ID %d", threshold.DataID)
-				continue
-			}
+// prepareLetters renders the letters a firing sends, to the threshold's own
+// recipients and those of its escalation tier, on behalf of its owner or of
+// every member of its campaign.
+func prepareLetters(ctx context.Context, db database.DBQuerier, batch *checkBatch, threshold models.Threshold, result ThresholdResult, plan dispatchPlan, explanation string) (thresholdLetters, error) {
+	letter, alert := prepareAlert(threshold, result, explanation)
+	alert.Tier = plan.escalationTier()
+	log.Printf("🔍 Observed %.2f for Data ID %d (%s, %s threshold)", alert.Change, letter.DataID, letter.Mode, letter.Direction)
 
-			log.Printf("🔍 Calculated percent change for Data ID %d: %.2f%%", threshold.DataID, percentChange)
+	recipients, err := dispatchRecipients(ctx, db, batch.recipients[threshold.ThresholdID], plan)
+	if err != nil {
+		return thresholdLetters{}, err
+	}
+	campaign := batch.campaign(threshold.ThresholdID)
+	if campaign == nil {
+		emails, err := renderNotifications(letter, alert, recipients, batch.userEmail(threshold.UserID))
+		return thresholdLetters{emails: emails}, err
+	}
+	emails, members, err := campaignLetters(ctx, db, *campaign, letter, alert, recipients)
+	return thresholdLetters{emails: emails, campaign: campaign, members: members}, err
+}
 
-			recipients, err := fetchRecipientsForThreshold(db, threshold.ThresholdID)
-			if err != nil {
-				log.Printf("❌ Error fetching recipients for Threshold ID %d: %v", threshold.ThresholdID, err)
-				continue
-			}
-			userEmail := fetchUserEmail(db, threshold.UserID)
+// commitThresholdCheck stores a threshold's new state and queues the letters
+// of a firing, all or none of it. Nothing is committed once ctx is cancelled,
+// which is how a run that lost its leadership stops short of writing.
+func commitThresholdCheck(ctx context.Context, db database.DBQuerier, threshold models.Threshold, plan dispatchPlan, now time.Time, letters thresholdLetters) error {
+	tx, err := db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
 
-			SendNotifications(threshold, dataName, percentChange, recipients, userEmail)
+	if err := saveThresholdState(tx, threshold, plan.state, plan.fired(), now, plan.breach); err != nil {
+		return err
+	}
+	if plan.fired() {
+		if err := enqueueNotifications(tx, threshold.ThresholdID, letters); err != nil {
+			return err
 		}
 	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
 }
 
-func fetchAllThresholds(db database.DBQuerier) ([]models.Threshold, error) {
-	rows, err := db.Query(context.Background(), `
-		SELECT threshold_id, user_id, data_id, threshold_value, notify_user 
-		FROM thresholds`)
+// evaluateThreshold evaluates a threshold's rule, or else its conditions.
+func evaluateThreshold(batch *checkBatch, threshold models.Threshold, evaluation *models.ThresholdEvaluation) (ThresholdResult, error) {
+	if threshold.Expression != "" {
+		return evaluateRule(batch, threshold, evaluation)
+	}
+	return evaluateConditions(batch, threshold, evaluation)
+}
+
+// prepareAlert describes a met threshold for its letters, which lead with the
+// first met condition; for an OR threshold that need not be the threshold's
+// own. The returned threshold carries that condition's data, mode, direction
+// and value.
+func prepareAlert(threshold models.Threshold, result ThresholdResult, explanation string) (models.Threshold, Alert) {
+	var met []ConditionResult
+	for _, conditionResult := range result.Conditions {
+		if conditionResult.Triggered {
+			met = append(met, conditionResult)
+		}
+	}
+	headline := met[0]
+
+	alert := Alert{
+		DataName:    headline.Condition.Name,
+		Latest:      headline.Latest,
+		Change:      headline.Observed,
+		Explanation: explanation,
+	}
+	for _, conditionResult := range met[1:] {
+		alert.AlsoMet = append(alert.AlsoMet, describeCondition(conditionResult.Condition.Name, conditionResult))
+	}
+	if threshold.Expression != "" {
+		alert.AlsoMet = append(alert.AlsoMet, fmt.Sprintf("The rule %q holds", threshold.Expression))
+	}
+
+	letter := threshold
+	letter.DataID, letter.Mode, letter.Direction, letter.ThresholdValue = headline.Condition.DataID, headline.Condition.Mode, headline.Condition.Direction, headline.Condition.Value
+	return letter, alert
+}
+
+// evaluateConditions evaluates a threshold's own condition and any extra ones,
+// recording the values each was compared against.
+func evaluateConditions(batch *checkBatch, threshold models.Threshold, evaluation *models.ThresholdEvaluation) (ThresholdResult, error) {
+	conditions := threshold.AllConditions()
+	inputs := make([]ThresholdInput, len(conditions))
+	latest := make([]models.Data, len(conditions))
+	for i, condition := range conditions {
+		var err error
+		if inputs[i], latest[i], err = batch.conditionInput(condition); err != nil {
+			return ThresholdResult{}, err
+		}
+	}
+	return evaluateConditionInputs(threshold, latest, inputs, evaluation)
+}
+
+// evaluateConditionInputs evaluates a threshold's conditions against inputs
+// already loaded, one per condition along with its series' latest row.
+func evaluateConditionInputs(threshold models.Threshold, latest []models.Data, inputs []ThresholdInput, evaluation *models.ThresholdEvaluation) (ThresholdResult, error) {
+	result, err := EvaluateCompound(threshold, inputs)
+	if err != nil {
+		return ThresholdResult{}, err
+	}
+
+	comparisons := make([]string, len(result.Conditions))
+	for i := range result.Conditions {
+		result.Conditions[i].Condition.Name = latest[i].Name
+		evaluation.Inputs = append(evaluation.Inputs, evaluationInput(latest[i], inputs[i], result.Conditions[i]))
+		comparisons[i] = evaluation.Inputs[i].Comparison
+	}
+	evaluation.Metric = &result.Observed
+	evaluation.Comparison = strings.Join(comparisons, " "+threshold.Operator+" ")
+	result.Year, result.Period = latest[0].Year, latest[0].Period
+	return result, nil
+}
+
+var thresholdColumnNames = []string{
+	"threshold_id", "user_id", "data_id", "threshold_value", "mode", "direction", "baseline_value", "notify_user",
+	"state", "hysteresis", "cooldown_hours", "last_triggered_at", "operator", "expression",
+	"breach_periods", "breach_year", "breach_period",
+}
+
+// thresholdColumns lists the columns scanned by thresholdScanTargets, each
+// prefixed with a table alias such as "t.".
+func thresholdColumns(alias string) string {
+	return alias + strings.Join(thresholdColumnNames, ", "+alias)
+}
+
+func thresholdScanTargets(threshold *models.Threshold) []interface{} {
+	return []interface{}{
+		&threshold.ThresholdID, &threshold.UserID, &threshold.DataID, &threshold.ThresholdValue, &threshold.Mode, &threshold.Direction, &threshold.BaselineValue, &threshold.NotifyUser,
+		&threshold.State, &threshold.Hysteresis, &threshold.CooldownHours, &threshold.LastTriggeredAt, &threshold.Operator, &threshold.Expression,
+		&threshold.BreachPeriods, &threshold.BreachYear, &threshold.BreachPeriod,
+	}
+}
+
+// thresholdIsActive is the condition, on a thresholds table aliased as "t",
+// that leaves out thresholds which are paused or have expired.
+const thresholdIsActive = `(t.paused_until IS NULL OR t.paused_until <= NOW())
+		  AND (t.expires_at IS NULL OR t.expires_at > NOW())`
+
+// fetchThreshold loads a single threshold with its conditions. pgx.ErrNoRows
+// is wrapped when it does not exist.
+func fetchThreshold(db database.DBQuerier, thresholdID int) (models.Threshold, error) {
+	var threshold models.Threshold
+	err := db.QueryRow(context.Background(), `
+		SELECT `+thresholdColumns("")+`
+		FROM thresholds
+		WHERE threshold_id = $1`, thresholdID).Scan(thresholdScanTargets(&threshold)...)
+	if err != nil {
+		return threshold, fmt.Errorf("error fetching threshold %d: %w", thresholdID, err)
+	}
+
+	thresholds := []models.Threshold{threshold}
+	if err := attachThresholdConditions(db, thresholds); err != nil {
+		return threshold, err
+	}
+	return thresholds[0], nil
+}
+
+func fetchAllThresholds(ctx context.Context, db database.DBQuerier) ([]models.Threshold, error) {
+	rows, err := db.Query(ctx, `
+		SELECT `+thresholdColumns("t.")+`
+		FROM thresholds t
+		WHERE `+thresholdIsActive)
 	if err != nil {
 		log.Printf("❌ Failed to fetch thresholds: %v", err)
 		return nil, err
@@ -78,7 +373,7 @@ func fetchAllThresholds(db database.DBQuerier) ([]models.Threshold, error) {
 	var thresholds []models.Threshold
 	for rows.Next() {
 		var threshold models.Threshold
-		if err := rows.Scan(&threshold.ThresholdID, &threshold.UserID, &threshold.DataID, &threshold.ThresholdValue, &threshold.NotifyUser); err != nil {
+		if err := rows.Scan(thresholdScanTargets(&threshold)...); err != nil {
 			log.Printf("❌ Error scanning threshold row: %v", err)
 			return nil, err
 		}
@@ -89,25 +384,122 @@ func fetchAllThresholds(db database.DBQuerier) ([]models.Threshold, error) {
 		log.Printf("❌ Error iterating over threshold rows: %v", err)
 		return nil, err
 	}
+	rows.Close()
+
+	if err := attachThresholdConditions(db, thresholds); err != nil {
+		log.Printf("❌ Error fetching threshold conditions: %v", err)
+		return nil, err
+	}
 
 	log.Printf("✅ Fetched %d thresholds successfully.", len(thresholds))
 	return thresholds, nil
 }
 
-func fetchLatestDataValue(db database.DBQuerier, dataID int) (float64, error) {
-	var latestValue float64
-	err := db.QueryRow(context.Background(), "SELECT latest_value FROM data WHERE data_id = $1", dataID).Scan(&latestValue)
+func fetchThresholdsForSeries(ctx context.Context, db database.DBQuerier, seriesIDs []string) ([]models.Threshold, error) {
+	rows, err := db.Query(ctx, `
+		SELECT `+thresholdColumns("t.")+`
+		FROM thresholds t
+		JOIN data d ON d.data_id = t.data_id
+		WHERE (d.series_id = ANY($1)
+		   OR t.expression <> ''
+		   OR EXISTS (
+				SELECT 1 FROM threshold_conditions c
+				JOIN data cd ON cd.data_id = c.data_id
+				WHERE c.threshold_id = t.threshold_id AND cd.series_id = ANY($1)
+		   ))
+		  AND `+thresholdIsActive+`
+		ORDER BY t.threshold_id`, seriesIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var thresholds []models.Threshold
+	for rows.Next() {
+		var threshold models.Threshold
+		if err := rows.Scan(thresholdScanTargets(&threshold)...); err != nil {
+			return nil, err
+		}
+		thresholds = append(thresholds, threshold)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	return thresholds, attachThresholdConditions(db, thresholds)
+}
+
+// GetThresholdConditions returns the extra conditions of the given compound
+// thresholds, keyed by threshold ID and in the order they were added.
+func GetThresholdConditions(db database.DBQuerier, thresholdIDs []int) (map[int][]models.ThresholdCondition, error) {
+	conditions := make(map[int][]models.ThresholdCondition)
+	if len(thresholdIDs) == 0 {
+		return conditions, nil
+	}
+
+	rows, err := db.Query(context.Background(), `
+		SELECT c.condition_id, c.threshold_id, c.data_id, d.name, c.mode, c.direction, c.value, c.baseline_value
+		FROM threshold_conditions c
+		JOIN data d ON d.data_id = c.data_id
+		WHERE c.threshold_id = ANY($1)
+		ORDER BY c.threshold_id, c.position`, thresholdIDs)
 	if err != nil {
-		return 0, err
+		return nil, fmt.Errorf("error querying threshold conditions: %w", err)
 	}
-	return latestValue, nil
+	defer rows.Close()
+
+	for rows.Next() {
+		var condition models.ThresholdCondition
+		if err := rows.Scan(&condition.ConditionID, &condition.ThresholdID, &condition.DataID, &condition.Name,
+			&condition.Mode, &condition.Direction, &condition.Value, &condition.BaselineValue); err != nil {
+			return nil, fmt.Errorf("error scanning threshold condition: %w", err)
+		}
+		conditions[condition.ThresholdID] = append(conditions[condition.ThresholdID], condition)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading threshold conditions: %w", err)
+	}
+	return conditions, nil
 }
 
-func fetchSeriesIDForData(db database.DBQuerier, dataID int) (string, error) {
-	var seriesID string
-	err := db.QueryRow(context.Background(), "SELECT series_id FROM data WHERE data_id = $1", dataID).Scan(&seriesID)
+func attachThresholdConditions(db database.DBQuerier, thresholds []models.Threshold) error {
+	thresholdIDs := make([]int, len(thresholds))
+	for i := range thresholds {
+		thresholdIDs[i] = thresholds[i].ThresholdID
+	}
+
+	conditions, err := GetThresholdConditions(db, thresholdIDs)
 	if err != nil {
-		return "", err
+		return err
+	}
+	for i := range thresholds {
+		thresholds[i].Conditions = conditions[thresholds[i].ThresholdID]
+	}
+	return nil
+}
+
+// errThresholdChanged is returned when another check saved a threshold's state
+// after this one read it.
+var errThresholdChanged = errors.New("threshold changed since it was read")
+
+// saveThresholdState moves a threshold on from the state and breach it was
+// checked in. It fails with errThresholdChanged, saving nothing, if another
+// check moved the threshold on first, even if only its breach count.
+func saveThresholdState(tx pgx.Tx, threshold models.Threshold, state string, fired bool, now time.Time, breach Breach) error {
+	tag, err := tx.Exec(context.Background(), `
+		UPDATE thresholds
+		SET state = $1, last_triggered_at = CASE WHEN $2 THEN $3 ELSE last_triggered_at END,
+		    breach_periods = $5, breach_year = $6, breach_period = $7
+		WHERE threshold_id = $4 AND state = $8 AND last_triggered_at IS NOT DISTINCT FROM $9
+		  AND breach_periods = $10 AND breach_year = $11 AND breach_period = $12`,
+		state, fired, now, threshold.ThresholdID, breach.Periods, breach.Year, breach.Period, threshold.State, threshold.LastTriggeredAt,
+		threshold.BreachPeriods, threshold.BreachYear, threshold.BreachPeriod)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return errThresholdChanged
 	}
-	return seriesID, nil
+	return nil
 }
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"megga-backend/internal/database"
+	"megga-backend/internal/models"
+	"time"
+)
+
+// PreviewThreshold evaluates a threshold against the current data the way the
+// monitor would and renders the letters for it, without saving its state,
+// recording the evaluation or sending anything. The letters are rendered
+// whenever the threshold is met, even if it already fired and so would not
+// send them again, and go to the escalation tier the breach has reached.
+// pgx.ErrNoRows is wrapped when the threshold does not exist.
+func PreviewThreshold(ctx context.Context, db database.DBQuerier, thresholdID int) (models.ThresholdPreview, error) {
+	preview := models.ThresholdPreview{ThresholdID: thresholdID, Emails: []models.RenderedEmail{}}
+	threshold, err := fetchThreshold(db, thresholdID)
+	if err != nil {
+		return preview, err
+	}
+	batch, err := loadCheckBatch(ctx, db, []models.Threshold{threshold})
+	if err != nil {
+		return preview, err
+	}
+
+	evaluation := models.ThresholdEvaluation{
+		ThresholdID:   threshold.ThresholdID,
+		EvaluatedAt:   time.Now().UTC(),
+		Inputs:        []models.EvaluationInput{},
+		PreviousState: threshold.State,
+		State:         threshold.State,
+	}
+	result, err := evaluateThreshold(batch, threshold, &evaluation)
+	if err != nil {
+		evaluation.Error = err.Error()
+		evaluation.Explanation = "The threshold could not be evaluated, so it would not fire."
+		preview.Evaluation = evaluation
+		return preview, nil
+	}
+	evaluation.Triggered = result.Triggered
+
+	plan := planDispatch(threshold, batch.tiers[threshold.ThresholdID], result, evaluation)
+	evaluation.State = plan.state
+	evaluation.BreachPeriods = plan.breach.Periods
+	evaluation.Escalated = plan.escalate
+	evaluation.Tier = plan.tier
+	evaluation.Explanation = explainEvaluation(threshold, evaluation, plan.fired())
+	preview.WouldFire = plan.fired()
+	preview.Evaluation = evaluation
+	if !result.Triggered {
+		return preview, nil
+	}
+
+	letter, alert := prepareAlert(threshold, result, evaluation.Explanation)
+	alert.Tier = plan.escalationTier()
+	recipients, err := dispatchRecipients(ctx, db, batch.recipients[threshold.ThresholdID], plan)
+	if err != nil {
+		return preview, err
+	}
+	if campaign := batch.campaign(threshold.ThresholdID); campaign != nil {
+		members, err := fetchCampaignMembers(ctx, db, campaign.CampaignID)
+		if err != nil {
+			return preview, err
+		}
+		preview.Emails = RenderCampaignNotifications(*campaign, letter, alert, recipients, members)
+		return preview, nil
+	}
+	preview.Emails = RenderNotifications(letter, alert, recipients, batch.userEmail(threshold.UserID))
+	return preview, nil
+}
@@ -0,0 +1,177 @@
+package expression_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"megga-backend/internal/expression"
+)
+
+// fakeSource holds each series' values newest first.
+type fakeSource struct {
+	values  map[string][]float64
+	yearAgo map[string]float64
+	reads   []string
+}
+
+func (s *fakeSource) Recent(series string, n int) ([]float64, error) {
+	s.reads = append(s.reads, series)
+	values := s.values[series]
+	if len(values) > n {
+		values = values[:n]
+	}
+	return values, nil
+}
+
+func (s *fakeSource) YearAgo(series string) (float64, error) {
+	value, ok := s.yearAgo[series]
+	if !ok {
+		return 0, expression.ErrNotEnoughData
+	}
+	return value, nil
+}
+
+func newSource() *fakeSource {
+	return &fakeSource{
+		values: map[string][]float64{
+			"eggs":          {4.40, 4.00, 3.80, 3.60},
+			"gasoline":      {3.60, 3.70},
+			"LEU0252881600": {365, 365},
+		},
+		yearAgo: map[string]float64{"eggs": 4.00},
+	}
+}
+
+func TestParse_SyntaxErrors(t *testing.T) {
+	tests := []struct {
+		rule string
+		msg  string
+	}{
+		{"", "rule is empty"},
+		{"latest(eggs)", "rule must be a comparison"},
+		{"latest(eggs) > ", "expected a number, function or \"(\""},
+		{"eggs > 4", "must be used inside a function"},
+		{"median(eggs) > 4", "unknown function"},
+		{"latest(eggs > 4", "expected \")\""},
+		{"avg_n(eggs) > 4", "number of periods"},
+		{"avg_n(eggs, 0) > 4", "whole number from 1"},
+		{"latest(eggs) > 4 and 3", "needs a comparison on each side"},
+		{"(latest(eggs) > 4) + 1 > 2", "needs a number on each side"},
+		{"latest(eggs) > 4 $", "unexpected character"},
+		{"latest(eggs) > 4 < 5", "unexpected \"<\""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			_, err := expression.Parse(tt.rule)
+			var syntaxErr *expression.SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("Expected a syntax error, got %v", err)
+			}
+			if !strings.Contains(syntaxErr.Msg, tt.msg) {
+				t.Errorf("Expected %q in the error, got %q", tt.msg, syntaxErr.Msg)
+			}
+		})
+	}
+}
+
+func TestParse_Series(t *testing.T) {
+	rule, err := expression.Parse("yoy(eggs) > 8 AND latest(gasoline) > 3.5 or max_n(eggs, 3) > 5")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if series := rule.Series(); !reflect.DeepEqual(series, []string{"eggs", "gasoline"}) {
+		t.Errorf("Expected eggs and gasoline, got %v", series)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		rule     string
+		expected bool
+	}{
+		{"latest(eggs) > 4", true},
+		{"previous(eggs) >= 4", true},
+		{"change(eggs) > 9.9 and change(eggs) < 10.1", true},
+		{"yoy(eggs) > 8 and latest(gasoline) > 3.5", true},
+		{"yoy(eggs) > 8 and change(gasoline) > 0", false},
+		{"avg_n(eggs, 4) > 3.94 and avg_n(eggs, 4) < 3.96", true},
+		{"max_n(eggs, 3) - min_n(eggs, 3) > 0.5", true},
+		{"not latest(gasoline) > 4 or latest(eggs) > 100", true},
+		{"change(LEU0252881600) == 0", true},
+		{"-latest(eggs) * 2 < -8", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			rule, err := expression.Parse(tt.rule)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			holds, err := rule.Evaluate(newSource())
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if holds != tt.expected {
+				t.Errorf("Expected %t, got %t", tt.expected, holds)
+			}
+		})
+	}
+}
+
+func TestEvaluate_NotEnoughData(t *testing.T) {
+	rule, err := expression.Parse("avg_n(gasoline, 6) > 3")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := rule.Evaluate(newSource()); !errors.Is(err, expression.ErrNotEnoughData) {
+		t.Errorf("Expected ErrNotEnoughData, got %v", err)
+	}
+}
+
+func TestEvaluate_ShortCircuits(t *testing.T) {
+	rule, err := expression.Parse("latest(eggs) > 100 and yoy(gasoline) > 5")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	src := newSource()
+	holds, err := rule.Evaluate(src)
+	if err != nil || holds {
+		t.Fatalf("Expected false without error, got %t, %v", holds, err)
+	}
+	if !reflect.DeepEqual(src.reads, []string{"eggs"}) {
+		t.Errorf("Expected only eggs to be read, got %v", src.reads)
+	}
+}
+
+func TestTrace_RecordsCalls(t *testing.T) {
+	rule, err := expression.Parse("latest(eggs) > 4 and avg_n(eggs, 2) < 5")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	holds, calls, err := rule.Trace(newSource())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var got []string
+	for _, call := range calls {
+		got = append(got, call.String())
+	}
+	want := []string{"latest(eggs) = 4.40", "avg_n(eggs, 2) = 4.20"}
+	if !holds || !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected the rule to hold with calls %v, got %t, %v", want, holds, got)
+	}
+}
+
+func TestIsValidAlias(t *testing.T) {
+	for alias, expected := range map[string]bool{
+		"eggs": true, "natural_gas": true, "APU0000708111": true,
+		"": false, "2eggs": false, "eggs-large": false, "latest": false, "and": false,
+	} {
+		if got := expression.IsValidAlias(alias); got != expected {
+			t.Errorf("IsValidAlias(%q) = %t, expected %t", alias, got, expected)
+		}
+	}
+}
@@ -0,0 +1,281 @@
+package handlers_test
+
+import (
+	"bytes"
+	"megga-backend/handlers"
+	"megga-backend/internal/database"
+	"megga-backend/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v4"
+	"github.com/pashagolub/pgxmock"
+)
+
+func TestCreateCampaign_MissingLetter(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	body := bytes.NewBufferString(`{"userId": 1, "dataId": 2, "thresholdValue": 10, "recipients": [1], "name": "Cheaper Eggs"}`)
+	req := httptest.NewRequest(http.MethodPost, "/campaigns", body)
+	w := httptest.NewRecorder()
+
+	handlers.CreateCampaign(w, req, mock)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+}
+
+func TestCreateCampaign_StoresThresholdAndCampaign(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	expectSignedInUser(mock, "owner@example.com", 1)
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO thresholds").
+		WithArgs(1, 2, 10.0, models.ThresholdModePercentPrevious, models.ThresholdDirectionRise, false, 0.0, 0, models.ThresholdOperatorAnd, "", (*time.Time)(nil)).
+		WillReturnRows(pgxmock.NewRows([]string{"threshold_id"}).AddRow(9))
+	mock.ExpectExec("INSERT INTO threshold_recipients").
+		WithArgs(9, 1).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectQuery("INSERT INTO campaigns").
+		WithArgs(1, 9, "Cheaper Eggs", "Write to your representative", "Eggs are up", "Dear [Recipient Name]").
+		WillReturnRows(pgxmock.NewRows([]string{"campaign_id"}).AddRow(3))
+	mock.ExpectCommit()
+
+	// notifyUser is ignored, since members get their own letters, and so is
+	// userId: the signed-in user owns the campaign.
+	body := bytes.NewBufferString(`{"userId": 9, "dataId": 2, "thresholdValue": 10, "direction": "rise", "notifyUser": true, "recipients": [1],
+		"name": "Cheaper Eggs", "description": "Write to your representative", "letterSubject": "Eggs are up", "letterTemplate": "Dear [Recipient Name]"}`)
+	req := httptest.NewRequest(http.MethodPost, "/campaigns", body)
+	req.Header.Set("X-User-Email", "owner@example.com")
+	w := httptest.NewRecorder()
+
+	handlers.CreateCampaign(w, req, mock)
+
+	if w.Code != http.StatusCreated {
+		t.Errorf("Expected status 201, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), `"campaign_id":3`) {
+		t.Errorf("Expected the new campaign's ID, got %s", w.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+// expectSignedInUser expects the lookup of the user a request's token names.
+func expectSignedInUser(mock pgxmock.PgxPoolIface, email string, userID int) {
+	mock.ExpectQuery("SELECT user_id FROM users").
+		WithArgs(email).
+		WillReturnRows(pgxmock.NewRows([]string{"user_id"}).AddRow(userID))
+}
+
+func serveCampaignMembership(mock pgxmock.PgxPoolIface, handler func(http.ResponseWriter, *http.Request, database.DBQuerier), action, email, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/campaigns/3/"+action, strings.NewReader(body))
+	req = mux.SetURLVars(req, map[string]string{"id": "3"})
+	if email != "" {
+		req.Header.Set("X-User-Email", email)
+	}
+	w := httptest.NewRecorder()
+	handler(w, req, mock)
+	return w
+}
+
+func TestJoinCampaign_NotFound(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	expectSignedInUser(mock, "member@example.com", 7)
+	mock.ExpectQuery("SELECT 1 FROM campaigns").
+		WithArgs(3).
+		WillReturnError(pgx.ErrNoRows)
+
+	w := serveCampaignMembership(mock, handlers.JoinCampaign, "join", "member@example.com", "")
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+}
+
+func TestJoinCampaign_AddsMember(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	expectSignedInUser(mock, "member@example.com", 7)
+	mock.ExpectQuery("SELECT 1 FROM campaigns").
+		WithArgs(3).
+		WillReturnRows(pgxmock.NewRows([]string{"?column?"}).AddRow(1))
+	mock.ExpectExec("INSERT INTO campaign_members").
+		WithArgs(3, 7).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+	w := serveCampaignMembership(mock, handlers.JoinCampaign, "join", "member@example.com", "")
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+func TestJoinCampaign_CannotEnrollAnotherUser(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	// The body names user 9, but the signed-in user 7 is the one who joins.
+	expectSignedInUser(mock, "member@example.com", 7)
+	mock.ExpectQuery("SELECT 1 FROM campaigns").
+		WithArgs(3).
+		WillReturnRows(pgxmock.NewRows([]string{"?column?"}).AddRow(1))
+	mock.ExpectExec("INSERT INTO campaign_members").
+		WithArgs(3, 7).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+	w := serveCampaignMembership(mock, handlers.JoinCampaign, "join", "member@example.com", `{"userId": 9}`)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+func TestJoinCampaign_RequiresSignedInUser(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	w := serveCampaignMembership(mock, handlers.JoinCampaign, "join", "", `{"userId": 9}`)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401, got %d", w.Code)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+func TestLeaveCampaign_NotAMember(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	expectSignedInUser(mock, "member@example.com", 7)
+	mock.ExpectExec("DELETE FROM campaign_members").
+		WithArgs(3, 7).
+		WillReturnResult(pgxmock.NewResult("DELETE", 0))
+
+	w := serveCampaignMembership(mock, handlers.LeaveCampaign, "leave", "member@example.com", "")
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+}
+
+func TestLeaveCampaign_CannotRemoveAnotherUser(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	// Only the signed-in user's own membership is removed.
+	expectSignedInUser(mock, "member@example.com", 7)
+	mock.ExpectExec("DELETE FROM campaign_members").
+		WithArgs(3, 7).
+		WillReturnResult(pgxmock.NewResult("DELETE", 1))
+
+	w := serveCampaignMembership(mock, handlers.LeaveCampaign, "leave", "member@example.com", `{"userId": 9}`)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+func TestGetCampaignStats_OnlyForOwner(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	mock.ExpectQuery("FROM campaigns c").
+		WithArgs(3).
+		WillReturnRows(pgxmock.NewRows([]string{"email"}).AddRow("owner@example.com"))
+
+	req := httptest.NewRequest(http.MethodGet, "/campaigns/3/stats", nil)
+	req.Header.Set("X-User-Email", "member@example.com")
+	req = mux.SetURLVars(req, map[string]string{"id": "3"})
+	w := httptest.NewRecorder()
+
+	handlers.GetCampaignStats(w, req, mock)
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403, got %d", w.Code)
+	}
+}
+
+func TestGetCampaignStats_CountsMembersAndLetters(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	delivered := time.Date(2025, time.March, 12, 13, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("FROM campaigns c").
+		WithArgs(3).
+		WillReturnRows(pgxmock.NewRows([]string{"email"}).AddRow("Owner@example.com"))
+	mock.ExpectQuery("FROM notification_outbox o").
+		WithArgs(3, models.OutboxSent, models.EmailKindRecipient).
+		WillReturnRows(pgxmock.NewRows([]string{"members", "deliveries", "letters", "last_delivered_at"}).AddRow(12, 2, 20, &delivered))
+
+	req := httptest.NewRequest(http.MethodGet, "/campaigns/3/stats", nil)
+	req.Header.Set("X-User-Email", "owner@example.com")
+	req = mux.SetURLVars(req, map[string]string{"id": "3"})
+	w := httptest.NewRecorder()
+
+	handlers.GetCampaignStats(w, req, mock)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	for _, want := range []string{`"members":12`, `"deliveries":2`, `"letters_sent":20`, `"last_delivered_at":"2025-03-12T13:00:00Z"`} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("Expected %s in the stats, got %s", want, w.Body.String())
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
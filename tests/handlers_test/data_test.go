@@ -21,6 +21,11 @@ func TestCreateData(t *testing.T) {
 	}
 	defer mock.Close()
 
+	mock.ExpectQuery("SELECT series_id, name, unit, category, active, provider, priority, created_at, updated_at FROM series_catalog").
+		WithArgs("APU0000708111").
+		WillReturnRows(pgxmock.NewRows([]string{"series_id", "name", "unit", "category", "active", "provider", "priority", "created_at", "updated_at"}).
+			AddRow("APU0000708111", "Eggs, Grade A, Large", "per dozen", "food", true, "bls", "normal", time.Now(), time.Now()))
+
 	mock.ExpectQuery("SELECT data_id FROM data WHERE series_id =").
 		WithArgs("APU0000708111").
 		WillReturnError(pgx.ErrNoRows)
@@ -51,6 +56,28 @@ func TestCreateData(t *testing.T) {
 	}
 }
 
+func TestCreateData_UnknownSeries(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	mock.ExpectQuery("SELECT series_id, name, unit, category, active, provider, priority, created_at, updated_at FROM series_catalog").
+		WithArgs("NOT_IN_CATALOG").
+		WillReturnError(pgx.ErrNoRows)
+
+	body := bytes.NewBufferString(`{"series_id": "NOT_IN_CATALOG", "latest_value": 1.0}`)
+	req := httptest.NewRequest(http.MethodPost, "/data", body)
+	w := httptest.NewRecorder()
+
+	handlers.CreateData(w, req, mock)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
 func TestCreateData_InvalidBody(t *testing.T) {
 	mock, err := pgxmock.NewPool()
 	if err != nil {
@@ -266,3 +293,27 @@ func TestRejectNegativeValues(t *testing.T) {
 		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
 	}
 }
+
+func TestCreateData_InvalidPeriod(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	body := bytes.NewBufferString(`{
+		"series_id": "APU0000708111",
+		"latest_value": 4.146,
+		"period": "M14",
+		"year": "2024"
+	}`)
+	req := httptest.NewRequest(http.MethodPost, "/data", body)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	handlers.CreateData(w, req, mock)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
@@ -0,0 +1,201 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"megga-backend/handlers"
+	"megga-backend/internal/middleware"
+	"megga-backend/internal/models"
+	"megga-backend/internal/scheduler"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v4"
+	"github.com/pashagolub/pgxmock"
+)
+
+type fakeJobRunner struct {
+	jobRunID int
+	err      error
+	started  []string
+}
+
+func (f *fakeJobRunner) RunNow(ctx context.Context, name string) (int, error) {
+	f.started = append(f.started, name)
+	return f.jobRunID, f.err
+}
+
+func setupJobRouter(mock pgxmock.PgxPoolIface, runner handlers.JobRunner) *mux.Router {
+	router := mux.NewRouter()
+	adminRouter := router.PathPrefix("/admin").Subrouter()
+	adminRouter.Use(middleware.RequireAdmin([]string{"admin@example.com"}))
+	handlers.RegisterJobRoutes(adminRouter, mock, runner)
+	return router
+}
+
+func TestTriggerIngest_Accepted(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	runner := &fakeJobRunner{jobRunID: 12}
+	req := httptest.NewRequest(http.MethodPost, "/admin/jobs/ingest", nil)
+	req.Header.Set("X-User-Email", "admin@example.com")
+	w := httptest.NewRecorder()
+
+	setupJobRouter(mock, runner).ServeHTTP(w, req)
+
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusAccepted, w.Code, w.Body.String())
+	}
+	if len(runner.started) != 1 || runner.started[0] != "ingest" {
+		t.Errorf("Expected the ingest job to be started, got %v", runner.started)
+	}
+
+	var response map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response["job_run_id"] != float64(12) {
+		t.Errorf("Expected job_run_id 12, got %v", response["job_run_id"])
+	}
+}
+
+func TestTriggerIngest_AlreadyRunning(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	runner := &fakeJobRunner{err: scheduler.ErrJobRunning}
+	req := httptest.NewRequest(http.MethodPost, "/admin/jobs/ingest", nil)
+	req.Header.Set("X-User-Email", "admin@example.com")
+	w := httptest.NewRecorder()
+
+	setupJobRouter(mock, runner).ServeHTTP(w, req)
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected status %d, got %d", http.StatusConflict, w.Code)
+	}
+}
+
+func TestTriggerIngest_NotLeader(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	runner := &fakeJobRunner{err: scheduler.ErrNotLeader}
+	req := httptest.NewRequest(http.MethodPost, "/admin/jobs/ingest", nil)
+	req.Header.Set("X-User-Email", "admin@example.com")
+	w := httptest.NewRecorder()
+
+	setupJobRouter(mock, runner).ServeHTTP(w, req)
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected status %d, got %d", http.StatusConflict, w.Code)
+	}
+}
+
+func TestGetJobRun_WithIngestionOutcomes(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	startedAt := time.Date(2025, 1, 16, 13, 30, 0, 0, time.UTC)
+	finishedAt := startedAt.Add(90 * time.Second)
+	ingestionRunID := 7
+	checked, triggered := 3, 1
+
+	mock.ExpectQuery("SELECT job_run_id, job_name, trigger, scheduled_for, status, error, started_at, finished_at, ingestion_run_id").
+		WithArgs(12).
+		WillReturnRows(pgxmock.NewRows([]string{"job_run_id", "job_name", "trigger", "scheduled_for", "status", "error", "started_at", "finished_at", "ingestion_run_id"}).
+			AddRow(12, "ingest", models.JobTriggerManual, startedAt, models.JobSucceeded, "", startedAt, &finishedAt, &ingestionRunID))
+	mock.ExpectQuery("FROM ingestion_runs").
+		WithArgs([]int{7}).
+		WillReturnRows(pgxmock.NewRows([]string{"ingestion_run_id", "status", "error", "started_at", "finished_at", "thresholds_checked", "thresholds_triggered"}).
+			AddRow(7, models.IngestionPartial, "", startedAt, &finishedAt, &checked, &triggered))
+	mock.ExpectQuery("FROM ingestion_series_results").
+		WithArgs([]int{7}).
+		WillReturnRows(pgxmock.NewRows([]string{"ingestion_run_id", "series_id", "provider", "outcome", "message", "observation_count"}).
+			AddRow(7, "CUUR0000SA0", "bls", models.OutcomeOK, "", 13).
+			AddRow(7, "CUUR0000XX0", "bls", models.OutcomeInvalidSeries, "Series does not exist", 0))
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/jobs/12", nil)
+	req.Header.Set("X-User-Email", "admin@example.com")
+	w := httptest.NewRecorder()
+
+	setupJobRouter(mock, &fakeJobRunner{}).ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var run models.JobRun
+	if err := json.Unmarshal(w.Body.Bytes(), &run); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if run.DurationSeconds == nil || *run.DurationSeconds != 90 {
+		t.Errorf("Expected a 90 second duration, got %v", run.DurationSeconds)
+	}
+	if run.Ingestion == nil || run.Ingestion.Status != models.IngestionPartial || len(run.Ingestion.Series) != 2 {
+		t.Fatalf("Expected the partial ingestion with 2 series outcomes, got %+v", run.Ingestion)
+	}
+	if run.Ingestion.Thresholds == nil || run.Ingestion.Thresholds.Triggered != 1 {
+		t.Errorf("Expected 1 triggered threshold, got %+v", run.Ingestion.Thresholds)
+	}
+	if run.Ingestion.Series[1].Outcome != models.OutcomeInvalidSeries {
+		t.Errorf("Expected the second series to be invalid, got %s", run.Ingestion.Series[1].Outcome)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+func TestGetJobRun_NotFound(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	mock.ExpectQuery("FROM job_runs").
+		WithArgs(99).
+		WillReturnError(pgx.ErrNoRows)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/jobs/99", nil)
+	req.Header.Set("X-User-Email", "admin@example.com")
+	w := httptest.NewRecorder()
+
+	setupJobRouter(mock, &fakeJobRunner{}).ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestGetJobRuns_InvalidLimit(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/jobs?limit=abc", nil)
+	req.Header.Set("X-User-Email", "admin@example.com")
+	w := httptest.NewRecorder()
+
+	setupJobRouter(mock, &fakeJobRunner{}).ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
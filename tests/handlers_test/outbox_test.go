@@ -0,0 +1,158 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"megga-backend/handlers"
+	"megga-backend/internal/middleware"
+	"megga-backend/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pashagolub/pgxmock"
+)
+
+func setupOutboxRouter(mock pgxmock.PgxPoolIface) *mux.Router {
+	router := mux.NewRouter()
+	adminRouter := router.PathPrefix("/admin").Subrouter()
+	adminRouter.Use(middleware.RequireAdmin([]string{"admin@example.com"}))
+	handlers.RegisterOutboxRoutes(adminRouter, mock)
+	return router
+}
+
+func serveOutbox(mock pgxmock.PgxPoolIface, method, target string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	req.Header.Set("X-User-Email", "admin@example.com")
+	w := httptest.NewRecorder()
+	setupOutboxRouter(mock).ServeHTTP(w, req)
+	return w
+}
+
+func TestGetOutbox_ListsDeadLetters(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	createdAt := time.Date(2025, 2, 14, 13, 30, 0, 0, time.UTC)
+	thresholdID := 4
+	mock.ExpectQuery("FROM notification_outbox").
+		WithArgs(models.OutboxDead, 50).
+		WillReturnRows(pgxmock.NewRows([]string{"outbox_id", "threshold_id", "campaign_id", "kind", "to_address", "subject", "body", "status", "attempts",
+			"next_attempt_at", "last_error", "created_at", "sent_at"}).
+			AddRow(9, &thresholdID, (*int)(nil), models.EmailKindRecipient, "rep@example.com", "Urgent", "Dear Pat Doe,", models.OutboxDead, 8,
+				createdAt, "550 mailbox unavailable", createdAt, (*time.Time)(nil)))
+
+	w := serveOutbox(mock, http.MethodGet, "/admin/outbox?status=dead")
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var letters []models.OutboxNotification
+	if err := json.Unmarshal(w.Body.Bytes(), &letters); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(letters) != 1 || letters[0].LastError != "550 mailbox unavailable" || *letters[0].ThresholdID != 4 {
+		t.Errorf("Expected the dead letter with its error, got %+v", letters)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+func TestGetOutbox_InvalidStatus(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	w := serveOutbox(mock, http.MethodGet, "/admin/outbox?status=lost")
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestRequeueDeadLetter(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	mock.ExpectExec("UPDATE notification_outbox").
+		WithArgs(9, models.OutboxPending, models.OutboxDead).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+	w := serveOutbox(mock, http.MethodPost, "/admin/outbox/9/requeue")
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+func TestRequeueDeadLetter_NotDead(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	// A letter that is still pending or was sent is not a dead letter.
+	mock.ExpectExec("UPDATE notification_outbox").
+		WithArgs(9, models.OutboxPending, models.OutboxDead).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+	w := serveOutbox(mock, http.MethodPost, "/admin/outbox/9/requeue")
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestDiscardDeadLetter(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	mock.ExpectExec("DELETE FROM notification_outbox").
+		WithArgs(9, models.OutboxDead).
+		WillReturnResult(pgxmock.NewResult("DELETE", 1))
+
+	w := serveOutbox(mock, http.MethodDelete, "/admin/outbox/9")
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+func TestDiscardDeadLetter_NotFound(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	mock.ExpectExec("DELETE FROM notification_outbox").
+		WithArgs(9, models.OutboxDead).
+		WillReturnResult(pgxmock.NewResult("DELETE", 0))
+
+	w := serveOutbox(mock, http.MethodDelete, "/admin/outbox/9")
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
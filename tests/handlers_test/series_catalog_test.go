@@ -0,0 +1,163 @@
+package handlers_test
+
+import (
+	"bytes"
+	"megga-backend/handlers"
+	"megga-backend/internal/middleware"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v4"
+	"github.com/pashagolub/pgxmock"
+)
+
+func setupAdminRouter(mock pgxmock.PgxPoolIface) *mux.Router {
+	router := mux.NewRouter()
+	adminRouter := router.PathPrefix("/admin").Subrouter()
+	adminRouter.Use(middleware.RequireAdmin([]string{"admin@example.com"}))
+	handlers.RegisterSeriesCatalogRoutes(adminRouter, mock)
+	return router
+}
+
+func TestSeriesCatalog_RequiresAdmin(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/series", nil)
+	req.Header.Set("X-User-Email", "someone@example.com")
+	w := httptest.NewRecorder()
+
+	setupAdminRouter(mock).ServeHTTP(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status %d, got %d", http.StatusForbidden, w.Code)
+	}
+}
+
+func TestCreateSeries_Success(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT series_id FROM series_catalog WHERE series_id = $1")).
+		WithArgs("APU0000717311").
+		WillReturnError(pgx.ErrNoRows)
+
+	mock.ExpectQuery("INSERT INTO series_catalog").
+		WithArgs("APU0000717311", "Coffee, 100%, Ground Roast", "per lb.", "food", true, "bls", "normal", "").
+		WillReturnRows(pgxmock.NewRows([]string{"created_at", "updated_at"}).AddRow(time.Now(), time.Now()))
+
+	body := bytes.NewBufferString(`{
+		"series_id": "APU0000717311",
+		"name": "Coffee, 100%, Ground Roast",
+		"unit": "per lb.",
+		"category": "food"
+	}`)
+	req := httptest.NewRequest(http.MethodPost, "/admin/series", body)
+	req.Header.Set("X-User-Email", "Admin@Example.com")
+	w := httptest.NewRecorder()
+
+	setupAdminRouter(mock).ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Errorf("Expected status %d, got %d", http.StatusCreated, w.Code)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet mock expectations: %v", err)
+	}
+}
+
+func TestCreateSeries_Duplicate(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT series_id FROM series_catalog WHERE series_id = $1")).
+		WithArgs("APU0000708111").
+		WillReturnRows(pgxmock.NewRows([]string{"series_id"}).AddRow("APU0000708111"))
+
+	body := bytes.NewBufferString(`{"series_id": "APU0000708111", "name": "Eggs"}`)
+	req := httptest.NewRequest(http.MethodPost, "/admin/series", body)
+	w := httptest.NewRecorder()
+
+	handlers.CreateSeries(w, req, mock)
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected status %d, got %d", http.StatusConflict, w.Code)
+	}
+}
+
+func TestCreateSeries_UnknownProvider(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	body := bytes.NewBufferString(`{"series_id": "CPIAUCSL", "name": "CPI", "provider": "imf"}`)
+	req := httptest.NewRequest(http.MethodPost, "/admin/series", body)
+	w := httptest.NewRecorder()
+
+	handlers.CreateSeries(w, req, mock)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestUpdateSeries_Deactivate(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	mock.ExpectExec("UPDATE series_catalog").
+		WithArgs("Eggs, Grade A, Large", "per dozen", "food", false, "bls", "normal", "", "APU0000708111").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+	body := bytes.NewBufferString(`{"name": "Eggs, Grade A, Large", "unit": "per dozen", "category": "food", "active": false}`)
+	req := httptest.NewRequest(http.MethodPut, "/admin/series/APU0000708111", body)
+	req = mux.SetURLVars(req, map[string]string{"id": "APU0000708111"})
+	w := httptest.NewRecorder()
+
+	handlers.UpdateSeries(w, req, mock)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+}
+
+func TestDeleteSeries_NotFound(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	mock.ExpectExec("DELETE FROM series_catalog").
+		WithArgs("MISSING").
+		WillReturnResult(pgxmock.NewResult("DELETE", 0))
+
+	req := httptest.NewRequest(http.MethodDelete, "/admin/series/MISSING", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": "MISSING"})
+	w := httptest.NewRecorder()
+
+	handlers.DeleteSeries(w, req, mock)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
@@ -1,13 +1,20 @@
 package handlers_test
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"megga-backend/handlers"
+	"megga-backend/internal/models"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v4"
+	"github.com/lib/pq"
 	"github.com/pashagolub/pgxmock"
 )
 
@@ -76,3 +83,722 @@ func TestDeleteThreshold_DBError(t *testing.T) {
 		t.Errorf("Expected status 500, got %d", w.Code)
 	}
 }
+
+func TestCreateThreshold_InvalidMode(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	body := bytes.NewBufferString(`{"userId": 1, "dataId": 2, "thresholdValue": 10, "mode": "percent_weekly", "recipients": [1]}`)
+	req := httptest.NewRequest(http.MethodPost, "/thresholds", body)
+	w := httptest.NewRecorder()
+
+	handlers.CreateThreshold(w, req, mock)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+}
+
+func TestCreateThreshold_InvalidOperator(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	body := bytes.NewBufferString(`{"userId": 1, "dataId": 2, "thresholdValue": 10, "operator": "xor", "recipients": [1],
+		"conditions": [{"dataId": 3, "value": 1}]}`)
+	req := httptest.NewRequest(http.MethodPost, "/thresholds", body)
+	w := httptest.NewRecorder()
+
+	handlers.CreateThreshold(w, req, mock)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+}
+
+func TestCreateThreshold_StoresConditions(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO thresholds").
+		WithArgs(1, 2, 10.0, models.ThresholdModePercentPrevious, models.ThresholdDirectionRise, false, 0.0, 0, models.ThresholdOperatorAnd, "", (*time.Time)(nil)).
+		WillReturnRows(pgxmock.NewRows([]string{"threshold_id"}).AddRow(9))
+	mock.ExpectExec("INSERT INTO threshold_conditions").
+		WithArgs(9, 3, models.ThresholdModePercentPrevious, models.ThresholdDirectionEither, 1.0, 1).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectExec("INSERT INTO threshold_recipients").
+		WithArgs(9, 1).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectCommit()
+
+	body := bytes.NewBufferString(`{"userId": 1, "dataId": 2, "thresholdValue": 10, "direction": "rise", "recipients": [1],
+		"conditions": [{"dataId": 3, "value": 1}]}`)
+	req := httptest.NewRequest(http.MethodPost, "/thresholds", body)
+	w := httptest.NewRecorder()
+
+	handlers.CreateThreshold(w, req, mock)
+
+	if w.Code != http.StatusCreated {
+		t.Errorf("Expected status 201, got %d", w.Code)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+func TestCreateThreshold_InvalidEscalation(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	for _, escalations := range []string{
+		`[{"afterPeriods": 1, "recipients": [2]}]`,
+		`[{"afterPeriods": 3, "recipients": [2]}, {"afterPeriods": 3, "recipients": [4]}]`,
+		`[{"afterPeriods": 3}]`,
+	} {
+		body := bytes.NewBufferString(`{"userId": 1, "dataId": 2, "thresholdValue": 10, "recipients": [1], "escalations": ` + escalations + `}`)
+		req := httptest.NewRequest(http.MethodPost, "/thresholds", body)
+		w := httptest.NewRecorder()
+
+		handlers.CreateThreshold(w, req, mock)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", escalations, w.Code)
+		}
+	}
+}
+
+func TestCreateThreshold_StoresEscalations(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO thresholds").
+		WithArgs(1, 2, 10.0, models.ThresholdModePercentPrevious, models.ThresholdDirectionEither, false, 0.0, 0, models.ThresholdOperatorAnd, "", (*time.Time)(nil)).
+		WillReturnRows(pgxmock.NewRows([]string{"threshold_id"}).AddRow(9))
+	mock.ExpectExec("INSERT INTO threshold_escalations").
+		WithArgs(9, 3, pq.Array([]int64{2, 4}), "", "").
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectExec("INSERT INTO threshold_escalations").
+		WithArgs(9, 6, pq.Array([]int64(nil)), "Still waiting", "Dear [Recipient Name], ...").
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectExec("INSERT INTO threshold_recipients").
+		WithArgs(9, 1).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectCommit()
+
+	body := bytes.NewBufferString(`{"userId": 1, "dataId": 2, "thresholdValue": 10, "recipients": [1], "escalations": [
+		{"afterPeriods": 3, "recipients": [2, 4]},
+		{"afterPeriods": 6, "letterSubject": "Still waiting", "letterTemplate": "Dear [Recipient Name], ..."}]}`)
+	req := httptest.NewRequest(http.MethodPost, "/thresholds", body)
+	w := httptest.NewRecorder()
+
+	handlers.CreateThreshold(w, req, mock)
+
+	if w.Code != http.StatusCreated {
+		t.Errorf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+func TestCreateThreshold_InvalidExpression(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	body := bytes.NewBufferString(`{"userId": 1, "expression": "eggs > 4", "recipients": [1]}`)
+	req := httptest.NewRequest(http.MethodPost, "/thresholds", body)
+	w := httptest.NewRecorder()
+
+	handlers.CreateThreshold(w, req, mock)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "Invalid expression: position 1") {
+		t.Errorf("Expected the syntax error in the response, got %q", w.Body.String())
+	}
+}
+
+func TestCreateThreshold_ExpressionUsesFirstSeries(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	rule := "yoy(eggs) > 8 and latest(gasoline) > 3.5"
+	mock.ExpectQuery("FROM series_catalog s").
+		WithArgs([]string{"eggs", "gasoline"}).
+		WillReturnRows(pgxmock.NewRows([]string{"series_id", "alias", "data_id"}).
+			AddRow("APU00007471A", "gasoline", 8).
+			AddRow("APU0000708111", "eggs", 1))
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO thresholds").
+		WithArgs(1, 1, 0.0, models.ThresholdModePercentPrevious, models.ThresholdDirectionEither, false, 0.0, 0, models.ThresholdOperatorAnd, rule, (*time.Time)(nil)).
+		WillReturnRows(pgxmock.NewRows([]string{"threshold_id"}).AddRow(9))
+	mock.ExpectExec("INSERT INTO threshold_recipients").
+		WithArgs(9, 1).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectCommit()
+
+	body := bytes.NewBufferString(`{"userId": 1, "expression": "` + rule + `", "recipients": [1]}`)
+	req := httptest.NewRequest(http.MethodPost, "/thresholds", body)
+	w := httptest.NewRecorder()
+
+	handlers.CreateThreshold(w, req, mock)
+
+	if w.Code != http.StatusCreated {
+		t.Errorf("Expected status 201, got %d", w.Code)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+func TestCreateThreshold_DefaultsToPercentPrevious(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO thresholds").
+		WithArgs(1, 2, 10.0, models.ThresholdModePercentPrevious, models.ThresholdDirectionEither, true, 0.0, 0, models.ThresholdOperatorAnd, "", (*time.Time)(nil)).
+		WillReturnRows(pgxmock.NewRows([]string{"threshold_id"}).AddRow(9))
+	mock.ExpectExec("INSERT INTO threshold_recipients").
+		WithArgs(9, 1).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectCommit()
+
+	body := bytes.NewBufferString(`{"userId": 1, "dataId": 2, "thresholdValue": 10, "notifyUser": true, "recipients": [1]}`)
+	req := httptest.NewRequest(http.MethodPost, "/thresholds", body)
+	w := httptest.NewRecorder()
+
+	handlers.CreateThreshold(w, req, mock)
+
+	if w.Code != http.StatusCreated {
+		t.Errorf("Expected status 201, got %d", w.Code)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+func TestUpdateThreshold_InvalidModeValue(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	mock.ExpectQuery("SELECT mode, expression FROM thresholds").
+		WithArgs(42).
+		WillReturnRows(pgxmock.NewRows([]string{"mode", "expression"}).AddRow(models.ThresholdModePercentPrevious, ""))
+
+	body := bytes.NewBufferString(`{"thresholdValue": -3, "mode": "absolute"}`)
+	req := httptest.NewRequest(http.MethodPut, "/thresholds/42", body)
+	req = mux.SetURLVars(req, map[string]string{"id": "42"})
+	w := httptest.NewRecorder()
+
+	handlers.UpdateThreshold(w, req, mock)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+func TestUpdateThreshold_DirectionChecksStoredMode(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	// The update leaves the mode out, so "either" is checked against the
+	// stored absolute mode and rejected before anything is written.
+	mock.ExpectQuery("SELECT mode, expression FROM thresholds").
+		WithArgs(42).
+		WillReturnRows(pgxmock.NewRows([]string{"mode", "expression"}).AddRow(models.ThresholdModeAbsolute, ""))
+
+	body := bytes.NewBufferString(`{"thresholdValue": 4.5, "direction": "either"}`)
+	req := httptest.NewRequest(http.MethodPut, "/thresholds/42", body)
+	req = mux.SetURLVars(req, map[string]string{"id": "42"})
+	w := httptest.NewRecorder()
+
+	handlers.UpdateThreshold(w, req, mock)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d: %s", w.Code, w.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+func TestUpdateThreshold_ValueChecksStoredMode(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	// The update leaves the mode out, so the negative value is checked against
+	// the stored percent mode and rejected before anything is written.
+	mock.ExpectQuery("SELECT mode, expression FROM thresholds").
+		WithArgs(42).
+		WillReturnRows(pgxmock.NewRows([]string{"mode", "expression"}).AddRow(models.ThresholdModePercentPrevious, ""))
+
+	body := bytes.NewBufferString(`{"thresholdValue": -3}`)
+	req := httptest.NewRequest(http.MethodPut, "/thresholds/42", body)
+	req = mux.SetURLVars(req, map[string]string{"id": "42"})
+	w := httptest.NewRecorder()
+
+	handlers.UpdateThreshold(w, req, mock)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d: %s", w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), "positive percentage") {
+		t.Errorf("Expected the percent value message, got %q", w.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+func TestUpdateThreshold_RuleSkipsValueChecks(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	// A rule is stored with no value under the default percent mode, which
+	// would fail the value and hysteresis checks if they were run.
+	mock.ExpectQuery("SELECT mode, expression FROM thresholds").
+		WithArgs(42).
+		WillReturnRows(pgxmock.NewRows([]string{"mode", "expression"}).AddRow(models.ThresholdModePercentPrevious, "CUUR0000SA0 > 3"))
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE thresholds").
+		WithArgs(0.0, true, "", "", 0.0, 24, "", "", (*time.Time)(nil), 42, false).
+		WillReturnRows(pgxmock.NewRows([]string{"threshold_id"}).AddRow(42))
+	mock.ExpectQuery("SELECT recipient_id FROM threshold_recipients").
+		WithArgs(42).
+		WillReturnRows(pgxmock.NewRows([]string{"recipient_id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	body := bytes.NewBufferString(`{"notifyUser": true, "cooldownHours": 24, "recipients": [1]}`)
+	req := httptest.NewRequest(http.MethodPut, "/thresholds/42", body)
+	req = mux.SetURLVars(req, map[string]string{"id": "42"})
+	w := httptest.NewRecorder()
+
+	handlers.UpdateThreshold(w, req, mock)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+func TestUpdateThreshold_ClearsExpression(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	// Once the expression is cleared the threshold is an ordinary one again,
+	// so its value is checked and the update tells the database to clear it.
+	mock.ExpectQuery("SELECT mode, expression FROM thresholds").
+		WithArgs(42).
+		WillReturnRows(pgxmock.NewRows([]string{"mode", "expression"}).AddRow(models.ThresholdModePercentPrevious, "CUUR0000SA0 > 3"))
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE thresholds").
+		WithArgs(5.0, true, "", "", 0.0, 0, "", "", (*time.Time)(nil), 42, true).
+		WillReturnRows(pgxmock.NewRows([]string{"threshold_id"}).AddRow(42))
+	mock.ExpectQuery("SELECT recipient_id FROM threshold_recipients").
+		WithArgs(42).
+		WillReturnRows(pgxmock.NewRows([]string{"recipient_id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	body := bytes.NewBufferString(`{"thresholdValue": 5, "notifyUser": true, "clearExpression": true, "recipients": [1]}`)
+	req := httptest.NewRequest(http.MethodPut, "/thresholds/42", body)
+	req = mux.SetURLVars(req, map[string]string{"id": "42"})
+	w := httptest.NewRecorder()
+
+	handlers.UpdateThreshold(w, req, mock)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+func TestUpdateThreshold_RuleRejectsConditions(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	mock.ExpectQuery("SELECT mode, expression FROM thresholds").
+		WithArgs(42).
+		WillReturnRows(pgxmock.NewRows([]string{"mode", "expression"}).AddRow(models.ThresholdModePercentPrevious, "CUUR0000SA0 > 3"))
+
+	body := bytes.NewBufferString(`{"operator": "or", "conditions": [{"dataId": 3, "value": 2}], "recipients": [1]}`)
+	req := httptest.NewRequest(http.MethodPut, "/thresholds/42", body)
+	req = mux.SetURLVars(req, map[string]string{"id": "42"})
+	w := httptest.NewRecorder()
+
+	handlers.UpdateThreshold(w, req, mock)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d: %s", w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), "cannot also have conditions") {
+		t.Errorf("Expected the conditions message, got %q", w.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+func TestCreateThreshold_AbsoluteNeedsDirection(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	body := bytes.NewBufferString(`{"userId": 1, "dataId": 2, "thresholdValue": 4.5, "mode": "absolute", "direction": "either", "recipients": [1]}`)
+	req := httptest.NewRequest(http.MethodPost, "/thresholds", body)
+	w := httptest.NewRecorder()
+
+	handlers.CreateThreshold(w, req, mock)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+}
+
+func TestGetThresholdEvaluations_NotFound(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	mock.ExpectQuery("SELECT 1 FROM thresholds").
+		WithArgs(42).
+		WillReturnError(pgx.ErrNoRows)
+
+	req := httptest.NewRequest(http.MethodGet, "/thresholds/42/evaluations", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": "42"})
+	w := httptest.NewRecorder()
+
+	handlers.GetThresholdEvaluations(w, req, mock)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+}
+
+func TestGetThresholdEvaluations_DecodesInputs(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	metric := 4.76
+	mock.ExpectQuery("SELECT 1 FROM thresholds").
+		WithArgs(42).
+		WillReturnRows(pgxmock.NewRows([]string{"?column?"}).AddRow(1))
+	mock.ExpectQuery("FROM threshold_evaluations").
+		WithArgs(42, 10).
+		WillReturnRows(pgxmock.NewRows([]string{"evaluation_id", "threshold_id", "evaluated_at", "inputs", "metric", "comparison", "triggered",
+			"previous_state", "state", "notified", "explanation", "error", "breach_periods", "tier", "escalated"}).
+			AddRow(7, 42, time.Now(), []byte(`[{"name":"Eggs, Grade A, Large","series_id":"APU0000708111","latest":5.5,"previous":5.25,"observed":4.76,"comparison":"|4.76%| >= 10.00%","met":false}]`),
+				&metric, "|4.76%| >= 10.00%", false, models.ThresholdStateArmed, models.ThresholdStateArmed, false,
+				"The threshold is not met, so it did not fire.", "", 0, 0, false))
+
+	req := httptest.NewRequest(http.MethodGet, "/thresholds/42/evaluations?limit=10", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": "42"})
+	w := httptest.NewRecorder()
+
+	handlers.GetThresholdEvaluations(w, req, mock)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var evaluations []models.ThresholdEvaluation
+	if err := json.NewDecoder(w.Body).Decode(&evaluations); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(evaluations) != 1 || len(evaluations[0].Inputs) != 1 || *evaluations[0].Inputs[0].Previous != 5.25 {
+		t.Errorf("Expected one evaluation with its inputs, got %+v", evaluations)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+func TestGetThresholdEvaluations_InvalidLimit(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	req := httptest.NewRequest(http.MethodGet, "/thresholds/42/evaluations?limit=1000", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": "42"})
+	w := httptest.NewRecorder()
+
+	handlers.GetThresholdEvaluations(w, req, mock)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+}
+
+func TestBacktestThreshold_InvalidDateRange(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	body := `{"dataId": 1, "thresholdValue": 10, "from": "2024-06-01", "to": "2024-01-01"}`
+	req := httptest.NewRequest(http.MethodPost, "/thresholds/backtest", strings.NewReader(body))
+	w := httptest.NewRecorder()
+
+	handlers.BacktestThreshold(w, req, mock)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+}
+
+func TestBacktestThreshold_ReturnsFirings(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	mock.ExpectQuery("FROM data WHERE data_id = ANY").
+		WithArgs([]int{1}).
+		WillReturnRows(pgxmock.NewRows([]string{"data_id", "series_id", "name"}).AddRow(1, "APU0000708111", "Eggs, Grade A, Large"))
+	mock.ExpectQuery("FROM data_observations").
+		WithArgs([]string{"APU0000708111"}).
+		WillReturnRows(pgxmock.NewRows([]string{"series_id", "year", "period", "value"}).
+			AddRow("APU0000708111", "2024", "M01", 4.00).
+			AddRow("APU0000708111", "2024", "M02", 4.50))
+
+	body := `{"dataId": 1, "thresholdValue": 10, "direction": "rise", "from": "2024-01-01", "to": "2024-12-31"}`
+	req := httptest.NewRequest(http.MethodPost, "/thresholds/backtest", strings.NewReader(body))
+	w := httptest.NewRecorder()
+
+	handlers.BacktestThreshold(w, req, mock)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var backtest models.ThresholdBacktest
+	if err := json.NewDecoder(w.Body).Decode(&backtest); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if backtest.Skipped != 1 || len(backtest.Firings) != 1 || backtest.Firings[0].Comparison != "12.50% >= 10.00%" {
+		t.Errorf("Expected January skipped and a firing in February, got %+v", backtest)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+func TestPreviewThreshold_NotFound(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	mock.ExpectQuery("FROM thresholds").
+		WithArgs(42).
+		WillReturnError(pgx.ErrNoRows)
+
+	req := httptest.NewRequest(http.MethodPost, "/thresholds/42/preview", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": "42"})
+	w := httptest.NewRecorder()
+
+	handlers.PreviewThreshold(w, req, mock)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+}
+
+func TestCreateThreshold_ExpiryInThePast(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	body := `{"userId": 1, "dataId": 2, "thresholdValue": 10, "recipients": [1], "expiresAt": "2020-01-01T00:00:00Z"}`
+	req := httptest.NewRequest(http.MethodPost, "/thresholds", strings.NewReader(body))
+	w := httptest.NewRecorder()
+
+	handlers.CreateThreshold(w, req, mock)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+}
+
+func TestSnoozeThreshold_InvalidHours(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/thresholds/42/snooze", strings.NewReader(`{"hours": 0}`))
+	req = mux.SetURLVars(req, map[string]string{"id": "42"})
+	w := httptest.NewRecorder()
+
+	handlers.SnoozeThreshold(w, req, mock)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+}
+
+func TestSnoozeThreshold_SetsPausedUntil(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	pausedUntil := time.Date(2025, time.March, 2, 12, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SET paused_until = NOW()").
+		WithArgs(24, 42).
+		WillReturnRows(pgxmock.NewRows([]string{"paused_until"}).AddRow(pausedUntil))
+
+	req := httptest.NewRequest(http.MethodPost, "/thresholds/42/snooze", strings.NewReader(`{"hours": 24}`))
+	req = mux.SetURLVars(req, map[string]string{"id": "42"})
+	w := httptest.NewRecorder()
+
+	handlers.SnoozeThreshold(w, req, mock)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), `"paused_until":"2025-03-02T12:00:00Z"`) {
+		t.Errorf("Expected the end of the pause in the response, got %s", w.Body.String())
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+func TestSnoozeThreshold_NotFound(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	mock.ExpectQuery("SET paused_until = NOW()").
+		WithArgs(1, 42).
+		WillReturnError(pgx.ErrNoRows)
+
+	req := httptest.NewRequest(http.MethodPost, "/thresholds/42/snooze", strings.NewReader(`{"hours": 1}`))
+	req = mux.SetURLVars(req, map[string]string{"id": "42"})
+	w := httptest.NewRecorder()
+
+	handlers.SnoozeThreshold(w, req, mock)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+}
+
+func TestResumeThreshold_ClearsPause(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	mock.ExpectExec("SET paused_until = NULL").
+		WithArgs(42).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+	req := httptest.NewRequest(http.MethodPost, "/thresholds/42/resume", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": "42"})
+	w := httptest.NewRecorder()
+
+	handlers.ResumeThreshold(w, req, mock)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+func TestResumeThreshold_NotFound(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	mock.ExpectExec("SET paused_until = NULL").
+		WithArgs(42).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+	req := httptest.NewRequest(http.MethodPost, "/thresholds/42/resume", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": "42"})
+	w := httptest.NewRecorder()
+
+	handlers.ResumeThreshold(w, req, mock)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+}
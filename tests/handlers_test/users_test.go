@@ -9,6 +9,7 @@ import (
 	"net/http/httptest"
 	"reflect"
 	"regexp"
+	"strings"
 	"testing"
 
 	"megga-backend/handlers"
@@ -207,17 +208,22 @@ func TestGetThresholdsForUser_Success(t *testing.T) {
 	defer mock.Close()
 
 	mock.ExpectQuery(regexp.QuoteMeta(`
-    SELECT t.threshold_id, t.data_id, d.name, t.threshold_value, t.notify_user,
-       	COALESCE(ARRAY_AGG(tr.recipient_id) FILTER (WHERE tr.recipient_id IS NOT NULL), ARRAY[]::BIGINT[]) AS recipients
+    SELECT t.threshold_id, t.data_id, d.name, t.threshold_value, t.mode, t.direction, t.state, t.notify_user,
+       	COALESCE(ARRAY_AGG(tr.recipient_id) FILTER (WHERE tr.recipient_id IS NOT NULL), ARRAY[]::BIGINT[]) AS recipients,
+       	t.operator, t.expression, t.paused_until, t.expires_at
 	FROM thresholds t
 	JOIN data d ON t.data_id = d.data_id
 	LEFT JOIN threshold_recipients tr ON t.threshold_id = tr.threshold_id
 	WHERE t.user_id = $1
 	GROUP BY t.threshold_id, d.name`)).
 		WithArgs(1).
-		WillReturnRows(pgxmock.NewRows([]string{"threshold_id", "data_id", "name", "threshold_value", "notify_user", "recipients"}).
-			AddRow(101, 1, "Eggs", 10.0, true, []int64{1, 2}).
-			AddRow(102, 2, "Milk", 15.5, false, []int64{3}))
+		WillReturnRows(pgxmock.NewRows([]string{"threshold_id", "data_id", "name", "threshold_value", "mode", "direction", "state", "notify_user", "recipients", "operator", "expression", "paused_until", "expires_at"}).
+			AddRow(101, 1, "Eggs", 10.0, "percent_previous", "either", "armed", true, []int64{1, 2}, "and", "", nil, nil).
+			AddRow(102, 2, "Milk", 15.5, "absolute", "rise", "triggered", false, []int64{3}, "or", "", nil, nil))
+	mock.ExpectQuery("FROM threshold_conditions c").
+		WithArgs([]int{101, 102}).
+		WillReturnRows(pgxmock.NewRows([]string{"condition_id", "threshold_id", "data_id", "name", "mode", "direction", "value", "baseline_value"}).
+			AddRow(1, 102, 3, "Median Weekly Earnings", "percent_previous", "fall", 1.0, nil))
 
 	req := httptest.NewRequest("GET", "/users/1/thresholds", nil)
 	w := httptest.NewRecorder()
@@ -227,6 +233,9 @@ func TestGetThresholdsForUser_Success(t *testing.T) {
 	if w.Code != http.StatusOK {
 		t.Errorf("Expected status 200, got %d", w.Code)
 	}
+	if !strings.Contains(w.Body.String(), `"name":"Median Weekly Earnings"`) {
+		t.Errorf("Expected the compound threshold's conditions in the response, got %s", w.Body.String())
+	}
 }
 
 func TestGetThresholdsForUser_NoThresholds(t *testing.T) {
@@ -237,8 +246,9 @@ func TestGetThresholdsForUser_NoThresholds(t *testing.T) {
 	defer mock.Close()
 
 	mock.ExpectQuery(regexp.QuoteMeta(`
-    SELECT t.threshold_id, t.data_id, d.name, t.threshold_value, t.notify_user,
-       	COALESCE(ARRAY_AGG(tr.recipient_id) FILTER (WHERE tr.recipient_id IS NOT NULL), ARRAY[]::BIGINT[]) AS recipients
+    SELECT t.threshold_id, t.data_id, d.name, t.threshold_value, t.mode, t.direction, t.state, t.notify_user,
+       	COALESCE(ARRAY_AGG(tr.recipient_id) FILTER (WHERE tr.recipient_id IS NOT NULL), ARRAY[]::BIGINT[]) AS recipients,
+       	t.operator, t.expression, t.paused_until, t.expires_at
 	FROM thresholds t
 	JOIN data d ON t.data_id = d.data_id
 	LEFT JOIN threshold_recipients tr ON t.threshold_id = tr.threshold_id
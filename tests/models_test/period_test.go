@@ -0,0 +1,80 @@
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	"megga-backend/internal/models"
+)
+
+func TestParsePeriod(t *testing.T) {
+	tests := []struct {
+		name          string
+		year          string
+		period        string
+		expectedDate  time.Time
+		expectedLabel string
+	}{
+		{"Monthly", "2024", "M12", time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC), "December 2024"},
+		{"Quarterly", "2024", "Q03", time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC), "Q3 2024"},
+		{"Semiannual", "2024", "S02", time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC), "H2 2024"},
+		{"Annual", "2024", "A01", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), "2024"},
+		{"Annual Average", "2024", "M13", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), "2024"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			period, err := models.ParsePeriod(tt.year, tt.period)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if !period.Date().Equal(tt.expectedDate) {
+				t.Errorf("Expected date %v, got %v", tt.expectedDate, period.Date())
+			}
+			if period.Label() != tt.expectedLabel {
+				t.Errorf("Expected label %q, got %q", tt.expectedLabel, period.Label())
+			}
+		})
+	}
+}
+
+func TestParsePeriod_Invalid(t *testing.T) {
+	for _, input := range [][2]string{{"2024", "M14"}, {"2024", "Q06"}, {"2024", "X01"}, {"", "M01"}, {"2024", ""}} {
+		if _, err := models.ParsePeriod(input[0], input[1]); err == nil {
+			t.Errorf("Expected error for %v, got nil", input)
+		}
+	}
+}
+
+func TestPeriod_Ordering(t *testing.T) {
+	november, _ := models.ParsePeriod("2024", "M11")
+	december, _ := models.ParsePeriod("2024", "M12")
+	january, _ := models.ParsePeriod("2025", "M01")
+	fourthQuarter, _ := models.ParsePeriod("2024", "Q04")
+
+	if !november.Before(december) || !december.Before(january) {
+		t.Errorf("Expected months to be ordered chronologically")
+	}
+	if !december.Before(fourthQuarter) || !november.Before(fourthQuarter) {
+		t.Errorf("Expected a quarter to sort after the months it covers")
+	}
+	if january.Previous() != december {
+		t.Errorf("Expected the period before January 2025 to be December 2024, got %s", january.Previous().Label())
+	}
+	if january.YearAgo().Label() != "January 2024" {
+		t.Errorf("Expected a year before January 2025 to be January 2024, got %s", january.YearAgo().Label())
+	}
+}
+
+func TestIsAnnualAverage(t *testing.T) {
+	for _, code := range []string{"M13", "Q05", "S03"} {
+		if !models.IsAnnualAverage(code) {
+			t.Errorf("Expected %s to be an annual average", code)
+		}
+	}
+	for _, code := range []string{"M12", "Q04", "A01"} {
+		if models.IsAnnualAverage(code) {
+			t.Errorf("Expected %s not to be an annual average", code)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package scheduler_test
+
+import (
+	"testing"
+	"time"
+
+	"megga-backend/internal/scheduler"
+)
+
+func TestParseSchedule_Invalid(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "*/0 * * * *", "a * * * *"} {
+		if _, err := scheduler.ParseSchedule(expr); err == nil {
+			t.Errorf("Expected error for %q, got nil", expr)
+		}
+	}
+}
+
+func TestSchedule_Next(t *testing.T) {
+	eastern, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("Failed to load time zone: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		expr     string
+		after    time.Time
+		expected time.Time
+	}{
+		{"Later Today", "30 8 * * 1-5", time.Date(2025, 1, 14, 7, 0, 0, 0, eastern), time.Date(2025, 1, 14, 8, 30, 0, 0, eastern)},
+		{"Skips Weekend", "30 8 * * 1-5", time.Date(2025, 1, 17, 9, 0, 0, 0, eastern), time.Date(2025, 1, 20, 8, 30, 0, 0, eastern)},
+		{"Strictly After", "30 8 * * *", time.Date(2025, 1, 14, 8, 30, 0, 0, eastern), time.Date(2025, 1, 15, 8, 30, 0, 0, eastern)},
+		{"Step", "*/15 * * * *", time.Date(2025, 1, 14, 8, 31, 0, 0, eastern), time.Date(2025, 1, 14, 8, 45, 0, 0, eastern)},
+		{"Month Rollover", "0 0 1 * *", time.Date(2024, 12, 15, 0, 0, 0, 0, eastern), time.Date(2025, 1, 1, 0, 0, 0, 0, eastern)},
+		{"Day Or Weekday", "0 9 1 * 0", time.Date(2025, 1, 2, 0, 0, 0, 0, eastern), time.Date(2025, 1, 5, 9, 0, 0, 0, eastern)},
+		{"Sunday As Seven", "0 9 * * 7", time.Date(2025, 1, 2, 0, 0, 0, 0, eastern), time.Date(2025, 1, 5, 9, 0, 0, 0, eastern)},
+		{"Across DST", "30 8 * * *", time.Date(2025, 3, 8, 9, 0, 0, 0, eastern), time.Date(2025, 3, 9, 8, 30, 0, 0, eastern)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := scheduler.ParseSchedule(tt.expr)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			next := schedule.Next(tt.after)
+			if !next.Equal(tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, next)
+			}
+		})
+	}
+}
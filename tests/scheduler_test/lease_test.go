@@ -0,0 +1,187 @@
+package scheduler_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"megga-backend/internal/models"
+	"megga-backend/internal/scheduler"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/pashagolub/pgxmock"
+)
+
+func TestElector_AcquireAndLose(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	elector := scheduler.NewElector(mock, "background-jobs", 30*time.Second)
+	elected := 0
+	elector.OnElected = func(ctx context.Context) { elected++ }
+
+	mock.ExpectQuery("INSERT INTO leases").
+		WithArgs("background-jobs", elector.Holder, int64(30000)).
+		WillReturnRows(pgxmock.NewRows([]string{"holder"}).AddRow(elector.Holder))
+	mock.ExpectQuery("INSERT INTO leases").
+		WithArgs("background-jobs", elector.Holder, int64(30000)).
+		WillReturnRows(pgxmock.NewRows([]string{"holder"}).AddRow(elector.Holder))
+	mock.ExpectQuery("INSERT INTO leases").
+		WithArgs("background-jobs", elector.Holder, int64(30000)).
+		WillReturnError(pgx.ErrNoRows)
+
+	for i, expected := range []bool{true, true, false} {
+		acquired, err := elector.TryAcquire(context.Background())
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if acquired != expected || elector.IsLeader() != expected {
+			t.Errorf("Attempt %d: expected leader=%v, got %v", i+1, expected, acquired)
+		}
+	}
+
+	if elected != 1 {
+		t.Errorf("Expected OnElected to run once, ran %d times", elected)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+func TestElector_DatabaseErrorDropsLeadership(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	elector := scheduler.NewElector(mock, "background-jobs", 30*time.Second)
+
+	mock.ExpectQuery("INSERT INTO leases").
+		WillReturnRows(pgxmock.NewRows([]string{"holder"}).AddRow(elector.Holder))
+	mock.ExpectQuery("INSERT INTO leases").
+		WillReturnError(errors.New("connection reset"))
+
+	elector.TryAcquire(context.Background())
+	if _, err := elector.TryAcquire(context.Background()); err == nil {
+		t.Errorf("Expected error, got nil")
+	}
+	if elector.IsLeader() {
+		t.Errorf("Expected leadership to be dropped when the lease cannot be renewed")
+	}
+}
+
+type staticLeader bool
+
+func (l staticLeader) IsLeader() bool {
+	return bool(l)
+}
+
+func TestScheduler_FollowerSkipsCatchUp(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	jobs := scheduler.New(mock, time.UTC)
+	jobs.Leader = staticLeader(false)
+	jobs.Register("ingest", "30 8 * * *", func(ctx context.Context) error {
+		t.Errorf("Expected follower not to run the job")
+		return nil
+	})
+
+	jobs.CatchUp(context.Background())
+	jobs.Wait()
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+func TestScheduler_FollowerRefusesManualRun(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	jobs := scheduler.New(mock, time.UTC)
+	jobs.Leader = staticLeader(false)
+	jobs.Register("ingest", "30 8 * * *", func(ctx context.Context) error {
+		t.Errorf("Expected follower not to run the job")
+		return nil
+	})
+
+	if _, err := jobs.RunNow(context.Background(), "ingest"); !errors.Is(err, scheduler.ErrNotLeader) {
+		t.Errorf("Expected ErrNotLeader, got %v", err)
+	}
+	jobs.Wait()
+
+	// Recording a run would be an unexpected call.
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+// switchLeader is a leader that can be demoted while a run is under way.
+type switchLeader struct {
+	leader atomic.Bool
+}
+
+func (l *switchLeader) IsLeader() bool {
+	return l.leader.Load()
+}
+
+func TestScheduler_RunStopsWhenLeadershipIsLost(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	now := time.Date(2025, 1, 16, 10, 0, 0, 0, time.UTC)
+	leader := &switchLeader{}
+	leader.leader.Store(true)
+
+	jobs := scheduler.New(mock, time.UTC)
+	jobs.Now = func() time.Time { return now }
+	jobs.Leader = leader
+	jobs.LeaderCheckInterval = time.Millisecond
+
+	started := make(chan struct{})
+	var cause error
+	jobs.Register("ingest", "30 8 * * *", func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		cause = context.Cause(ctx)
+		return ctx.Err()
+	})
+
+	mock.ExpectQuery("INSERT INTO job_runs").
+		WithArgs("ingest", models.JobTriggerManual, now, models.JobRunning).
+		WillReturnRows(pgxmock.NewRows([]string{"job_run_id"}).AddRow(5))
+	mock.ExpectExec("UPDATE job_runs").
+		WithArgs(models.JobFailed, "stopped after losing leadership: context canceled", 5).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+	if _, err := jobs.RunNow(context.Background(), "ingest"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	<-started
+	leader.leader.Store(false)
+	jobs.Wait()
+
+	if !errors.Is(cause, scheduler.ErrNotLeader) {
+		t.Errorf("Expected the run to be cancelled for losing leadership, got %v", cause)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
@@ -0,0 +1,147 @@
+package scheduler_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"megga-backend/internal/models"
+	"megga-backend/internal/scheduler"
+
+	"github.com/pashagolub/pgxmock"
+)
+
+func TestScheduler_CatchUpRunsMissedSlotOnce(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	eastern, _ := time.LoadLocation("America/New_York")
+	jobs := scheduler.New(mock, eastern)
+	jobs.Now = func() time.Time { return time.Date(2025, 1, 16, 10, 0, 0, 0, eastern) }
+
+	runs := 0
+	if err := jobs.Register("ingest", "30 8 * * *", func(ctx context.Context) error {
+		runs++
+		return errors.New("upstream unavailable")
+	}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	lastRun := time.Date(2025, 1, 13, 8, 30, 0, 0, eastern).UTC()
+	missed := time.Date(2025, 1, 16, 8, 30, 0, 0, eastern).UTC()
+
+	mock.ExpectQuery("SELECT scheduled_for FROM job_runs").
+		WithArgs("ingest").
+		WillReturnRows(pgxmock.NewRows([]string{"scheduled_for"}).AddRow(lastRun))
+	mock.ExpectQuery("INSERT INTO job_runs").
+		WithArgs("ingest", models.JobTriggerCatchUp, missed, models.JobRunning).
+		WillReturnRows(pgxmock.NewRows([]string{"job_run_id"}).AddRow(1))
+	mock.ExpectExec("UPDATE job_runs").
+		WithArgs(models.JobFailed, "upstream unavailable", 1).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+	jobs.CatchUp(context.Background())
+	jobs.Wait()
+
+	if runs != 1 {
+		t.Errorf("Expected the job to run once, ran %d times", runs)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+func TestScheduler_CatchUpSkipsUpToDateJobs(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	eastern, _ := time.LoadLocation("America/New_York")
+	jobs := scheduler.New(mock, eastern)
+	jobs.Now = func() time.Time { return time.Date(2025, 1, 16, 10, 0, 0, 0, eastern) }
+
+	runs := 0
+	jobs.Register("ingest", "30 8 * * *", func(ctx context.Context) error {
+		runs++
+		return nil
+	})
+
+	mock.ExpectQuery("SELECT scheduled_for FROM job_runs").
+		WithArgs("ingest").
+		WillReturnRows(pgxmock.NewRows([]string{"scheduled_for"}).AddRow(time.Date(2025, 1, 16, 8, 30, 0, 0, eastern).UTC()))
+
+	jobs.CatchUp(context.Background())
+	jobs.Wait()
+
+	if runs != 0 {
+		t.Errorf("Expected no catch-up run, ran %d times", runs)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+func TestScheduler_RunNowRecordsManualRun(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	now := time.Date(2025, 1, 16, 10, 0, 0, 0, time.UTC)
+	jobs := scheduler.New(mock, time.UTC)
+	jobs.Now = func() time.Time { return now }
+
+	release := make(chan struct{})
+	seenJobRunID := 0
+	jobs.Register("ingest", "30 8 * * *", func(ctx context.Context) error {
+		seenJobRunID, _ = scheduler.JobRunID(ctx)
+		<-release
+		return nil
+	})
+
+	mock.ExpectQuery("INSERT INTO job_runs").
+		WithArgs("ingest", models.JobTriggerManual, now, models.JobRunning).
+		WillReturnRows(pgxmock.NewRows([]string{"job_run_id"}).AddRow(5))
+	mock.ExpectExec("UPDATE job_runs").
+		WithArgs(models.JobSucceeded, "", 5).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+	jobRunID, err := jobs.RunNow(context.Background(), "ingest")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if jobRunID != 5 {
+		t.Errorf("Expected job run 5, got %d", jobRunID)
+	}
+
+	if _, err := jobs.RunNow(context.Background(), "ingest"); !errors.Is(err, scheduler.ErrJobRunning) {
+		t.Errorf("Expected ErrJobRunning while the job runs, got %v", err)
+	}
+
+	close(release)
+	jobs.Wait()
+
+	if seenJobRunID != 5 {
+		t.Errorf("Expected the job to see job run 5, got %d", seenJobRunID)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+func TestScheduler_RegisterRejectsInvalidSchedule(t *testing.T) {
+	jobs := scheduler.New(nil, time.UTC)
+	if err := jobs.Register("ingest", "every day", func(ctx context.Context) error { return nil }); err == nil {
+		t.Errorf("Expected error for invalid schedule, got nil")
+	}
+}
@@ -1,8 +1,14 @@
 package services_test
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
+	"megga-backend/internal/models"
 	"megga-backend/internal/services"
 
 	"github.com/pashagolub/pgxmock"
@@ -15,7 +21,7 @@ func TestFetchBLSData_Timeout(t *testing.T) {
 	}
 	defer mock.Close()
 
-	err = services.FetchLatestBLSData(mock)
+	_, err = services.FetchLatestBLSData(context.Background(), mock)
 	if err == nil {
 		t.Errorf("Expected timeout error, got nil")
 	}
@@ -30,3 +36,139 @@ func TestParseBLSResponse_MalformedJSON(t *testing.T) {
 	}
 }
 
+func TestParseBLSObservations_AllPeriods(t *testing.T) {
+	body := `{"status": "REQUEST_SUCCEEDED", "Results": {"series": [
+		{"seriesID": "APU0000708111", "data": [
+			{"year": "2024", "period": "M12", "value": "4.146"},
+			{"year": "2024", "period": "M11", "value": "3.650"},
+			{"year": "2024", "period": "M10", "value": "-"}
+		]},
+		{"seriesID": "APU0000702111", "data": []}
+	]}}`
+
+	observations, err := services.ParseBLSObservations([]byte(body))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	eggs := observations["APU0000708111"]
+	if len(eggs) != 2 {
+		t.Fatalf("Expected 2 parsable observations, got %d", len(eggs))
+	}
+	if eggs[0].Period != "M12" || eggs[0].Value != 4.146 {
+		t.Errorf("Expected latest observation first, got %+v", eggs[0])
+	}
+	if _, exists := observations["APU0000702111"]; exists {
+		t.Errorf("Expected series without data to be omitted")
+	}
+}
+
+func TestParseBLSResult_PerSeriesOutcomes(t *testing.T) {
+	body := `{"status": "REQUEST_SUCCEEDED", "message": [
+		"No Data Available for Series APU0000702111 Year: 2024",
+		"Series does not exist for Series APUBOGUS"
+	], "Results": {"series": [
+		{"seriesID": "APU0000708111", "data": [{"year": "2024", "period": "M12", "value": "4.146"}]},
+		{"seriesID": "APU0000702111", "data": []}
+	]}}`
+
+	result, err := services.ParseBLSResult([]byte(body), []string{"APU0000708111", "APU0000702111", "APUBOGUS"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := map[string]string{
+		"APU0000708111": models.OutcomeOK,
+		"APU0000702111": models.OutcomeNoData,
+		"APUBOGUS":      models.OutcomeInvalidSeries,
+	}
+	for _, outcome := range result.Outcomes {
+		if outcome.Outcome != expected[outcome.SeriesID] {
+			t.Errorf("Expected %s to be %s, got %s", outcome.SeriesID, expected[outcome.SeriesID], outcome.Outcome)
+		}
+	}
+	if len(result.Observations) != 1 {
+		t.Errorf("Expected 1 observation, got %d", len(result.Observations))
+	}
+}
+
+func TestParseBLSObservations_PreliminaryFootnotes(t *testing.T) {
+	body := `{"status": "REQUEST_SUCCEEDED", "Results": {"series": [
+		{"seriesID": "APU0000708111", "data": [
+			{"year": "2024", "period": "M12", "value": "4.146", "footnotes": [{"code": "P", "text": "preliminary"}]},
+			{"year": "2024", "period": "M11", "value": "3.650", "footnotes": [{}]}
+		]}
+	]}}`
+
+	observations, err := services.ParseBLSObservations([]byte(body))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	eggs := observations["APU0000708111"]
+	if len(eggs) != 2 {
+		t.Fatalf("Expected 2 observations, got %d", len(eggs))
+	}
+	if !eggs[0].Preliminary || eggs[0].Footnotes != "preliminary" {
+		t.Errorf("Expected December to be preliminary, got %+v", eggs[0])
+	}
+	if eggs[1].Preliminary || eggs[1].Footnotes != "" {
+		t.Errorf("Expected November to be final, got %+v", eggs[1])
+	}
+}
+
+func TestParseBLSResult_Throttled(t *testing.T) {
+	body := `{"status": "REQUEST_NOT_PROCESSED", "message": [
+		"Request could not be serviced, as the daily threshold for total number of requests allocated to the user has been reached."
+	], "Results": {}}`
+
+	result, err := services.ParseBLSResult([]byte(body), []string{"APU0000708111"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(result.Outcomes) != 1 || result.Outcomes[0].Outcome != models.OutcomeThrottled {
+		t.Errorf("Expected series to be reported as throttled, got %+v", result.Outcomes)
+	}
+}
+
+func TestBackfillBLSData_InvalidRange(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	if err := services.BackfillBLSData(mock, 2024, 2020); err == nil {
+		t.Errorf("Expected error for reversed year range, got nil")
+	}
+}
+
+func TestBLSProvider_BatchesSeriesRequests(t *testing.T) {
+	var batchSizes []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			SeriesID []string `json:"seriesid"`
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		batchSizes = append(batchSizes, len(payload.SeriesID))
+		w.Write([]byte(`{"status": "REQUEST_SUCCEEDED", "Results": {"series": []}}`))
+	}))
+	defer server.Close()
+
+	seriesIDs := make([]string, 120)
+	for i := range seriesIDs {
+		seriesIDs[i] = fmt.Sprintf("SERIES%03d", i)
+	}
+
+	provider := &services.BLSProvider{URL: server.URL, Client: server.Client()}
+	if _, err := provider.Fetch(context.Background(), seriesIDs); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(batchSizes) != 3 || batchSizes[0] != 50 || batchSizes[2] != 20 {
+		t.Errorf("Expected batches of 50, 50 and 20 series, got %v", batchSizes)
+	}
+	if provider.RequestsNeeded(120) != 3 {
+		t.Errorf("Expected 3 requests for 120 series, got %d", provider.RequestsNeeded(120))
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"regexp"
 	"testing"
+	"time"
 
+	"megga-backend/internal/models"
 	"megga-backend/internal/services"
 
 	"github.com/pashagolub/pgxmock"
@@ -204,3 +206,145 @@ func TestSaveBLSData_UnexpectedAPIData(t *testing.T) {
 		t.Errorf("Expected an error for unknown series, got nil")
 	}
 }
+
+func TestSaveObservations_InsertsNewPeriods(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	observations := []models.DataObservation{
+		{SeriesID: "APU0000708111", Year: "2024", Period: "M12", Value: 4.146, Preliminary: true, Footnotes: "preliminary"},
+		{SeriesID: "APU0000708111", Year: "2024", Period: "M11", Value: 3.65},
+	}
+
+	stored := 3.65
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO data_observations").
+		WithArgs("APU0000708111", "2024", "M12", 4.15, true, "preliminary").
+		WillReturnRows(pgxmock.NewRows([]string{"value", "exists"}).AddRow(nil, true))
+	mock.ExpectQuery("INSERT INTO data_observations").
+		WithArgs("APU0000708111", "2024", "M11", 3.65, false, "").
+		WillReturnRows(pgxmock.NewRows([]string{"value", "exists"}).AddRow(&stored, false))
+	mock.ExpectCommit()
+
+	inserted, revisions, err := services.SaveObservations(mock, observations)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if inserted != 1 {
+		t.Errorf("Expected 1 new observation, got %d", inserted)
+	}
+	if len(revisions) != 0 {
+		t.Errorf("Expected no revisions, got %+v", revisions)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+func TestSaveObservations_RecordsRevisions(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	observations := []models.DataObservation{
+		{SeriesID: "APU0000708111", Year: "2024", Period: "M11", Value: 3.71},
+	}
+
+	previous := 3.65
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO data_observations").
+		WithArgs("APU0000708111", "2024", "M11", 3.71, false, "").
+		WillReturnRows(pgxmock.NewRows([]string{"value", "exists"}).AddRow(&previous, true))
+	mock.ExpectQuery("INSERT INTO data_revisions").
+		WithArgs("APU0000708111", "2024", "M11", 3.65, 3.71, false).
+		WillReturnRows(pgxmock.NewRows([]string{"revision_id", "detected_at"}).AddRow(3, time.Now()))
+	mock.ExpectCommit()
+
+	inserted, revisions, err := services.SaveObservations(mock, observations)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if inserted != 0 {
+		t.Errorf("Expected no new observations, got %d", inserted)
+	}
+	if len(revisions) != 1 || revisions[0].RevisionID != 3 || revisions[0].PreviousValue != 3.65 || revisions[0].RevisedValue != 3.71 {
+		t.Errorf("Expected one revision from 3.65 to 3.71, got %+v", revisions)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+func TestSaveBLSData_RevisesLatestPeriod(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	blsData := map[string]struct {
+		Value  float64
+		Year   string
+		Period string
+	}{
+		"APU0000708111": {Value: 4.21, Year: "2024", Period: "M12"},
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT data_id, latest_value, previous_value, year, period FROM data WHERE series_id = $1`)).
+		WithArgs("APU0000708111").
+		WillReturnRows(pgxmock.NewRows([]string{"data_id", "latest_value", "previous_value", "year", "period"}).
+			AddRow(1, 4.15, 3.65, "2024", "M12"))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE data SET latest_value = $1, last_updated = NOW() WHERE data_id = $2`)).
+		WithArgs(4.21, 1).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectCommit()
+
+	err = services.SaveBLSData(mock, blsData)
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+func TestSaveBLSData_IgnoreOlderPeriod(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	blsData := map[string]struct {
+		Value  float64
+		Year   string
+		Period string
+	}{
+		"APU0000708111": {Value: 3.65, Year: "2024", Period: "M11"},
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT data_id, latest_value, previous_value, year, period FROM data WHERE series_id = $1`)).
+		WithArgs("APU0000708111").
+		WillReturnRows(pgxmock.NewRows([]string{"data_id", "latest_value", "previous_value", "year", "period"}).
+			AddRow(1, 4.15, 3.65, "2024", "M12"))
+	mock.ExpectRollback()
+
+	err = services.SaveBLSData(mock, blsData)
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
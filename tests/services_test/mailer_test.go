@@ -0,0 +1,235 @@
+package services_test
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"mime/quotedprintable"
+	"net"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"megga-backend/internal/config"
+	"megga-backend/internal/models"
+	"megga-backend/internal/services"
+)
+
+var letter = models.RenderedEmail{
+	Kind:    models.EmailKindRecipient,
+	To:      "rep@example.com",
+	Subject: "Urgent: Eggs, Grade A, Large Economic Data Alert",
+	Body:    "Dear Pat Doe,\n\nEggs cost 10.50% more than last month – please act.\n",
+}
+
+// smtpSink accepts one SMTP session and hands back what it was sent.
+type smtpSink struct {
+	addr     string
+	from     string
+	to       []string
+	data     string
+	finished chan error
+}
+
+func startSMTPSink(t *testing.T) *smtpSink {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to start SMTP sink: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	sink := &smtpSink{addr: listener.Addr().String(), finished: make(chan error, 1)}
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			sink.finished <- err
+			return
+		}
+		defer conn.Close()
+		sink.finished <- sink.serve(textproto.NewConn(conn))
+	}()
+	return sink
+}
+
+func (s *smtpSink) serve(conn *textproto.Conn) error {
+	if err := conn.PrintfLine("220 sink ready"); err != nil {
+		return err
+	}
+	for {
+		line, err := conn.ReadLine()
+		if err != nil {
+			return err
+		}
+		command := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch command {
+		case "EHLO", "HELO":
+			err = conn.PrintfLine("250 sink")
+		case "MAIL":
+			s.from = line
+			err = conn.PrintfLine("250 OK")
+		case "RCPT":
+			s.to = append(s.to, line)
+			err = conn.PrintfLine("250 OK")
+		case "DATA":
+			if err = conn.PrintfLine("354 Go ahead"); err != nil {
+				return err
+			}
+			data, err := io.ReadAll(conn.DotReader())
+			if err != nil {
+				return err
+			}
+			s.data = string(data)
+			err = conn.PrintfLine("250 Queued")
+		case "QUIT":
+			return conn.PrintfLine("221 Bye")
+		default:
+			err = conn.PrintfLine("502 Not implemented")
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// parseMessage splits a message into its headers and decoded body.
+func parseMessage(t *testing.T, message string) (textproto.MIMEHeader, string) {
+	t.Helper()
+	reader := textproto.NewReader(bufio.NewReader(strings.NewReader(message)))
+	header, err := reader.ReadMIMEHeader()
+	if err != nil {
+		t.Fatalf("Failed to read message headers: %v", err)
+	}
+	body, err := io.ReadAll(quotedprintable.NewReader(reader.R))
+	if err != nil {
+		t.Fatalf("Failed to decode message body: %v", err)
+	}
+	return header, strings.ReplaceAll(string(body), "\r\n", "\n")
+}
+
+func TestSMTPMailer_SendsToSink(t *testing.T) {
+	sink := startSMTPSink(t)
+	mailer := services.SMTPMailer{Addr: sink.addr, Host: "127.0.0.1", From: "alerts@megga.example"}
+
+	if err := mailer.Send(letter); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := <-sink.finished; err != nil {
+		t.Fatalf("SMTP session failed: %v", err)
+	}
+
+	if sink.from != "MAIL FROM:<alerts@megga.example>" || len(sink.to) != 1 || sink.to[0] != "RCPT TO:<rep@example.com>" {
+		t.Errorf("Expected the letter to go from the sender to the recipient, got %q to %q", sink.from, sink.to)
+	}
+	header, body := parseMessage(t, sink.data)
+	if header.Get("To") != "rep@example.com" || header.Get("Subject") != letter.Subject {
+		t.Errorf("Expected the letter's headers, got %v", header)
+	}
+	if body != letter.Body {
+		t.Errorf("Expected body %q, got %q", letter.Body, body)
+	}
+}
+
+func TestFileMailer_WritesEmlFiles(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "outbox")
+	mailer := services.FileMailer{Dir: dir, From: "alerts@megga.example"}
+
+	for i := 0; i < 2; i++ {
+		if err := mailer.Send(letter); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil || len(files) != 2 {
+		t.Fatalf("Expected one .eml file per letter, got %v, %v", files, err)
+	}
+	message, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", files[0], err)
+	}
+	header, body := parseMessage(t, string(message))
+	if header.Get("From") != "alerts@megga.example" || header.Get("To") != "rep@example.com" || body != letter.Body {
+		t.Errorf("Expected the letter in the file, got %v\n%s", header, body)
+	}
+}
+
+func TestFileMailer_RejectsHeaderInjection(t *testing.T) {
+	mailer := services.FileMailer{Dir: t.TempDir()}
+	injected := letter
+	injected.To = "rep@example.com\r\nBcc: everyone@example.com"
+
+	if err := mailer.Send(injected); err == nil {
+		t.Error("Expected an address with a line break to be rejected")
+	}
+}
+
+func TestNewMailer(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.MailConfig
+		want    services.Mailer
+		wantErr bool
+	}{
+		{"log", config.MailConfig{Backend: config.MailBackendLog}, services.LogMailer{}, false},
+		{"file", config.MailConfig{Backend: config.MailBackendFile, DropDir: "mail", From: "a@example.com"}, services.FileMailer{Dir: "mail", From: "a@example.com"}, false},
+		{"smtp", config.MailConfig{Backend: config.MailBackendSMTP, SMTPHost: "mail.example.com", SMTPPort: "587", From: "a@example.com"},
+			services.SMTPMailer{Addr: "mail.example.com:587", Host: "mail.example.com", From: "a@example.com"}, false},
+		{"smtp without host", config.MailConfig{Backend: config.MailBackendSMTP, SMTPPort: "587", From: "a@example.com"}, nil, true},
+		{"unknown", config.MailConfig{Backend: "pigeon"}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := services.NewMailer(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %#v, got %#v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSMTPMailer_GivesUpOnSilentServer(t *testing.T) {
+	// The server accepts the connection but never greets, so the session
+	// runs into its deadline instead of holding the letter.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to start silent server: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			defer conn.Close()
+			io.Copy(io.Discard, conn)
+		}
+	}()
+
+	mailer := services.SMTPMailer{Addr: listener.Addr().String(), Host: "127.0.0.1", From: "alerts@megga.example", Timeout: 100 * time.Millisecond}
+	started := time.Now()
+	err = mailer.Send(letter)
+
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Fatalf("Expected a timeout, got %v", err)
+	}
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Errorf("Expected the send to give up quickly, took %v", elapsed)
+	}
+}
+
+// recordingMailer keeps the letters it is given.
+type recordingMailer struct {
+	sent []models.RenderedEmail
+}
+
+func (m *recordingMailer) Send(email models.RenderedEmail) error {
+	m.sent = append(m.sent, email)
+	return nil
+}
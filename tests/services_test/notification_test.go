@@ -1,50 +1,235 @@
 package services
 
 import (
-	"bytes"
-	"log"
+	"strings"
 	"testing"
+	"time"
 
 	"megga-backend/internal/models"
 	"megga-backend/internal/services"
+
+	"github.com/pashagolub/pgxmock"
 )
 
-func TestSendNotifications(t *testing.T) {
+func TestRenderNotifications(t *testing.T) {
 	threshold := models.Threshold{
 		ThresholdID:    1,
 		UserID:         1,
 		ThresholdValue: 10.0,
 		NotifyUser:     true,
 	}
+	recipients := []models.Recipient{
+		{RecipientID: 1, Email: "test@example.com", FirstName: "Test", LastName: "User"},
+	}
 
-	// 🎯 Mock recipients
+	emails := services.RenderNotifications(threshold, services.Alert{DataName: "Milk, Fresh, Low Fat", Latest: 4.20, Change: 12.0}, recipients, "user@example.com")
+
+	if len(emails) != 2 || emails[0].To != "test@example.com" || emails[1].To != "user@example.com" {
+		t.Fatalf("❌ Expected the recipient's and the user's letters, got %+v", emails)
+	}
+	if emails[0].Kind != models.EmailKindRecipient || emails[1].Kind != models.EmailKindUser {
+		t.Errorf("❌ Expected a recipient letter then a user letter, got %q and %q", emails[0].Kind, emails[1].Kind)
+	}
+}
+
+func TestRenderNotifications_FallingThresholdUsesDecreaseLetter(t *testing.T) {
+	threshold := models.Threshold{
+		ThresholdID:    2,
+		UserID:         1,
+		ThresholdValue: 10.0,
+		Mode:           models.ThresholdModePercentPrevious,
+		Direction:      models.ThresholdDirectionFall,
+	}
 	recipients := []models.Recipient{
 		{RecipientID: 1, Email: "test@example.com", FirstName: "Test", LastName: "User"},
 	}
 
-	// 🎯 Mock user email
-	userEmail := "user@example.com"
+	emails := services.RenderNotifications(threshold, services.Alert{DataName: "Eggs, Grade A, Large", Latest: 3.50, Change: -12.0}, recipients, "user@example.com")
+
+	if len(emails) != 1 || !strings.Contains(emails[0].Body, "has decreased by 12.00%") {
+		t.Errorf("❌ Expected the decrease letter with an unsigned change, got %+v", emails)
+	}
+}
+
+func TestRenderNotifications_UserLetterExplainsAlert(t *testing.T) {
+	threshold := models.Threshold{
+		ThresholdID:    3,
+		UserID:         1,
+		ThresholdValue: 4.25,
+		Mode:           models.ThresholdModeAbsolute,
+		Direction:      models.ThresholdDirectionRise,
+		NotifyUser:     true,
+	}
 
-	// 🎯 Capture logs
-	var logBuffer bytes.Buffer
-	log.SetOutput(&logBuffer)
-	defer log.SetOutput(nil)
+	emails := services.RenderNotifications(threshold, services.Alert{
+		DataName:    "Eggs, Grade A, Large",
+		Latest:      4.40,
+		Change:      4.40,
+		Explanation: "Eggs, Grade A, Large: 4.40 >= 4.25, met.\nThe threshold was armed and is now met, so it fired.",
+	}, nil, "user@example.com")
+	if len(emails) != 1 {
+		t.Fatalf("❌ Expected only the user letter, got %+v", emails)
+	}
 
-	// 🎯 Run function with required arguments
-	services.SendNotifications(threshold, "Milk, Fresh, Low Fat", 12.0, recipients, userEmail)
+	body := emails[0].Body
+	if !strings.Contains(body, "New Value: 4.40") || !strings.Contains(body, "Threshold: 4.25") {
+		t.Errorf("❌ Expected the latest value and threshold in the user letter, got:\n%s", body)
+	}
+	if !strings.Contains(body, "Why this alert fired:\nEggs, Grade A, Large: 4.40 >= 4.25, met.") {
+		t.Errorf("❌ Expected the explanation in the user letter, got:\n%s", body)
+	}
+	if strings.Contains(body, "{") {
+		t.Errorf("❌ Expected every placeholder to be filled, got:\n%s", body)
+	}
+}
 
-	// 🛠 Print the actual logs for debugging
-	actualLogs := logBuffer.String()
-	t.Logf("🔍 Captured Logs:\n%s", actualLogs)
+func TestProcessRevisions_NotifiesOwners(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
 
-	// 🎯 Check logs
-	expectedLog := "📧 [MOCK EMAIL] To: test@example.com"
-	if !bytes.Contains([]byte(actualLogs), []byte(expectedLog)) {
-		t.Errorf("❌ Expected log: %s", expectedLog)
+	mock.ExpectQuery("FROM data_revisions").
+		WillReturnRows(pgxmock.NewRows([]string{"revision_id", "series_id", "year", "period", "previous_value", "revised_value", "preliminary", "detected_at"}).
+			AddRow(3, "APU0000708111", "2024", "M11", 3.65, 3.71, false, time.Now()))
+	mock.ExpectQuery("FROM thresholds").
+		WithArgs("APU0000708111").
+		WillReturnRows(pgxmock.NewRows([]string{"user_id", "email", "first_name", "name"}).
+			AddRow(1, "user@example.com", "Jane", "Eggs, Grade A, Large"))
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO notification_outbox").
+		WithArgs((*int)(nil), (*int)(nil), models.EmailKindUser, "user@example.com", "A Number Behind Your MEGGA Threshold Was Corrected",
+			textContains("Revised Value: 3.71")).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectExec("UPDATE data_revisions SET processed_at").
+		WithArgs(3).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectCommit()
+
+	processed, err := services.ProcessRevisions(mock)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if processed != 1 {
+		t.Errorf("Expected 1 processed revision, got %d", processed)
 	}
 
-	expectedUserLog := "📧 [MOCK EMAIL] To: user@example.com"
-	if !bytes.Contains([]byte(actualLogs), []byte(expectedUserLog)) {
-		t.Errorf("❌ Expected user email log: %s", expectedUserLog)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
 	}
 }
+
+func TestExpireThresholds_NotifiesOwnerAndDeletes(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	mock.ExpectQuery("WHERE t.expires_at <= NOW()").
+		WillReturnRows(pgxmock.NewRows([]string{"threshold_id", "user_id", "email", "first_name", "name", "expires_at", "campaign_id", "campaign_name"}).
+			AddRow(7, 1, "user@example.com", "Jane", "Eggs, Grade A, Large", time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC), (*int)(nil), ""))
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM thresholds WHERE threshold_id").
+		WithArgs(7).
+		WillReturnResult(pgxmock.NewResult("DELETE", 1))
+	mock.ExpectExec("INSERT INTO notification_outbox").
+		WithArgs((*int)(nil), (*int)(nil), models.EmailKindUser, "user@example.com", "Your MEGGA Threshold Has Expired",
+			textContains("Your threshold on Eggs, Grade A, Large reached its expiry date of March 1, 2025")).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectCommit()
+
+	removed, err := services.ExpireThresholds(mock)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("Expected 1 removed threshold, got %d", removed)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+func TestExpireThresholds_TellsCampaignMembers(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	campaignID := 3
+	mock.ExpectQuery("WHERE t.expires_at <= NOW()").
+		WillReturnRows(pgxmock.NewRows([]string{"threshold_id", "user_id", "email", "first_name", "name", "expires_at", "campaign_id", "campaign_name"}).
+			AddRow(7, 1, "owner@example.com", "Jane", "Eggs, Grade A, Large", time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC), &campaignID, "Cheaper Eggs"))
+	mock.ExpectQuery("FROM campaign_members m").
+		WithArgs(campaignID).
+		WillReturnRows(pgxmock.NewRows([]string{"user_id", "email", "first_name", "last_name"}).
+			AddRow(1, "owner@example.com", "Jane", "Doe").
+			AddRow(2, "member@example.com", "Sam", "Lee"))
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM thresholds WHERE threshold_id").
+		WithArgs(7).
+		WillReturnResult(pgxmock.NewResult("DELETE", 1))
+	mock.ExpectExec("INSERT INTO notification_outbox").
+		WithArgs((*int)(nil), (*int)(nil), models.EmailKindUser, "owner@example.com", "Your MEGGA Threshold Has Expired", textContains("Hi Jane,")).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	// The owner is also a member, but gets only the expiry notice.
+	mock.ExpectExec("INSERT INTO notification_outbox").
+		WithArgs((*int)(nil), (*int)(nil), models.EmailKindUser, "member@example.com", "A MEGGA Campaign You Joined Has Ended",
+			textContains(`The campaign "Cheaper Eggs" watched Eggs, Grade A, Large until its expiry date of March 1, 2025.`)).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectCommit()
+
+	removed, err := services.ExpireThresholds(mock)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("Expected 1 removed threshold, got %d", removed)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+func TestExpireThresholds_AlreadyDeleted(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	mock.ExpectQuery("WHERE t.expires_at <= NOW()").
+		WillReturnRows(pgxmock.NewRows([]string{"threshold_id", "user_id", "email", "first_name", "name", "expires_at", "campaign_id", "campaign_name"}).
+			AddRow(7, 1, "user@example.com", "Jane", "Eggs, Grade A, Large", time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC), (*int)(nil), ""))
+	// The owner deleted it in the meantime, so no notice is queued.
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM thresholds WHERE threshold_id").
+		WithArgs(7).
+		WillReturnResult(pgxmock.NewResult("DELETE", 0))
+	mock.ExpectRollback()
+
+	removed, err := services.ExpireThresholds(mock)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if removed != 0 {
+		t.Errorf("Expected no removed thresholds, got %d", removed)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+// textContains matches a string argument that contains it.
+type textContains string
+
+func (c textContains) Match(v interface{}) bool {
+	s, ok := v.(string)
+	return ok && strings.Contains(s, string(c))
+}
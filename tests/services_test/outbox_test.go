@@ -0,0 +1,255 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"megga-backend/internal/models"
+	"megga-backend/internal/services"
+
+	"github.com/pashagolub/pgxmock"
+)
+
+func outboxRows() *pgxmock.Rows {
+	return pgxmock.NewRows([]string{"outbox_id", "threshold_id", "campaign_id", "kind", "to_address", "subject", "body", "status", "attempts",
+		"next_attempt_at", "last_error", "created_at", "sent_at"})
+}
+
+func addOutboxRow(rows *pgxmock.Rows, outboxID, attempts int, to string) *pgxmock.Rows {
+	thresholdID := 1
+	return rows.AddRow(outboxID, &thresholdID, (*int)(nil), models.EmailKindRecipient, to, "Urgent: Eggs, Grade A, Large Economic Data Alert", "Dear Pat Doe,",
+		models.OutboxPending, attempts, time.Now(), "", time.Now(), (*time.Time)(nil))
+}
+
+// failingMailer fails every letter.
+type failingMailer struct{}
+
+func (failingMailer) Send(email models.RenderedEmail) error {
+	return errors.New("421 service not available")
+}
+
+// expectClaim expects the claim of the next due letter, returning rows, or
+// none when rows is nil. The rows' attempts include the one being claimed.
+func expectClaim(mock pgxmock.PgxPoolIface, rows *pgxmock.Rows) {
+	if rows == nil {
+		rows = outboxRows()
+	}
+	mock.ExpectQuery("FOR UPDATE SKIP LOCKED").
+		WithArgs(models.OutboxPending, 300.0).
+		WillReturnRows(rows)
+}
+
+func TestDispatchOutbox_SendsDueLetters(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	recorder := &recordingMailer{}
+	services.SetMailer(recorder)
+	defer services.SetMailer(services.LogMailer{})
+
+	// Each letter is claimed, sent and recorded on its own, with no
+	// transaction held open while it is sent.
+	expectClaim(mock, addOutboxRow(outboxRows(), 1, 1, "rep@example.com"))
+	mock.ExpectExec("UPDATE notification_outbox").
+		WithArgs(1, models.OutboxSent, 1).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	expectClaim(mock, addOutboxRow(outboxRows(), 2, 4, "senator@example.com"))
+	mock.ExpectExec("UPDATE notification_outbox").
+		WithArgs(2, models.OutboxSent, 4).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	expectClaim(mock, nil)
+
+	dispatch, err := services.DispatchOutbox(context.Background(), mock)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if dispatch != (models.OutboxDispatch{Sent: 2}) {
+		t.Errorf("Expected 2 letters sent, got %+v", dispatch)
+	}
+	if len(recorder.sent) != 2 || recorder.sent[0].To != "rep@example.com" || recorder.sent[1].To != "senator@example.com" {
+		t.Errorf("Expected both letters to be sent in order, got %+v", recorder.sent)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+func TestDispatchOutbox_RetriesThenGivesUp(t *testing.T) {
+	t.Setenv("OUTBOX_MAX_ATTEMPTS", "4")
+	t.Setenv("OUTBOX_RETRY_DELAY", "30s")
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	services.SetMailer(failingMailer{})
+	defer services.SetMailer(services.LogMailer{})
+
+	// The claims count each attempt. The first letter has failed twice before
+	// and waits two minutes after its third failure; the second has used up
+	// its attempts.
+	expectClaim(mock, addOutboxRow(outboxRows(), 1, 3, "rep@example.com"))
+	mock.ExpectExec("UPDATE notification_outbox").
+		WithArgs(1, 3, "421 service not available", 120.0).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	expectClaim(mock, addOutboxRow(outboxRows(), 2, 4, "senator@example.com"))
+	mock.ExpectExec("UPDATE notification_outbox").
+		WithArgs(2, models.OutboxDead, 4, "421 service not available").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	expectClaim(mock, nil)
+
+	dispatch, err := services.DispatchOutbox(context.Background(), mock)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if dispatch != (models.OutboxDispatch{Retried: 1, Dead: 1}) {
+		t.Errorf("Expected one letter to retry and one dead letter, got %+v", dispatch)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+func TestDispatchOutbox_GivesUpOnLetterWithoutOutcome(t *testing.T) {
+	t.Setenv("OUTBOX_MAX_ATTEMPTS", "4")
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	recorder := &recordingMailer{}
+	services.SetMailer(recorder)
+	defer services.SetMailer(services.LogMailer{})
+
+	// Every earlier claim ended before an outcome was recorded, so the fifth
+	// claim gives the letter up instead of sending it again.
+	expectClaim(mock, addOutboxRow(outboxRows(), 1, 5, "rep@example.com"))
+	mock.ExpectExec("UPDATE notification_outbox").
+		WithArgs(1, models.OutboxDead, 5, "no outcome was recorded for the last attempt").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	expectClaim(mock, nil)
+
+	dispatch, err := services.DispatchOutbox(context.Background(), mock)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if dispatch != (models.OutboxDispatch{Dead: 1}) || len(recorder.sent) != 0 {
+		t.Errorf("Expected the letter to be given up unsent, got %+v and %d sent", dispatch, len(recorder.sent))
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+func TestDispatchOutbox_KeepsEarlierOutcomes(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	recorder := &recordingMailer{}
+	services.SetMailer(recorder)
+	defer services.SetMailer(services.LogMailer{})
+
+	// The first letter is recorded as sent before the second is claimed, so
+	// failing to record the second does not send the first again.
+	expectClaim(mock, addOutboxRow(outboxRows(), 1, 1, "rep@example.com"))
+	mock.ExpectExec("UPDATE notification_outbox").
+		WithArgs(1, models.OutboxSent, 1).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	expectClaim(mock, addOutboxRow(outboxRows(), 2, 1, "senator@example.com"))
+	mock.ExpectExec("UPDATE notification_outbox").
+		WithArgs(2, models.OutboxSent, 1).
+		WillReturnError(errors.New("connection reset"))
+
+	dispatch, err := services.DispatchOutbox(context.Background(), mock)
+	if err == nil {
+		t.Fatal("Expected the failed update to be reported")
+	}
+	if dispatch.Sent != 2 || len(recorder.sent) != 2 {
+		t.Errorf("Expected each letter to be sent once, got %+v and %d sent", dispatch, len(recorder.sent))
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+// laterLeader becomes the leader on its third check, and stops being it once
+// ctx is cancelled.
+type laterLeader struct {
+	ctx    context.Context
+	checks int32
+}
+
+func (l *laterLeader) IsLeader() bool {
+	return atomic.AddInt32(&l.checks, 1) > 2 && l.ctx.Err() == nil
+}
+
+func TestRunOutboxDispatcher_WaitsForLeadership(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	// Only the first tick as leader claims anything, and the dispatcher
+	// stops once it has.
+	expectClaim(mock, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	db := cancelAfterQuery{PgxPoolIface: mock, query: "FOR UPDATE SKIP LOCKED", cancel: cancel}
+
+	leader := &laterLeader{ctx: ctx}
+	done := make(chan struct{})
+	go func() {
+		services.RunOutboxDispatcher(ctx, db, leader, time.Millisecond)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the dispatcher to stop after its first claim")
+	}
+
+	if checks := atomic.LoadInt32(&leader.checks); checks < 3 {
+		t.Errorf("Expected no claim before the third tick, got one on tick %d", checks)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+func TestOutboxRetryDelay(t *testing.T) {
+	t.Setenv("OUTBOX_RETRY_DELAY", "1m")
+
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{4, 8 * time.Minute},
+		{9, 256 * time.Minute},
+		{10, 6 * time.Hour},
+		{50, 6 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		if got := services.OutboxRetryDelay(tt.attempts); got != tt.want {
+			t.Errorf("OutboxRetryDelay(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,444 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"megga-backend/internal/models"
+	"megga-backend/internal/services"
+
+	"github.com/pashagolub/pgxmock"
+)
+
+type fakeProvider struct {
+	name         string
+	observations []models.DataObservation
+	err          error
+	requested    []string
+}
+
+func (p *fakeProvider) Name() string {
+	return p.name
+}
+
+func (p *fakeProvider) Fetch(ctx context.Context, seriesIDs []string) (services.FetchResult, error) {
+	p.requested = append(p.requested, seriesIDs...)
+	if p.err != nil {
+		return services.FetchResult{}, p.err
+	}
+
+	result := services.FetchResult{Observations: p.observations}
+	for _, seriesID := range seriesIDs {
+		count := 0
+		for _, observation := range p.observations {
+			if observation.SeriesID == seriesID {
+				count++
+			}
+		}
+		outcome := models.SeriesOutcome{SeriesID: seriesID, Provider: p.name, Outcome: models.OutcomeOK, ObservationCount: count}
+		if count == 0 {
+			outcome.Outcome = models.OutcomeNoData
+		}
+		result.Outcomes = append(result.Outcomes, outcome)
+	}
+	return result, nil
+}
+
+func catalogRows() *pgxmock.Rows {
+	return pgxmock.NewRows([]string{"series_id", "name", "unit", "category", "active", "provider", "priority", "created_at", "updated_at"}).
+		AddRow("APU0000708111", "Eggs, Grade A, Large", "per dozen", "food", true, "bls", "high", time.Now(), time.Now()).
+		AddRow("CPIAUCSL", "Consumer Price Index", "index", "inflation", true, "fred", "normal", time.Now(), time.Now())
+}
+
+func expectIngestionRunStart(mock pgxmock.PgxPoolIface) {
+	mock.ExpectQuery("INSERT INTO ingestion_runs").
+		WithArgs(models.IngestionRunning).
+		WillReturnRows(pgxmock.NewRows([]string{"ingestion_run_id", "started_at"}).AddRow(7, time.Now()))
+}
+
+func TestIngestLatestData_UsesProviderPerSeries(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	provider := &fakeProvider{
+		name: models.ProviderBLS,
+		observations: []models.DataObservation{
+			{SeriesID: "APU0000708111", Year: "2024", Period: "M12", Value: 4.15},
+			{SeriesID: "APU0000708111", Year: "2024", Period: "M11", Value: 3.65},
+		},
+	}
+
+	selectData := regexp.QuoteMeta(`SELECT data_id, latest_value, previous_value, year, period FROM data WHERE series_id = $1`)
+	dataColumns := []string{"data_id", "latest_value", "previous_value", "year", "period"}
+
+	expectIngestionRunStart(mock)
+	mock.ExpectQuery("FROM series_catalog").WillReturnRows(catalogRows())
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(selectData).
+		WithArgs("APU0000708111").
+		WillReturnRows(pgxmock.NewRows(dataColumns).AddRow(1, 3.65, 3.37, "2024", "M11"))
+	mock.ExpectExec("UPDATE data").
+		WithArgs(4.15, "2024", "M12", 1).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+	stored := 3.65
+	mock.ExpectQuery("INSERT INTO data_observations").
+		WithArgs("APU0000708111", "2024", "M12", 4.15, false, "").
+		WillReturnRows(pgxmock.NewRows([]string{"value", "exists"}).AddRow(nil, true))
+	mock.ExpectQuery("INSERT INTO data_observations").
+		WithArgs("APU0000708111", "2024", "M11", 3.65, false, "").
+		WillReturnRows(pgxmock.NewRows([]string{"value", "exists"}).AddRow(&stored, false))
+	mock.ExpectCommit()
+
+	mock.ExpectQuery("FROM thresholds t").
+		WithArgs([]string{"APU0000708111"}).
+		WillReturnRows(thresholdRows())
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO ingestion_series_results").
+		WithArgs(7, "APU0000708111", models.ProviderBLS, models.OutcomeOK, "", 2).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectExec("INSERT INTO ingestion_series_results").
+		WithArgs(7, "CPIAUCSL", models.ProviderFRED, models.OutcomeError, "no provider configured", 0).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectQuery("UPDATE ingestion_runs").
+		WithArgs(models.IngestionPartial, "", 0, 0, 7).
+		WillReturnRows(pgxmock.NewRows([]string{"finished_at"}).AddRow(time.Now()))
+	mock.ExpectCommit()
+
+	result, err := services.IngestLatestData(context.Background(), mock, map[string]services.DataProvider{
+		models.ProviderBLS: provider,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if result.IngestionRunID != 7 || result.Status != models.IngestionPartial || len(result.Series) != 2 {
+		t.Errorf("Unexpected ingestion result: %+v", result)
+	}
+
+	if len(provider.requested) != 1 || provider.requested[0] != "APU0000708111" {
+		t.Errorf("Expected only the BLS series to be requested, got %v", provider.requested)
+	}
+
+	if result.Thresholds == nil || len(result.Thresholds.SeriesIDs) != 1 || result.Thresholds.SeriesIDs[0] != "APU0000708111" {
+		t.Errorf("Expected thresholds to be checked for the changed series only, got %+v", result.Thresholds)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+func TestIngestLatestData_RechecksRevisedSeries(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	// December is unchanged, but November, which percent_previous compares
+	// against, was revised.
+	provider := &fakeProvider{
+		name: models.ProviderBLS,
+		observations: []models.DataObservation{
+			{SeriesID: "APU0000708111", Year: "2024", Period: "M12", Value: 4.15},
+			{SeriesID: "APU0000708111", Year: "2024", Period: "M11", Value: 3.65},
+		},
+	}
+
+	expectIngestionRunStart(mock)
+	mock.ExpectQuery("FROM series_catalog").WillReturnRows(catalogRows())
+
+	latest, stored := 4.15, 3.50
+	mock.ExpectBegin()
+	mock.ExpectQuery("FROM data WHERE series_id").
+		WithArgs("APU0000708111").
+		WillReturnRows(pgxmock.NewRows([]string{"data_id", "latest_value", "previous_value", "year", "period"}).AddRow(1, 4.15, 3.50, "2024", "M12"))
+	mock.ExpectQuery("INSERT INTO data_observations").
+		WithArgs("APU0000708111", "2024", "M12", 4.15, false, "").
+		WillReturnRows(pgxmock.NewRows([]string{"value", "exists"}).AddRow(&latest, false))
+	mock.ExpectQuery("INSERT INTO data_observations").
+		WithArgs("APU0000708111", "2024", "M11", 3.65, false, "").
+		WillReturnRows(pgxmock.NewRows([]string{"value", "exists"}).AddRow(&stored, true))
+	mock.ExpectQuery("INSERT INTO data_revisions").
+		WithArgs("APU0000708111", "2024", "M11", 3.50, 3.65, false).
+		WillReturnRows(pgxmock.NewRows([]string{"revision_id", "detected_at"}).AddRow(3, time.Now()))
+	mock.ExpectCommit()
+
+	mock.ExpectQuery("FROM data_revisions").
+		WillReturnRows(pgxmock.NewRows([]string{"revision_id", "series_id", "year", "period", "previous_value", "revised_value", "preliminary", "detected_at"}))
+
+	mock.ExpectQuery("FROM thresholds t").
+		WithArgs([]string{"APU0000708111"}).
+		WillReturnRows(thresholdRows())
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO ingestion_series_results").
+		WithArgs(7, "APU0000708111", models.ProviderBLS, models.OutcomeOK, "", 2).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectExec("INSERT INTO ingestion_series_results").
+		WithArgs(7, "CPIAUCSL", models.ProviderFRED, models.OutcomeError, "no provider configured", 0).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectQuery("UPDATE ingestion_runs").
+		WithArgs(models.IngestionPartial, "", 0, 0, 7).
+		WillReturnRows(pgxmock.NewRows([]string{"finished_at"}).AddRow(time.Now()))
+	mock.ExpectCommit()
+
+	result, err := services.IngestLatestData(context.Background(), mock, map[string]services.DataProvider{
+		models.ProviderBLS: provider,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(result.Revisions) != 1 {
+		t.Errorf("Expected the November revision to be reported, got %+v", result.Revisions)
+	}
+	if result.Thresholds == nil || len(result.Thresholds.SeriesIDs) != 1 || result.Thresholds.SeriesIDs[0] != "APU0000708111" {
+		t.Errorf("Expected thresholds on the revised series to be checked, got %+v", result.Thresholds)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+func TestIngestLatestData_StopsWritingWhenCancelled(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	provider := &cancellingProvider{
+		fakeProvider: fakeProvider{
+			name:         models.ProviderBLS,
+			observations: []models.DataObservation{{SeriesID: "APU0000708111", Year: "2024", Period: "M12", Value: 4.15}},
+		},
+		cancel: cancel,
+	}
+
+	// The run is cancelled while the provider is fetching, as it is when the
+	// instance loses its leadership, so none of the fetched data is saved.
+	expectIngestionRunStart(mock)
+	mock.ExpectQuery("FROM series_catalog").WillReturnRows(catalogRows())
+	db := &cancelledRun{cancelAfterQuery: cancelAfterQuery{PgxPoolIface: mock, query: "never issued", cancel: cancel}, run: ctx}
+
+	_, err = services.IngestLatestData(ctx, db, map[string]services.DataProvider{models.ProviderBLS: provider})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected the run to be cancelled, got %v", err)
+	}
+	if len(db.late) > 0 {
+		t.Errorf("Expected no statements after cancellation, got %q", db.late)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+// cancellingProvider cancels the run once it has fetched.
+type cancellingProvider struct {
+	fakeProvider
+	cancel context.CancelFunc
+}
+
+func (p *cancellingProvider) Fetch(ctx context.Context, seriesIDs []string) (services.FetchResult, error) {
+	result, err := p.fakeProvider.Fetch(ctx, seriesIDs)
+	p.cancel()
+	return result, err
+}
+
+func TestIngestLatestData_ProviderFailure(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	expectIngestionRunStart(mock)
+	mock.ExpectQuery("FROM series_catalog").WillReturnRows(catalogRows())
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO ingestion_series_results").
+		WithArgs(7, "APU0000708111", models.ProviderBLS, models.OutcomeError, "connection refused", 0).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectExec("INSERT INTO ingestion_series_results").
+		WithArgs(7, "CPIAUCSL", models.ProviderFRED, models.OutcomeError, "no provider configured", 0).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectQuery("UPDATE ingestion_runs").
+		WithArgs(models.IngestionFailed, pgxmock.AnyArg(), nil, nil, 7).
+		WillReturnRows(pgxmock.NewRows([]string{"finished_at"}).AddRow(time.Now()))
+	mock.ExpectCommit()
+
+	result, err := services.IngestLatestData(context.Background(), mock, map[string]services.DataProvider{
+		models.ProviderBLS: &fakeProvider{name: models.ProviderBLS, err: errors.New("connection refused")},
+	})
+	if err == nil {
+		t.Errorf("Expected an error when every provider fails, got nil")
+	}
+	if result.Status != models.IngestionFailed {
+		t.Errorf("Expected run to be marked failed, got %q", result.Status)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+type fakeQuotaProvider struct {
+	fakeProvider
+	remaining int
+}
+
+func (p *fakeQuotaProvider) RemainingRequests(ctx context.Context) (int, error) {
+	return p.remaining, nil
+}
+
+func (p *fakeQuotaProvider) RequestsNeeded(seriesCount int) int {
+	return seriesCount
+}
+
+func TestIngestLatestData_DefersLowPriorityNearQuota(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	expectIngestionRunStart(mock)
+	mock.ExpectQuery("FROM series_catalog").WillReturnRows(
+		pgxmock.NewRows([]string{"series_id", "name", "unit", "category", "active", "provider", "priority", "created_at", "updated_at"}).
+			AddRow("APU0000708111", "Eggs, Grade A, Large", "per dozen", "food", true, "bls", "high", time.Now(), time.Now()).
+			AddRow("APU0000711311", "Oranges, Navel", "per lb.", "food", true, "bls", "low", time.Now(), time.Now()))
+
+	provider := &fakeQuotaProvider{
+		fakeProvider: fakeProvider{name: models.ProviderBLS, err: errors.New("stop after planning")},
+		remaining:    5,
+	}
+
+	result, _ := services.IngestLatestData(context.Background(), mock, map[string]services.DataProvider{
+		models.ProviderBLS: provider,
+	})
+
+	if len(provider.requested) != 1 || provider.requested[0] != "APU0000708111" {
+		t.Errorf("Expected only the high-priority series to be fetched, got %v", provider.requested)
+	}
+
+	deferred := false
+	for _, outcome := range result.Series {
+		if outcome.SeriesID == "APU0000711311" && outcome.Outcome == models.OutcomeDeferred {
+			deferred = true
+		}
+	}
+	if !deferred {
+		t.Errorf("Expected the low-priority series to be reported as deferred, got %+v", result.Series)
+	}
+}
+
+func TestUsageTracker_Remaining(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	tracker := services.NewUsageTracker(mock, models.ProviderBLS, "secret-key", 500)
+	tracker.Now = func() time.Time { return time.Date(2025, 2, 3, 9, 0, 0, 0, time.UTC) }
+
+	mock.ExpectQuery("FROM api_usage").
+		WithArgs(models.ProviderBLS, tracker.KeyID, "2025-02-03").
+		WillReturnRows(pgxmock.NewRows([]string{"sum"}).AddRow(480))
+
+	remaining, err := tracker.Remaining(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if remaining != 20 {
+		t.Errorf("Expected 20 requests remaining, got %d", remaining)
+	}
+	if tracker.KeyID == "secret-key" {
+		t.Errorf("Expected API key to be hashed before storage")
+	}
+}
+
+func TestParseFREDResponse_NormalizesDates(t *testing.T) {
+	body := `{"observations": [
+		{"date": "2024-12-01", "value": "315.605"},
+		{"date": "2024-11-01", "value": "."},
+		{"date": "2024-10-01", "value": "314.1"}
+	]}`
+
+	observations, err := services.ParseFREDResponse("CPIAUCSL", []byte(body))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(observations) != 2 {
+		t.Fatalf("Expected 2 observations, got %d", len(observations))
+	}
+	if observations[0].Year != "2024" || observations[0].Period != "M12" || observations[0].Value != 315.605 {
+		t.Errorf("Unexpected first observation: %+v", observations[0])
+	}
+}
+
+func TestParseEIAResponse_CollapsesWeeklyData(t *testing.T) {
+	body := `{"response": {"data": [
+		{"period": "2024-12-16", "value": 3.05},
+		{"period": "2024-12-09", "value": "3.08"},
+		{"period": "2024-11-25", "value": 3.10},
+		{"period": "2024-11-18", "value": null}
+	]}}`
+
+	observations, err := services.ParseEIAResponse("PET.EMM_EPM0_PTE_NUS_DPG.W", []byte(body))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(observations) != 2 {
+		t.Fatalf("Expected one observation per month, got %d", len(observations))
+	}
+	if observations[0].Period != "M12" || observations[0].Value != 3.05 {
+		t.Errorf("Expected most recent weekly value for December, got %+v", observations[0])
+	}
+}
+
+func TestFREDProvider_ReportsInvalidAndThrottledSeries(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("series_id") {
+		case "CPIAUCSL":
+			w.Write([]byte(`{"observations": [{"date": "2024-12-01", "value": "315.605"}]}`))
+		case "BOGUS":
+			w.WriteHeader(http.StatusBadRequest)
+		default:
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer server.Close()
+
+	provider := &services.FREDProvider{URL: server.URL, Client: server.Client()}
+	result, err := provider.Fetch(context.Background(), []string{"CPIAUCSL", "BOGUS", "UNRATE", "PAYEMS"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := []string{models.OutcomeOK, models.OutcomeInvalidSeries, models.OutcomeThrottled, models.OutcomeThrottled}
+	if len(result.Outcomes) != len(expected) {
+		t.Fatalf("Expected %d outcomes, got %+v", len(expected), result.Outcomes)
+	}
+	for i, outcome := range result.Outcomes {
+		if outcome.Outcome != expected[i] {
+			t.Errorf("Expected %s to be %s, got %s", outcome.SeriesID, expected[i], outcome.Outcome)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package services_test
+
+import (
+	"testing"
+	"time"
+
+	"megga-backend/internal/models"
+	"megga-backend/internal/services"
+
+	"github.com/pashagolub/pgxmock"
+)
+
+func observationRows(seriesID string, values ...float64) *pgxmock.Rows {
+	rows := pgxmock.NewRows([]string{"series_id", "year", "period", "value"})
+	// Stored order is not period order, so the newest comes first.
+	for i := len(values) - 1; i >= 0; i-- {
+		rows.AddRow(seriesID, "2024", models.Period{Year: 2024, Kind: models.PeriodMonthly, Number: i + 1}.Code(), values[i])
+	}
+	return rows
+}
+
+func TestBacktestThreshold_ReplaysStates(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	mock.ExpectQuery("FROM data WHERE data_id = ANY").
+		WithArgs([]int{1}).
+		WillReturnRows(pgxmock.NewRows([]string{"data_id", "series_id", "name"}).AddRow(1, "APU0000708111", "Eggs, Grade A, Large"))
+	mock.ExpectQuery("FROM data_observations").
+		WithArgs([]string{"APU0000708111"}).
+		WillReturnRows(observationRows("APU0000708111", 3.80, 4.10, 4.20, 3.90, 3.70, 4.30))
+
+	threshold := models.Threshold{
+		DataID:         1,
+		ThresholdValue: 4.0,
+		Mode:           models.ThresholdModeAbsolute,
+		Direction:      models.ThresholdDirectionRise,
+		Hysteresis:     0.25,
+		Operator:       models.ThresholdOperatorAnd,
+	}
+	from := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC)
+
+	backtest, err := services.BacktestThreshold(mock, threshold, from, to)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if backtest.Evaluated != 5 || backtest.Skipped != 0 {
+		t.Errorf("Expected 5 periods evaluated and none skipped, got %+v", backtest)
+	}
+	// February fires, March and April stay triggered or cool down, May clears
+	// the hysteresis band and June fires again.
+	if len(backtest.Firings) != 2 || backtest.Firings[0].Date != "2024-02-01" || backtest.Firings[1].Period != "June 2024" {
+		t.Fatalf("Expected firings in February and June, got %+v", backtest.Firings)
+	}
+	if backtest.Firings[1].Comparison != "4.30 >= 4.00" || *backtest.Firings[1].Inputs[0].Previous != 3.70 {
+		t.Errorf("Expected the June values, got %+v", backtest.Firings[1])
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+func TestBacktestThreshold_RuleSkipsPeriodsWithoutEnoughData(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	mock.ExpectQuery("FROM series_catalog s").
+		WithArgs([]string{"eggs"}).
+		WillReturnRows(pgxmock.NewRows([]string{"series_id", "alias", "data_id"}).AddRow("APU0000708111", "eggs", 1))
+	mock.ExpectQuery("FROM data WHERE data_id = ANY").
+		WithArgs([]int{1}).
+		WillReturnRows(pgxmock.NewRows([]string{"data_id", "series_id", "name"}).AddRow(1, "APU0000708111", "Eggs, Grade A, Large"))
+	mock.ExpectQuery("FROM data_observations").
+		WithArgs([]string{"APU0000708111"}).
+		WillReturnRows(observationRows("APU0000708111", 3.80, 4.10, 4.60))
+
+	threshold := models.Threshold{DataID: 1, Expression: "avg_n(eggs, 2) > 4", Operator: models.ThresholdOperatorAnd}
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC)
+
+	backtest, err := services.BacktestThreshold(mock, threshold, from, to)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if backtest.Evaluated != 2 || backtest.Skipped != 1 {
+		t.Errorf("Expected January to be skipped and two periods evaluated, got %+v", backtest)
+	}
+	if len(backtest.Firings) != 1 || backtest.Firings[0].Period != "March 2024" || backtest.Firings[0].Inputs[0].Name != "avg_n(eggs, 2)" {
+		t.Errorf("Expected a single firing in March, got %+v", backtest.Firings)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
@@ -0,0 +1,171 @@
+package services_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"megga-backend/internal/models"
+	"megga-backend/internal/services"
+)
+
+func floatPtr(value float64) *float64 {
+	return &value
+}
+
+func TestEvaluateThreshold_Modes(t *testing.T) {
+	input := services.ThresholdInput{Latest: 4.40, Previous: floatPtr(4.00), YearAgo: floatPtr(2.20)}
+
+	tests := []struct {
+		name      string
+		threshold models.Threshold
+		observed  float64
+		triggered bool
+	}{
+		{"absolute reached", models.Threshold{Mode: models.ThresholdModeAbsolute, ThresholdValue: 4.25}, 4.40, true},
+		{"absolute not reached", models.Threshold{Mode: models.ThresholdModeAbsolute, ThresholdValue: 5}, 4.40, false},
+		{"previous period", models.Threshold{Mode: models.ThresholdModePercentPrevious, ThresholdValue: 10}, 10, true},
+		{"previous period below", models.Threshold{Mode: models.ThresholdModePercentPrevious, ThresholdValue: 15}, 10, false},
+		{"since created drop", models.Threshold{Mode: models.ThresholdModePercentSinceCreated, ThresholdValue: 10, BaselineValue: floatPtr(5.50)}, -20, true},
+		{"year over year", models.Threshold{Mode: models.ThresholdModePercentYoY, ThresholdValue: 50}, 100, true},
+		{"rise only ignores a drop", models.Threshold{Mode: models.ThresholdModePercentSinceCreated, Direction: models.ThresholdDirectionRise, ThresholdValue: 10, BaselineValue: floatPtr(5.50)}, -20, false},
+		{"fall only", models.Threshold{Mode: models.ThresholdModePercentSinceCreated, Direction: models.ThresholdDirectionFall, ThresholdValue: 10, BaselineValue: floatPtr(5.50)}, -20, true},
+		{"fall only ignores a rise", models.Threshold{Mode: models.ThresholdModePercentPrevious, Direction: models.ThresholdDirectionFall, ThresholdValue: 5}, 10, false},
+		{"absolute falling", models.Threshold{Mode: models.ThresholdModeAbsolute, Direction: models.ThresholdDirectionFall, ThresholdValue: 4.50}, 4.40, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := services.EvaluateThreshold(tt.threshold, input)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if diff := result.Observed - tt.observed; diff > 0.0001 || diff < -0.0001 {
+				t.Errorf("Expected observed %.4f, got %.4f", tt.observed, result.Observed)
+			}
+			if result.Triggered != tt.triggered {
+				t.Errorf("Expected triggered %t, got %t", tt.triggered, result.Triggered)
+			}
+		})
+	}
+}
+
+func TestEvaluateThreshold_MissingComparisonValue(t *testing.T) {
+	threshold := models.Threshold{Mode: models.ThresholdModePercentYoY, ThresholdValue: 5}
+
+	_, err := services.EvaluateThreshold(threshold, services.ThresholdInput{Latest: 4.40})
+	if !errors.Is(err, services.ErrMissingComparisonValue) {
+		t.Errorf("Expected ErrMissingComparisonValue, got %v", err)
+	}
+}
+
+func TestEvaluateCompound_Operators(t *testing.T) {
+	threshold := models.Threshold{
+		Mode: models.ThresholdModeAbsolute, Direction: models.ThresholdDirectionRise, ThresholdValue: 4.25,
+		Conditions: []models.ThresholdCondition{
+			{DataID: 2, Mode: models.ThresholdModePercentPrevious, Direction: models.ThresholdDirectionFall, Value: 1},
+		},
+	}
+	eggsUp := services.ThresholdInput{Latest: 4.40}
+	earningsFlat := services.ThresholdInput{Latest: 1150, Previous: floatPtr(1148)}
+
+	threshold.Operator = models.ThresholdOperatorAnd
+	result, err := services.EvaluateCompound(threshold, []services.ThresholdInput{eggsUp, earningsFlat})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if result.Triggered || len(result.Conditions) != 2 || !result.Conditions[0].Triggered || result.Conditions[1].Triggered {
+		t.Errorf("Expected an AND threshold with one unmet condition not to trigger, got %+v", result)
+	}
+
+	threshold.Operator = models.ThresholdOperatorOr
+	result, err = services.EvaluateCompound(threshold, []services.ThresholdInput{eggsUp, earningsFlat})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !result.Triggered || result.Observed != 4.40 {
+		t.Errorf("Expected an OR threshold to trigger on the first condition, got %+v", result)
+	}
+
+	_, err = services.EvaluateCompound(threshold, []services.ThresholdInput{eggsUp, {Latest: 1150}})
+	if !errors.Is(err, services.ErrMissingComparisonValue) {
+		t.Errorf("Expected ErrMissingComparisonValue from the second condition, got %v", err)
+	}
+}
+
+func TestNextThresholdState(t *testing.T) {
+	now := time.Date(2025, 2, 12, 14, 0, 0, 0, time.UTC)
+	recently := now.Add(-2 * time.Hour)
+	longAgo := now.Add(-48 * time.Hour)
+
+	rising := models.Threshold{Mode: models.ThresholdModePercentPrevious, Direction: models.ThresholdDirectionRise, ThresholdValue: 5, Hysteresis: 1}
+
+	withState := func(state string, lastTriggered *time.Time, cooldownHours int) models.Threshold {
+		threshold := rising
+		threshold.State = state
+		threshold.LastTriggeredAt = lastTriggered
+		threshold.CooldownHours = cooldownHours
+		return threshold
+	}
+
+	tests := []struct {
+		name      string
+		threshold models.Threshold
+		result    services.ThresholdResult
+		state     string
+		notify    bool
+	}{
+		{"armed crosses", withState(models.ThresholdStateArmed, nil, 0), services.ThresholdResult{Observed: 6, Triggered: true}, models.ThresholdStateTriggered, true},
+		{"armed stays quiet", withState(models.ThresholdStateArmed, nil, 0), services.ThresholdResult{Observed: 2}, models.ThresholdStateArmed, false},
+		{"triggered holds", withState(models.ThresholdStateTriggered, &recently, 24), services.ThresholdResult{Observed: 7, Triggered: true}, models.ThresholdStateTriggered, false},
+		{"inside the band cools down", withState(models.ThresholdStateTriggered, &recently, 24), services.ThresholdResult{Observed: 4.5}, models.ThresholdStateCoolingDown, false},
+		{"cooling down crosses again", withState(models.ThresholdStateCoolingDown, &recently, 24), services.ThresholdResult{Observed: 5.5, Triggered: true}, models.ThresholdStateTriggered, false},
+		{"past the band re-arms", withState(models.ThresholdStateCoolingDown, &recently, 24), services.ThresholdResult{Observed: 3.5}, models.ThresholdStateArmed, false},
+		{"cooldown elapsed fires again", withState(models.ThresholdStateTriggered, &longAgo, 24), services.ThresholdResult{Observed: 7, Triggered: true}, models.ThresholdStateTriggered, true},
+		{"no cooldown never re-fires", withState(models.ThresholdStateTriggered, &longAgo, 0), services.ThresholdResult{Observed: 7, Triggered: true}, models.ThresholdStateTriggered, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, notify := services.NextThresholdState(tt.threshold, tt.result, now)
+			if state != tt.state || notify != tt.notify {
+				t.Errorf("Expected (%s, %t), got (%s, %t)", tt.state, tt.notify, state, notify)
+			}
+		})
+	}
+}
+
+func TestNextBreach(t *testing.T) {
+	breached := models.Threshold{BreachPeriods: 2, BreachYear: "2024", BreachPeriod: "M12"}
+
+	tests := []struct {
+		name      string
+		threshold models.Threshold
+		result    services.ThresholdResult
+		periods   int
+		advanced  bool
+	}{
+		{"first period", models.Threshold{}, services.ThresholdResult{Triggered: true, Year: "2025", Period: "M01"}, 1, true},
+		{"same period checked again", breached, services.ThresholdResult{Triggered: true, Year: "2024", Period: "M12"}, 2, false},
+		{"next period", breached, services.ThresholdResult{Triggered: true, Year: "2025", Period: "M01"}, 3, true},
+		{"no longer met", breached, services.ThresholdResult{Year: "2025", Period: "M01"}, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			breach, advanced := services.NextBreach(tt.threshold, tt.result)
+			if breach.Periods != tt.periods || advanced != tt.advanced {
+				t.Errorf("Expected (%d, %t), got (%d, %t)", tt.periods, tt.advanced, breach.Periods, advanced)
+			}
+		})
+	}
+}
+
+func TestEscalationTierFor(t *testing.T) {
+	tiers := []models.EscalationTier{{AfterPeriods: 2}, {AfterPeriods: 5}}
+	for periods, want := range map[int]int{1: 0, 2: 1, 4: 1, 5: 2, 9: 2} {
+		if got := services.EscalationTierFor(tiers, periods); got != want {
+			t.Errorf("Expected tier %d after %d periods, got %d", want, periods, got)
+		}
+	}
+}
@@ -0,0 +1,898 @@
+package services_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"megga-backend/internal/models"
+	"megga-backend/internal/services"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/pashagolub/pgxmock"
+)
+
+var dataNames = map[int]string{1: "Eggs, Grade A, Large", 2: "Median Weekly Earnings"}
+
+var seriesIDs = map[int]string{1: "APU0000708111", 2: "LEU0252881500"}
+
+// expectThresholdData expects the batch lookup of the given data IDs, each on
+// an active series last published for January 2025 at the given value.
+func expectThresholdData(mock pgxmock.PgxPoolIface, dataIDs []int, latestValues ...float64) {
+	rows := pgxmock.NewRows([]string{"data_id", "series_id", "name", "latest_value", "previous_value", "year", "period", "active"})
+	for i, dataID := range dataIDs {
+		rows.AddRow(dataID, seriesIDs[dataID], dataNames[dataID], latestValues[i], 0.0, "2025", "M01", true)
+	}
+	mock.ExpectQuery("FROM data d").
+		WithArgs(dataIDs).
+		WillReturnRows(rows)
+}
+
+// expectPreviousValue expects the batch lookup of December 2024 for the eggs
+// series.
+func expectPreviousValue(mock pgxmock.PgxPoolIface, value float64) {
+	mock.ExpectQuery("FROM data_observations o").
+		WithArgs([]string{"APU0000708111"}, []string{"2024"}, []string{"M12"}).
+		WillReturnRows(pgxmock.NewRows([]string{"series_id", "year", "period", "value"}).AddRow("APU0000708111", "2024", "M12", value))
+}
+
+// expectDispatchData expects the batch lookups of the thresholds' escalation
+// tiers, recipients, owners and campaigns. Nil rows stand for none; every
+// threshold belongs to user 1.
+func expectDispatchData(mock pgxmock.PgxPoolIface, thresholdIDs []int, tiers, recipients, campaigns *pgxmock.Rows) {
+	if tiers == nil {
+		tiers = tierRows()
+	}
+	if recipients == nil {
+		recipients = recipientRows()
+	}
+	if campaigns == nil {
+		campaigns = campaignRows()
+	}
+	mock.ExpectQuery("FROM threshold_escalations").
+		WithArgs(thresholdIDs).
+		WillReturnRows(tiers)
+	mock.ExpectQuery("FROM threshold_recipients tr").
+		WithArgs(thresholdIDs).
+		WillReturnRows(recipients)
+	mock.ExpectQuery("FROM users").
+		WithArgs([]int{1}).
+		WillReturnRows(pgxmock.NewRows([]string{"user_id", "email"}).AddRow(1, "owner@example.com"))
+	mock.ExpectQuery("FROM campaigns").
+		WithArgs(thresholdIDs).
+		WillReturnRows(campaigns)
+}
+
+func tierRows() *pgxmock.Rows {
+	return pgxmock.NewRows([]string{"escalation_id", "threshold_id", "after_periods", "recipient_ids", "letter_subject", "letter_template"})
+}
+
+func recipientRows() *pgxmock.Rows {
+	return pgxmock.NewRows([]string{"threshold_id", "recipient_id", "email", "first_name", "last_name", "designation"})
+}
+
+func campaignRows() *pgxmock.Rows {
+	return pgxmock.NewRows([]string{"campaign_id", "owner_id", "threshold_id", "name", "description", "letter_subject", "letter_template", "created_at"})
+}
+
+// expectEvaluation expects the row recorded for one threshold check.
+func expectEvaluation(mock pgxmock.PgxPoolIface, thresholdID int, triggered bool, previousState, state string, notified bool) {
+	mock.ExpectExec("INSERT INTO threshold_evaluations").
+		WithArgs(thresholdID, pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), triggered,
+			previousState, state, notified, pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+}
+
+// expectQueued expects a letter to be queued in the outbox, with a subject
+// matching subject and a body containing each of the given parts.
+func expectQueued(mock pgxmock.PgxPoolIface, thresholdID int, campaignID *int, kind, to string, subject interface{}, body ...string) {
+	mock.ExpectExec("INSERT INTO notification_outbox").
+		WithArgs(&thresholdID, campaignID, kind, to, subject, argContains(body)).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+}
+
+var noCampaign = (*int)(nil)
+
+func thresholdRows() *pgxmock.Rows {
+	return pgxmock.NewRows([]string{"threshold_id", "user_id", "data_id", "threshold_value", "mode", "direction", "baseline_value", "notify_user",
+		"state", "hysteresis", "cooldown_hours", "last_triggered_at", "operator", "expression",
+		"breach_periods", "breach_year", "breach_period"})
+}
+
+func conditionRows() *pgxmock.Rows {
+	return pgxmock.NewRows([]string{"condition_id", "threshold_id", "data_id", "name", "mode", "direction", "value", "baseline_value"})
+}
+
+func TestCheckThresholdsForSeries_CountsCheckedAndTriggered(t *testing.T) {
+	// One worker checks the thresholds in order, as the expectations are.
+	t.Setenv("THRESHOLD_WORKERS", "1")
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	mock.ExpectQuery("FROM thresholds t").
+		WithArgs([]string{"APU0000708111"}).
+		WillReturnRows(thresholdRows().
+			AddRow(1, 1, 1, 5.0, models.ThresholdModeAbsolute, models.ThresholdDirectionRise, nil, false, models.ThresholdStateArmed, 0.0, 0, nil, models.ThresholdOperatorAnd, "", 0, "", "").
+			AddRow(2, 1, 1, 10.0, models.ThresholdModePercentPrevious, models.ThresholdDirectionEither, nil, false, models.ThresholdStateArmed, 0.0, 0, nil, models.ThresholdOperatorAnd, "", 0, "", ""))
+	mock.ExpectQuery("FROM threshold_conditions c").
+		WithArgs([]int{1, 2}).
+		WillReturnRows(conditionRows())
+
+	// Both thresholds watch the same series, which is read once.
+	expectThresholdData(mock, []int{1}, 5.5)
+	expectPreviousValue(mock, 5.25)
+	expectDispatchData(mock, []int{1, 2}, nil, nil, nil)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE thresholds").
+		WithArgs(models.ThresholdStateTriggered, true, pgxmock.AnyArg(), 1, 1, "2025", "M01", models.ThresholdStateArmed, (*time.Time)(nil), 0, "", "").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectCommit()
+	expectEvaluation(mock, 1, true, models.ThresholdStateArmed, models.ThresholdStateTriggered, true)
+	expectEvaluation(mock, 2, false, models.ThresholdStateArmed, models.ThresholdStateArmed, false)
+
+	check, err := services.CheckThresholdsForSeries(context.Background(), mock, []string{"APU0000708111"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if check.Checked != 2 || check.Triggered != 1 {
+		t.Errorf("Expected 2 checked and 1 triggered, got %+v", check)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+func TestCheckThresholdsForSeries_TriggeredThresholdDoesNotFireAgain(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	lastTriggered := time.Now().Add(-time.Hour)
+	mock.ExpectQuery("FROM thresholds t").
+		WithArgs([]string{"APU0000708111"}).
+		WillReturnRows(thresholdRows().
+			AddRow(1, 1, 1, 5.0, models.ThresholdModeAbsolute, models.ThresholdDirectionRise, nil, true, models.ThresholdStateTriggered, 0.25, 24, &lastTriggered, models.ThresholdOperatorAnd, "", 1, "2025", "M01"))
+	mock.ExpectQuery("FROM threshold_conditions c").
+		WithArgs([]int{1}).
+		WillReturnRows(conditionRows())
+
+	expectThresholdData(mock, []int{1}, 5.5)
+	expectDispatchData(mock, []int{1}, nil, nil, nil)
+	expectEvaluation(mock, 1, true, models.ThresholdStateTriggered, models.ThresholdStateTriggered, false)
+
+	check, err := services.CheckThresholdsForSeries(context.Background(), mock, []string{"APU0000708111"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if check.Checked != 1 || check.Triggered != 0 {
+		t.Errorf("Expected 1 checked and none triggered, got %+v", check)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+func TestCheckThresholdsForSeries_EscalatesWhenBreachReachesTier(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	// The threshold fired in December and is still met in January, its second
+	// period in a row, which is when its first tier starts.
+	lastTriggered := time.Now().Add(-30 * 24 * time.Hour)
+	mock.ExpectQuery("FROM thresholds t").
+		WithArgs([]string{"APU0000708111"}).
+		WillReturnRows(thresholdRows().
+			AddRow(1, 1, 1, 5.0, models.ThresholdModeAbsolute, models.ThresholdDirectionRise, nil, false, models.ThresholdStateTriggered, 0.0, 0, &lastTriggered, models.ThresholdOperatorAnd, "", 1, "2024", "M12"))
+	mock.ExpectQuery("FROM threshold_conditions c").
+		WithArgs([]int{1}).
+		WillReturnRows(conditionRows())
+
+	expectThresholdData(mock, []int{1}, 5.5)
+	expectDispatchData(mock, []int{1},
+		tierRows().
+			AddRow(1, 1, 2, []int64{1, 5}, "Still waiting: [Threshold Name]", "Dear [Recipient Name],\n\nI wrote last month and have not heard back.\n\n[User First Name]").
+			AddRow(2, 1, 4, []int64{6}, "", ""),
+		recipientRows().AddRow(1, 1, "rep@example.com", "Pat", "Doe", "Representative"),
+		nil)
+	mock.ExpectQuery("FROM recipients").
+		WithArgs([]int64{5}).
+		WillReturnRows(pgxmock.NewRows([]string{"recipient_id", "email", "first_name", "last_name", "designation"}).
+			AddRow(5, "senator@example.com", "Sam", "Lee", "Senator"))
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE thresholds").
+		WithArgs(models.ThresholdStateTriggered, true, pgxmock.AnyArg(), 1, 2, "2025", "M01", models.ThresholdStateTriggered, &lastTriggered, 1, "2024", "M12").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	expectQueued(mock, 1, noCampaign, models.EmailKindRecipient, "rep@example.com", "Still waiting: Eggs, Grade A, Large",
+		"Dear Pat Doe,", "I wrote last month and have not heard back.")
+	expectQueued(mock, 1, noCampaign, models.EmailKindRecipient, "senator@example.com", "Still waiting: Eggs, Grade A, Large",
+		"Dear Sam Lee,", "I wrote last month and have not heard back.")
+	mock.ExpectCommit()
+	mock.ExpectExec("INSERT INTO threshold_evaluations").
+		WithArgs(1, pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), true,
+			models.ThresholdStateTriggered, models.ThresholdStateTriggered, true,
+			argContains{"held for 2 periods in a row, which reaches escalation tier 1, so it fired again"}, "", 2, 1, true).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+	check, err := services.CheckThresholdsForSeries(context.Background(), mock, []string{"APU0000708111"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if check.Triggered != 1 {
+		t.Errorf("Expected the threshold to escalate, got %+v", check)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+func TestCheckThresholdsForSeries_CompoundThresholdExplainsMetConditions(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	mock.ExpectQuery("FROM thresholds t").
+		WithArgs([]string{"APU0000708111"}).
+		WillReturnRows(thresholdRows().
+			AddRow(1, 1, 1, 5.0, models.ThresholdModeAbsolute, models.ThresholdDirectionRise, nil, false, models.ThresholdStateArmed, 0.0, 0, nil, models.ThresholdOperatorAnd, "", 0, "", ""))
+	mock.ExpectQuery("FROM threshold_conditions c").
+		WithArgs([]int{1}).
+		WillReturnRows(conditionRows().
+			AddRow(4, 1, 2, "Median Weekly Earnings", models.ThresholdModeAbsolute, models.ThresholdDirectionFall, 1200.0, nil))
+
+	expectThresholdData(mock, []int{1, 2}, 5.5, 1150.0)
+	expectDispatchData(mock, []int{1}, nil, recipientRows().AddRow(1, 1, "rep@example.com", "Pat", "Doe", "Representative"), nil)
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE thresholds").
+		WithArgs(models.ThresholdStateTriggered, true, pgxmock.AnyArg(), 1, 1, "2025", "M01", models.ThresholdStateArmed, (*time.Time)(nil), 0, "", "").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	// The letter lists the other met condition.
+	expectQueued(mock, 1, noCampaign, models.EmailKindRecipient, "rep@example.com", pgxmock.AnyArg(),
+		"At the same time:\n- Median Weekly Earnings is at 1150.00, at or below 1200.00")
+	mock.ExpectCommit()
+	expectEvaluation(mock, 1, true, models.ThresholdStateArmed, models.ThresholdStateTriggered, true)
+
+	check, err := services.CheckThresholdsForSeries(context.Background(), mock, []string{"APU0000708111"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if check.Checked != 1 || check.Triggered != 1 {
+		t.Errorf("Expected 1 checked and 1 triggered, got %+v", check)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+func TestCheckThresholdsForSeries_CampaignWritesForEachMember(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	mock.ExpectQuery("FROM thresholds t").
+		WithArgs([]string{"APU0000708111"}).
+		WillReturnRows(thresholdRows().
+			AddRow(1, 1, 1, 5.0, models.ThresholdModeAbsolute, models.ThresholdDirectionRise, nil, false, models.ThresholdStateArmed, 0.0, 0, nil, models.ThresholdOperatorAnd, "", 0, "", ""))
+	mock.ExpectQuery("FROM threshold_conditions c").
+		WithArgs([]int{1}).
+		WillReturnRows(conditionRows())
+
+	expectThresholdData(mock, []int{1}, 5.5)
+	expectDispatchData(mock, []int{1}, nil,
+		recipientRows().AddRow(1, 1, "rep@example.com", "Pat", "Doe", "Representative"),
+		campaignRows().
+			AddRow(3, 1, 1, "Cheaper Eggs", "", "Eggs are up, [Recipient Name]", "Dear [Recipient Name],\n\n[Threshold Name] is at a new high.\n\n[User First Name] [User Last Name]", time.Now()))
+	mock.ExpectQuery("FROM campaign_members m").
+		WithArgs(3).
+		WillReturnRows(pgxmock.NewRows([]string{"user_id", "email", "first_name", "last_name"}).
+			AddRow(7, "ana@example.com", "Ana", "Lopez").
+			AddRow(8, "ben@example.com", "Ben", "Okafor"))
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE thresholds").
+		WithArgs(models.ThresholdStateTriggered, true, pgxmock.AnyArg(), 1, 1, "2025", "M01", models.ThresholdStateArmed, (*time.Time)(nil), 0, "", "").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectExec("INSERT INTO campaign_deliveries").
+		WithArgs(3, 2, 2).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	campaignID := 3
+	userSubject := argContains{"Your MEGGA Threshold Was Hit"}
+	expectQueued(mock, 1, &campaignID, models.EmailKindRecipient, "rep@example.com", "Eggs are up, Pat Doe", "Ana Lopez")
+	expectQueued(mock, 1, &campaignID, models.EmailKindUser, "ana@example.com", userSubject, "Hi Ana,")
+	expectQueued(mock, 1, &campaignID, models.EmailKindRecipient, "rep@example.com", "Eggs are up, Pat Doe", "Ben Okafor")
+	expectQueued(mock, 1, &campaignID, models.EmailKindUser, "ben@example.com", userSubject, "Hi Ben,")
+	mock.ExpectCommit()
+	expectEvaluation(mock, 1, true, models.ThresholdStateArmed, models.ThresholdStateTriggered, true)
+
+	check, err := services.CheckThresholdsForSeries(context.Background(), mock, []string{"APU0000708111"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if check.Triggered != 1 {
+		t.Errorf("Expected the campaign to fire, got %+v", check)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+func TestCheckThresholdsForSeries_CampaignWithoutMembers(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	mock.ExpectQuery("FROM thresholds t").
+		WithArgs([]string{"APU0000708111"}).
+		WillReturnRows(thresholdRows().
+			AddRow(1, 1, 1, 5.0, models.ThresholdModeAbsolute, models.ThresholdDirectionRise, nil, false, models.ThresholdStateArmed, 0.0, 0, nil, models.ThresholdOperatorAnd, "", 0, "", ""))
+	mock.ExpectQuery("FROM threshold_conditions c").
+		WithArgs([]int{1}).
+		WillReturnRows(conditionRows())
+
+	expectThresholdData(mock, []int{1}, 5.5)
+	expectDispatchData(mock, []int{1}, nil,
+		recipientRows().AddRow(1, 1, "rep@example.com", "Pat", "Doe", "Representative"),
+		campaignRows().
+			AddRow(3, 1, 1, "Cheaper Eggs", "", "Eggs are up, [Recipient Name]", "Dear [Recipient Name],", time.Now()))
+	mock.ExpectQuery("FROM campaign_members m").
+		WithArgs(3).
+		WillReturnRows(pgxmock.NewRows([]string{"user_id", "email", "first_name", "last_name"}))
+	// Nothing is queued or recorded as a delivery, and the threshold stays
+	// armed so it fires once someone joins.
+	expectEvaluation(mock, 1, true, models.ThresholdStateArmed, models.ThresholdStateArmed, false)
+
+	check, err := services.CheckThresholdsForSeries(context.Background(), mock, []string{"APU0000708111"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if check.Checked != 1 || check.Triggered != 0 {
+		t.Errorf("Expected the campaign to be checked without firing, got %+v", check)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+func TestCheckThresholdsForSeries_RuleThreshold(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	mock.ExpectQuery("FROM thresholds t").
+		WithArgs([]string{"APU0000708111"}).
+		WillReturnRows(thresholdRows().
+			AddRow(1, 1, 1, 0.0, models.ThresholdModePercentPrevious, models.ThresholdDirectionEither, nil, false, models.ThresholdStateArmed, 0.0, 0, nil, models.ThresholdOperatorAnd, "latest(eggs) > 4", 0, "", ""))
+	mock.ExpectQuery("FROM threshold_conditions c").
+		WithArgs([]int{1}).
+		WillReturnRows(conditionRows())
+
+	// The batch resolves the series the rule names and loads their histories
+	// with the headline the letters report, so the rule reads nothing more.
+	mock.ExpectQuery("FROM series_catalog s").
+		WithArgs([]string{"eggs"}).
+		WillReturnRows(pgxmock.NewRows([]string{"series_id", "alias", "data_id"}).AddRow("APU0000708111", "eggs", 1))
+	expectThresholdData(mock, []int{1}, 4.40)
+	expectPreviousValue(mock, 4.00)
+	// The annual average sorts after December by its code, but is not the
+	// latest value.
+	mock.ExpectQuery("FROM data_observations").
+		WithArgs([]string{"APU0000708111"}).
+		WillReturnRows(pgxmock.NewRows([]string{"series_id", "year", "period", "value"}).
+			AddRow("APU0000708111", "2024", "M13", 3.60).
+			AddRow("APU0000708111", "2024", "M12", 4.40).
+			AddRow("APU0000708111", "2024", "M11", 4.00))
+	expectDispatchData(mock, []int{1}, nil, recipientRows().AddRow(1, 1, "rep@example.com", "Pat", "Doe", "Representative"), nil)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE thresholds").
+		WithArgs(models.ThresholdStateTriggered, true, pgxmock.AnyArg(), 1, 1, "2025", "M01", models.ThresholdStateArmed, (*time.Time)(nil), 0, "", "").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	// The letter reports the change and the rule.
+	expectQueued(mock, 1, noCampaign, models.EmailKindRecipient, "rep@example.com", pgxmock.AnyArg(),
+		"has increased by 10.00%", `The rule "latest(eggs) > 4" holds`)
+	mock.ExpectCommit()
+	expectEvaluation(mock, 1, true, models.ThresholdStateArmed, models.ThresholdStateTriggered, true)
+
+	check, err := services.CheckThresholdsForSeries(context.Background(), mock, []string{"APU0000708111"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if check.Checked != 1 || check.Triggered != 1 {
+		t.Errorf("Expected 1 checked and 1 triggered, got %+v", check)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+func TestCheckThresholdsForSeries_RulesShareTheBatchLoad(t *testing.T) {
+	t.Setenv("THRESHOLD_WORKERS", "1")
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	mock.ExpectQuery("FROM thresholds t").
+		WithArgs([]string{"APU0000708111"}).
+		WillReturnRows(thresholdRows().
+			AddRow(1, 1, 1, 0.0, models.ThresholdModePercentPrevious, models.ThresholdDirectionEither, nil, false, models.ThresholdStateArmed, 0.0, 0, nil, models.ThresholdOperatorAnd, "latest(eggs) > 5", 0, "", "").
+			AddRow(2, 1, 1, 0.0, models.ThresholdModePercentPrevious, models.ThresholdDirectionEither, nil, false, models.ThresholdStateArmed, 0.0, 0, nil, models.ThresholdOperatorAnd, "yoy(eggs) > 20", 0, "", ""))
+	mock.ExpectQuery("FROM threshold_conditions c").
+		WithArgs([]int{1, 2}).
+		WillReturnRows(conditionRows())
+
+	// Both rules name the same series, which is resolved and read once for
+	// the batch rather than once per rule.
+	mock.ExpectQuery("FROM series_catalog s").
+		WithArgs([]string{"eggs"}).
+		WillReturnRows(pgxmock.NewRows([]string{"series_id", "alias", "data_id"}).AddRow("APU0000708111", "eggs", 1))
+	expectThresholdData(mock, []int{1}, 4.40)
+	expectPreviousValue(mock, 4.00)
+	mock.ExpectQuery("FROM data_observations").
+		WithArgs([]string{"APU0000708111"}).
+		WillReturnRows(pgxmock.NewRows([]string{"series_id", "year", "period", "value"}).
+			AddRow("APU0000708111", "2025", "M01", 4.40).
+			AddRow("APU0000708111", "2024", "M12", 4.00).
+			AddRow("APU0000708111", "2024", "M01", 4.00))
+	expectDispatchData(mock, []int{1, 2}, nil, nil, nil)
+	// Neither rule holds: the latest value is 4.40, up 10% on a year ago.
+	expectEvaluation(mock, 1, false, models.ThresholdStateArmed, models.ThresholdStateArmed, false)
+	expectEvaluation(mock, 2, false, models.ThresholdStateArmed, models.ThresholdStateArmed, false)
+
+	check, err := services.CheckThresholdsForSeries(context.Background(), mock, []string{"APU0000708111"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if check.Checked != 2 || check.Triggered != 0 {
+		t.Errorf("Expected 2 checked and none triggered, got %+v", check)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+func TestCheckThresholdsForSeries_LosesRaceToAnotherCheck(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	mock.ExpectQuery("FROM thresholds t").
+		WithArgs([]string{"APU0000708111"}).
+		WillReturnRows(thresholdRows().
+			AddRow(1, 1, 1, 5.0, models.ThresholdModeAbsolute, models.ThresholdDirectionRise, nil, false, models.ThresholdStateArmed, 0.0, 0, nil, models.ThresholdOperatorAnd, "", 0, "", ""))
+	mock.ExpectQuery("FROM threshold_conditions c").
+		WithArgs([]int{1}).
+		WillReturnRows(conditionRows())
+	expectThresholdData(mock, []int{1}, 5.5)
+	expectDispatchData(mock, []int{1}, nil, recipientRows().AddRow(1, 1, "rep@example.com", "Pat", "Doe", "Representative"), nil)
+
+	// Another check fired the threshold after this one read it as armed, so
+	// the update matches no row and no letter is queued a second time.
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE thresholds").
+		WithArgs(models.ThresholdStateTriggered, true, pgxmock.AnyArg(), 1, 1, "2025", "M01", models.ThresholdStateArmed, (*time.Time)(nil), 0, "", "").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+	mock.ExpectRollback()
+	mock.ExpectExec("INSERT INTO threshold_evaluations").
+		WithArgs(1, pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), true,
+			models.ThresholdStateArmed, models.ThresholdStateArmed, false,
+			argContains{"Another check saved a new state"}, "", 1, 0, false).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+	check, err := services.CheckThresholdsForSeries(context.Background(), mock, []string{"APU0000708111"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if check.Checked != 1 || check.Triggered != 0 {
+		t.Errorf("Expected 1 checked and none triggered, got %+v", check)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+func TestCheckThresholdsForSeries_KeepsStateWhenLettersCannotBeQueued(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	mock.ExpectQuery("FROM thresholds t").
+		WithArgs([]string{"APU0000708111"}).
+		WillReturnRows(thresholdRows().
+			AddRow(1, 1, 1, 5.0, models.ThresholdModeAbsolute, models.ThresholdDirectionRise, nil, false, models.ThresholdStateArmed, 0.0, 0, nil, models.ThresholdOperatorAnd, "", 0, "", ""))
+	mock.ExpectQuery("FROM threshold_conditions c").
+		WithArgs([]int{1}).
+		WillReturnRows(conditionRows())
+	expectThresholdData(mock, []int{1}, 5.5)
+	expectDispatchData(mock, []int{1}, nil, recipientRows().AddRow(1, 1, "rep@example.com", "Pat", "Doe", "Representative"), nil)
+
+	// The state and the letters are saved together, so when the letter cannot
+	// be queued the threshold stays armed and fires on the next check.
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE thresholds").
+		WithArgs(models.ThresholdStateTriggered, true, pgxmock.AnyArg(), 1, 1, "2025", "M01", models.ThresholdStateArmed, (*time.Time)(nil), 0, "", "").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectExec("INSERT INTO notification_outbox").
+		WillReturnError(errors.New("connection reset"))
+	mock.ExpectRollback()
+	mock.ExpectExec("INSERT INTO threshold_evaluations").
+		WithArgs(1, pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), true,
+			models.ThresholdStateArmed, models.ThresholdStateArmed, false,
+			"The new state could not be saved, so the threshold did not fire.", argContains{"connection reset"}, 1, 0, false).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+	check, err := services.CheckThresholdsForSeries(context.Background(), mock, []string{"APU0000708111"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if check.Checked != 1 || check.Triggered != 0 {
+		t.Errorf("Expected 1 checked and none triggered, got %+v", check)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+// argContains matches a string argument containing every one of its parts.
+type argContains []string
+
+func (a argContains) Match(v interface{}) bool {
+	s, ok := v.(string)
+	if !ok {
+		return false
+	}
+	for _, part := range a {
+		if !strings.Contains(s, part) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestCheckThresholdsForSeries_RecordsEvaluation(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	mock.ExpectQuery("FROM thresholds t").
+		WithArgs([]string{"APU0000708111"}).
+		WillReturnRows(thresholdRows().
+			AddRow(2, 1, 1, 10.0, models.ThresholdModePercentPrevious, models.ThresholdDirectionEither, nil, false, models.ThresholdStateArmed, 0.0, 0, nil, models.ThresholdOperatorAnd, "", 0, "", ""))
+	mock.ExpectQuery("FROM threshold_conditions c").
+		WithArgs([]int{2}).
+		WillReturnRows(conditionRows())
+
+	expectThresholdData(mock, []int{1}, 5.5)
+	expectPreviousValue(mock, 5.25)
+	expectDispatchData(mock, []int{2}, nil, nil, nil)
+	mock.ExpectExec("INSERT INTO threshold_evaluations").
+		WithArgs(2, pgxmock.AnyArg(), argContains{`"name":"Eggs, Grade A, Large"`, `"latest":5.5`, `"previous":5.25`, `"met":false`},
+			pgxmock.AnyArg(), "|4.76%| >= 10.00%", false, models.ThresholdStateArmed, models.ThresholdStateArmed, false,
+			"Eggs, Grade A, Large: |4.76%| >= 10.00%, not met.\nThe threshold is not met, so it did not fire.", "", 0, 0, false).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+	check, err := services.CheckThresholdsForSeries(context.Background(), mock, []string{"APU0000708111"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if check.Checked != 1 || check.Triggered != 0 {
+		t.Errorf("Expected 1 checked and none triggered, got %+v", check)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+func TestCheckThresholdsForSeries_RecordsWhyThresholdWasSkipped(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	mock.ExpectQuery("FROM thresholds t").
+		WithArgs([]string{"APU0000708111"}).
+		WillReturnRows(thresholdRows().
+			AddRow(1, 1, 1, 5.0, models.ThresholdModeAbsolute, models.ThresholdDirectionRise, nil, false, models.ThresholdStateArmed, 0.0, 0, nil, models.ThresholdOperatorAnd, "", 0, "", ""))
+	mock.ExpectQuery("FROM threshold_conditions c").
+		WithArgs([]int{1}).
+		WillReturnRows(conditionRows())
+
+	mock.ExpectQuery("FROM data d").
+		WithArgs([]int{1}).
+		WillReturnRows(pgxmock.NewRows([]string{"data_id", "series_id", "name", "latest_value", "previous_value", "year", "period", "active"}).
+			AddRow(1, "APU0000708111", "Eggs, Grade A, Large", 5.5, 5.25, "2025", "M01", false))
+	expectDispatchData(mock, []int{1}, nil, nil, nil)
+	mock.ExpectExec("INSERT INTO threshold_evaluations").
+		WithArgs(1, pgxmock.AnyArg(), "[]", pgxmock.AnyArg(), "", false, models.ThresholdStateArmed, models.ThresholdStateArmed, false,
+			pgxmock.AnyArg(), argContains{"not active in the series catalog"}, 0, 0, false).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+	check, err := services.CheckThresholdsForSeries(context.Background(), mock, []string{"APU0000708111"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if check.Checked != 0 {
+		t.Errorf("Expected the threshold to be reported as not checked, got %+v", check)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+func TestMonitorThresholds_SkipsPausedAndExpiredThresholds(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	// Paused and expired thresholds are left out by the query, so none of
+	// them is evaluated or has an evaluation recorded.
+	mock.ExpectQuery(regexp.QuoteMeta("(t.paused_until IS NULL OR t.paused_until <= NOW())")).
+		WillReturnRows(thresholdRows())
+
+	check, err := services.MonitorThresholds(context.Background(), mock)
+	if err != nil || check.Checked != 0 {
+		t.Errorf("Expected nothing to be checked, got %+v, %v", check, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+func TestMonitorThresholds_ChecksThresholdsConcurrently(t *testing.T) {
+	t.Setenv("THRESHOLD_WORKERS", "3")
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	rows := thresholdRows()
+	for id := 1; id <= 5; id++ {
+		rows.AddRow(id, 1, 1, 10.0, models.ThresholdModeAbsolute, models.ThresholdDirectionRise, nil, false, models.ThresholdStateArmed, 0.0, 0, nil, models.ThresholdOperatorAnd, "", 0, "", "")
+	}
+	mock.ExpectQuery("FROM thresholds t").WillReturnRows(rows)
+	mock.ExpectQuery("FROM threshold_conditions c").
+		WithArgs([]int{1, 2, 3, 4, 5}).
+		WillReturnRows(conditionRows())
+	expectThresholdData(mock, []int{1}, 5.5)
+	expectDispatchData(mock, []int{1, 2, 3, 4, 5}, nil, nil, nil)
+
+	// The workers record their evaluations in whatever order they finish.
+	mock.MatchExpectationsInOrder(false)
+	for id := 1; id <= 5; id++ {
+		expectEvaluation(mock, id, false, models.ThresholdStateArmed, models.ThresholdStateArmed, false)
+	}
+
+	check, err := services.MonitorThresholds(context.Background(), mock)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if check.Checked != 5 || check.Triggered != 0 {
+		t.Errorf("Expected 5 checked and none triggered, got %+v", check)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+func TestMonitorThresholds_StopsWhenCancelled(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	mock.ExpectQuery("FROM thresholds t").
+		WillReturnRows(thresholdRows().
+			AddRow(1, 1, 1, 5.0, models.ThresholdModeAbsolute, models.ThresholdDirectionRise, nil, false, models.ThresholdStateArmed, 0.0, 0, nil, models.ThresholdOperatorAnd, "", 0, "", ""))
+	mock.ExpectQuery("FROM threshold_conditions c").
+		WithArgs([]int{1}).
+		WillReturnRows(conditionRows())
+	expectThresholdData(mock, []int{1}, 5.5)
+	expectDispatchData(mock, []int{1}, nil, nil, nil)
+
+	// Cancelled once the batch is loaded, before any threshold is handed to a
+	// worker, so none is checked and no state is saved.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	db := cancelAfterQuery{PgxPoolIface: mock, query: "FROM campaigns", cancel: cancel}
+
+	check, err := services.MonitorThresholds(ctx, db)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected the run to be cancelled, got %v", err)
+	}
+	if check.Checked != 0 {
+		t.Errorf("Expected nothing to be checked, got %+v", check)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+func TestCheckThresholdsForSeries_CancelledCheckStopsQuerying(t *testing.T) {
+	t.Setenv("THRESHOLD_WORKERS", "1")
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	mock.ExpectQuery("FROM thresholds t").
+		WithArgs([]string{"APU0000708111"}).
+		WillReturnRows(thresholdRows().
+			AddRow(1, 1, 1, 5.0, models.ThresholdModeAbsolute, models.ThresholdDirectionRise, nil, false, models.ThresholdStateArmed, 0.0, 0, nil, models.ThresholdOperatorAnd, "", 0, "", ""))
+	mock.ExpectQuery("FROM threshold_conditions c").
+		WithArgs([]int{1}).
+		WillReturnRows(conditionRows())
+	expectThresholdData(mock, []int{1}, 5.5)
+	expectDispatchData(mock, []int{1}, nil, nil,
+		campaignRows().AddRow(3, 1, 1, "Cheaper Eggs", "", "Eggs are up", "Dear [Recipient Name],", time.Now()))
+	mock.ExpectQuery("FROM campaign_members m").
+		WithArgs(3).
+		WillReturnRows(pgxmock.NewRows([]string{"user_id", "email", "first_name", "last_name"}).AddRow(7, "ana@example.com", "Ana", "Lopez"))
+
+	// Cancelled while the firing's letters are prepared, so neither the state
+	// nor the evaluation is written.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	db := &cancelledRun{cancelAfterQuery: cancelAfterQuery{PgxPoolIface: mock, query: "FROM campaign_members", cancel: cancel}, run: ctx}
+
+	check, err := services.CheckThresholdsForSeries(ctx, db, []string{"APU0000708111"})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected the run to be cancelled, got %v", err)
+	}
+	if check.Triggered != 0 {
+		t.Errorf("Expected nothing to fire, got %+v", check)
+	}
+	if len(db.late) > 0 {
+		t.Errorf("Expected no statements after cancellation, got %q", db.late)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}
+
+// cancelledRun behaves like a connection whose statements fail once their
+// context is cancelled, and records any statement issued after the run was
+// cancelled on a context that was not.
+type cancelledRun struct {
+	cancelAfterQuery
+	run  context.Context
+	late []string
+}
+
+func (c *cancelledRun) stopped(ctx context.Context, sql string) error {
+	if c.run.Err() != nil && ctx.Err() == nil {
+		c.late = append(c.late, sql)
+	}
+	return ctx.Err()
+}
+
+func (c *cancelledRun) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	if err := c.stopped(ctx, sql); err != nil {
+		return nil, err
+	}
+	return c.cancelAfterQuery.Query(ctx, sql, args...)
+}
+
+func (c *cancelledRun) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	if err := c.stopped(ctx, sql); err != nil {
+		return nil, err
+	}
+	return c.cancelAfterQuery.Exec(ctx, sql, args...)
+}
+
+func (c *cancelledRun) BeginTx(ctx context.Context, options pgx.TxOptions) (pgx.Tx, error) {
+	if err := c.stopped(ctx, "BEGIN"); err != nil {
+		return nil, err
+	}
+	return c.cancelAfterQuery.BeginTx(ctx, options)
+}
+
+// cancelAfterQuery cancels a run's context once a query containing query has
+// returned.
+type cancelAfterQuery struct {
+	pgxmock.PgxPoolIface
+	query  string
+	cancel context.CancelFunc
+}
+
+func (c cancelAfterQuery) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	rows, err := c.PgxPoolIface.Query(ctx, sql, args...)
+	if strings.Contains(sql, c.query) {
+		c.cancel()
+	}
+	return rows, err
+}
+
+func TestPreviewThreshold_RendersLettersWithoutSideEffects(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	var logBuffer bytes.Buffer
+	log.SetOutput(&logBuffer)
+	defer log.SetOutput(os.Stderr)
+
+	mock.ExpectQuery("FROM thresholds").
+		WithArgs(1).
+		WillReturnRows(thresholdRows().
+			AddRow(1, 1, 1, 5.0, models.ThresholdModeAbsolute, models.ThresholdDirectionRise, nil, true, models.ThresholdStateArmed, 0.0, 0, nil, models.ThresholdOperatorAnd, "", 0, "", ""))
+	mock.ExpectQuery("FROM threshold_conditions c").
+		WithArgs([]int{1}).
+		WillReturnRows(conditionRows())
+	expectThresholdData(mock, []int{1}, 5.5)
+	expectDispatchData(mock, []int{1}, nil, recipientRows().AddRow(1, 1, "rep@example.com", "Pat", "Doe", "Representative"), nil)
+
+	preview, err := services.PreviewThreshold(context.Background(), mock, 1)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !preview.WouldFire || preview.Evaluation.State != models.ThresholdStateTriggered || preview.Evaluation.Comparison != "5.50 >= 5.00" {
+		t.Errorf("Expected the threshold to fire, got %+v", preview)
+	}
+	if len(preview.Emails) != 2 || preview.Emails[0].To != "rep@example.com" || preview.Emails[1].Kind != models.EmailKindUser {
+		t.Fatalf("Expected a recipient letter and a user letter, got %+v", preview.Emails)
+	}
+	if !strings.Contains(preview.Emails[1].Body, "New Value: 5.50") {
+		t.Errorf("Expected the user letter to be filled in, got:\n%s", preview.Emails[1].Body)
+	}
+	if strings.Contains(logBuffer.String(), "[MOCK EMAIL]") {
+		t.Errorf("❌ Expected nothing to be sent, got:\n%s", logBuffer.String())
+	}
+
+	// Saving the state or recording the evaluation would be an unexpected call.
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ Unmet mock expectations: %v", err)
+	}
+}